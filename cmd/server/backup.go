@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"log"
+
+	"github.com/zhisme/tinylist/internal/config"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/worker"
+)
+
+// runBackup writes a consistent copy of the database to a new file, or without
+// a file a snapshot to the backup directory, removing the oldest ones like the
+// periodic backups of the server. It is safe to run while the server is up.
+func runBackup(cfg *config.Config, args []string) {
+	if len(args) > 1 {
+		log.Fatalf("Usage: tinylist backup [file]")
+	}
+
+	database := openDB(cfg, false)
+	defer database.Close()
+
+	if len(args) == 0 {
+		if cfg.Backup.Dir == "" {
+			log.Fatalf("No file given and backup.dir is not configured")
+		}
+		path, err := worker.NewBackupJob(database, cfg.Backup).Snapshot()
+		if err != nil {
+			log.Fatalf("Backup failed: %v", err)
+		}
+		log.Printf("Database backed up to %s", path)
+		return
+	}
+
+	if err := database.Backup(args[0]); err != nil {
+		log.Fatalf("Backup failed: %v", err)
+	}
+	log.Printf("Database backed up to %s", args[0])
+}
+
+// runRestore replaces the database with a snapshot after checking and
+// migrating it. It is refused while campaigns are sending. A running server
+// sees the restored data, but keeps the settings it loaded at startup until
+// restarted; prefer the restore endpoint then.
+func runRestore(cfg *config.Config, args []string) {
+	if len(args) != 1 {
+		log.Fatalf("Usage: tinylist restore <file>")
+	}
+
+	if err := db.ValidateSnapshot(args[0]); err != nil {
+		log.Fatalf("Invalid snapshot: %v", err)
+	}
+
+	database := openDB(cfg, false)
+	defer database.Close()
+
+	if err := database.Restore(args[0]); err != nil {
+		if errors.Is(err, db.ErrCampaignsSending) {
+			log.Fatalf("Campaigns are being sent, cancel them before restoring")
+		}
+		log.Fatalf("Restore failed: %v", err)
+	}
+	log.Printf("Database restored from %s", args[0])
+}
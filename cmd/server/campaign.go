@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/config"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/worker"
+)
+
+// runCampaign sends, cancels or shows the status of a campaign
+func runCampaign(cfg *config.Config, args []string) {
+	if len(args) != 2 {
+		log.Fatalf("Usage: tinylist campaign send|cancel|status <id>")
+	}
+	action, id := args[0], args[1]
+
+	database := openDB(cfg, true)
+	defer database.Close()
+
+	campaign, err := database.GetCampaignByUUID(id)
+	if err != nil {
+		log.Fatalf("Campaign %s not found", id)
+	}
+
+	switch action {
+	case "send":
+		sendCampaign(cfg, database, campaign)
+	case "cancel":
+		// The process sending the campaign notices the status change and stops
+		if err := database.CancelSendingCampaign(campaign.ID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Fatalf("Campaign %s is not currently sending", campaign.UUID)
+			}
+			log.Fatalf("Failed to cancel campaign: %v", err)
+		}
+		log.Printf("Campaign %s cancellation requested", campaign.UUID)
+	case "status":
+		printCampaign(campaign)
+	default:
+		log.Fatalf("Unknown campaign command %q, expected send, cancel or status", action)
+	}
+}
+
+// sendCampaign sends a campaign from this process and waits until it is done.
+// SIGINT or SIGTERM cancels sending, like the cancel command.
+func sendCampaign(cfg *config.Config, database *db.DB, campaign *models.Campaign) {
+	mail := newMailer(database)
+	if !mail.IsConfigured() {
+		log.Fatalf("Email delivery is not configured")
+	}
+	campaignWorker := worker.NewCampaignWorker(database, mail, cfg.Sending, publicURL(cfg))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		campaignWorker.CancelCampaign(campaign.ID)
+	}()
+
+	log.Printf("Sending campaign %s", campaign.UUID)
+	if err := campaignWorker.SendCampaign(campaign.ID); err != nil {
+		log.Fatalf("Campaign send failed: %v", err)
+	}
+
+	sent, err := database.GetCampaignByID(campaign.ID)
+	if err != nil {
+		log.Fatalf("Failed to get campaign: %v", err)
+	}
+	printCampaign(sent)
+}
+
+// printCampaign writes the status and progress of a campaign to stdout
+func printCampaign(campaign *models.Campaign) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%s\n", campaign.UUID)
+	fmt.Fprintf(tw, "Subject\t%s\n", campaign.Subject)
+	fmt.Fprintf(tw, "Status\t%s\n", campaign.Status)
+	fmt.Fprintf(tw, "Progress\t%d sent, %d failed of %d\n", campaign.SentCount, campaign.FailedCount, campaign.TotalCount)
+	for _, at := range []struct {
+		label string
+		time  *time.Time
+	}{
+		{"Scheduled", campaign.ScheduledAt},
+		{"Started", campaign.StartedAt},
+		{"Completed", campaign.CompletedAt},
+	} {
+		if at.time != nil {
+			fmt.Fprintf(tw, "%s\t%s\n", at.label, at.time.UTC().Format("2006-01-02 15:04:05 UTC"))
+		}
+	}
+	tw.Flush()
+}
+
+// runSendTest checks email delivery by sending a test email, or with -campaign
+// sends that campaign rendered for a sample subscriber
+func runSendTest(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("send-test", flag.ExitOnError)
+	campaignID := flags.String("campaign", "", "send this campaign instead of the delivery test email")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tinylist send-test [-campaign id] <email>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	emails := flags.Args()
+
+	database := openDB(cfg, true)
+	defer database.Close()
+
+	mail := newMailer(database)
+	if !mail.IsConfigured() {
+		log.Fatalf("Email delivery is not configured")
+	}
+
+	var results []worker.TestResult
+	if *campaignID == "" {
+		for _, email := range emails {
+			result := worker.TestResult{Email: email, Sent: true}
+			if err := mail.SendTest(email); err != nil {
+				result.Sent = false
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+		}
+	} else {
+		campaign, err := database.GetCampaignByUUID(*campaignID)
+		if err != nil {
+			log.Fatalf("Campaign %s not found", *campaignID)
+		}
+		campaignWorker := worker.NewCampaignWorker(database, mail, cfg.Sending, publicURL(cfg))
+		results, err = campaignWorker.SendTest(context.Background(), campaign, worker.SampleSubscriber(emails[0]), emails)
+		if err != nil {
+			log.Fatalf("Failed to render campaign: %v", err)
+		}
+	}
+
+	failed := false
+	for _, result := range results {
+		if !result.Sent {
+			log.Printf("%s: %s", result.Email, result.Error)
+			failed = true
+			continue
+		}
+		log.Printf("%s: sent", result.Email)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
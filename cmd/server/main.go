@@ -1,151 +1,99 @@
 package main
 
 import (
-	"context"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 	"github.com/zhisme/tinylist/internal/config"
 	"github.com/zhisme/tinylist/internal/db"
-	"github.com/zhisme/tinylist/internal/handlers/private"
-	"github.com/zhisme/tinylist/internal/handlers/public"
 	"github.com/zhisme/tinylist/internal/mailer"
-	authmw "github.com/zhisme/tinylist/internal/middleware"
-	"github.com/zhisme/tinylist/internal/worker"
 )
 
+// commands maps each subcommand to the function running it with the remaining arguments
+var commands = map[string]func(cfg *config.Config, args []string){
+	"serve":     runServe,
+	"migrate":   runMigrate,
+	"import":    runImport,
+	"export":    runExport,
+	"send-test": runSendTest,
+	"campaign":  runCampaign,
+	"backup":    runBackup,
+	"restore":   runRestore,
+}
+
+const usage = `Usage: tinylist [-config file] [command] [arguments]
+
+Commands:
+  serve                              Start the server (default)
+  migrate [status]                   Apply pending migrations, or only list them
+  import [flags] <file.csv | ->      Import subscribers from a CSV file
+  export [flags]                     Export subscribers as CSV or NDJSON
+  send-test [-campaign id] <email>   Send a test email
+  campaign send|cancel|status <id>   Send, cancel or show a campaign
+  backup [file]                      Write a copy of the database to file,
+                                     or a rotated snapshot to backup.dir
+  restore <file>                     Replace the database with a snapshot
+
+All commands work on the database directly, the server doesn't need to run.
+Run "tinylist <command> -h" for the flags of a command.
+`
+
 func main() {
+	configPath := flag.String("config", "config.yaml", "path to the config file")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+	}
+	flag.Parse()
+
+	command, args := "serve", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	run, ok := commands[command]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadFromFile(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Initialize database
-	database, err := db.New(cfg.Database.Path)
+	run(cfg, args)
+}
+
+// openDB opens the configured database, applying pending migrations if migrate is set
+func openDB(cfg *config.Config, migrate bool) *db.DB {
+	database, err := db.Open(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer database.Close()
 
-	// Run migrations
-	if err := database.Migrate(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	if migrate {
+		if err := database.Migrate(); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
 	}
+	return database
+}
 
-	// Initialize mailer (unconfigured - settings loaded from DB)
+// newMailer creates a mailer with the SMTP settings, delivery backend and
+// system emails stored in the database
+func newMailer(database *db.DB) *mailer.Mailer {
 	mail := mailer.New()
-
-	// Load SMTP settings from database
 	loadSMTPFromDB(database, mail)
+	loadDeliveryFromDB(database, mail)
+	loadSystemEmailsFromDB(database, mail)
+	return mail
+}
 
-	// Determine API base path (e.g., "" for /api/*, "/tinylist" for /tinylist/api/*)
-	basePath := cfg.Server.APIBasePath
-
-	// Public URL with base path for generating links in emails (e.g., verification links)
-	publicURLWithBasePath := cfg.Server.PublicURL + basePath
-
-	// Initialize campaign worker
-	campaignWorker := worker.NewCampaignWorker(database, mail, cfg.Sending, publicURLWithBasePath)
-
-	// Initialize router
-	r := chi.NewRouter()
-
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:5173", "http://localhost:8080", cfg.Server.PublicURL},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Timeout(60 * time.Second))
-
-	// Health check
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `{"status":"healthy"}`)
-	})
-
-	// Public API routes
-	subscribeHandler := public.NewSubscribeHandler(database, mail, publicURLWithBasePath)
-	verifyHandler := public.NewVerifyHandler(database)
-	unsubscribeHandler := public.NewUnsubscribeHandler(database)
-
-	r.Route(basePath+"/api", func(r chi.Router) {
-		r.Post("/subscribe", subscribeHandler.Subscribe)
-		r.Get("/verify/{token}", verifyHandler.Verify)
-		r.Get("/unsubscribe/{token}", unsubscribeHandler.Unsubscribe)
-	})
-
-	// Private API routes (protected by Basic Auth)
-	subscriberHandler := private.NewSubscriberHandler(database, mail, publicURLWithBasePath)
-	campaignHandler := private.NewCampaignHandler(database, campaignWorker, mail)
-	settingsHandler := private.NewSettingsHandler(database, mail)
-	statsHandler := private.NewStatsHandler(database)
-	r.Route(basePath+"/api/private", func(r chi.Router) {
-		r.Use(authmw.BasicAuth(cfg.Auth))
-		r.Get("/stats", statsHandler.GetStats)
-		r.Mount("/subscribers", subscriberHandler.Routes())
-		r.Mount("/campaigns", campaignHandler.Routes())
-		r.Mount("/settings", settingsHandler.Routes())
-	})
-
-	if basePath != "" {
-		log.Printf("API routes mounted at %s/api/*", basePath)
-	}
-	log.Printf("Basic Auth enabled for %s/api/private (user: %s)", basePath, cfg.Auth.Username)
-
-	// Server configuration
-	port := cfg.Server.Port
-  // TODO: maybe move to config yaml
-	if envPort := os.Getenv("PORT"); envPort != "" {
-		fmt.Sscanf(envPort, "%d", &port)
-	}
-
-	server := &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, port),
-		Handler:      r,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
-
-	// Start server in goroutine
-	go func() {
-		log.Printf("Starting TinyList server on %s:%d", cfg.Server.Host, port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
-		}
-	}()
-
-	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	log.Println("Shutting down server...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
-	}
-
-	log.Println("Server stopped")
+// publicURL returns the public URL including the API base path, used for links in emails
+func publicURL(cfg *config.Config) string {
+	return cfg.Server.PublicURL + cfg.Server.APIBasePath
 }
 
 // loadSMTPFromDB loads SMTP settings from database and reconfigures the mailer
@@ -182,3 +130,33 @@ func loadSMTPFromDB(database *db.DB, mail *mailer.Mailer) {
 
 	log.Println("SMTP settings loaded from database")
 }
+
+// loadDeliveryFromDB selects the delivery backend stored in the database, SMTP by default
+func loadDeliveryFromDB(database *db.DB, mail *mailer.Mailer) {
+	settings, err := database.GetAllSettings()
+	if err != nil {
+		log.Printf("Warning: failed to load settings from DB: %v", err)
+		return
+	}
+
+	backend, err := mailer.BackendFromSettings(settings)
+	if err != nil {
+		log.Printf("Warning: %v, falling back to SMTP", err)
+		return
+	}
+	if backend != nil {
+		mail.SetBackend(backend)
+		log.Printf("Delivering emails through the %s backend", settings["delivery_backend"])
+	}
+}
+
+// loadSystemEmailsFromDB loads customized system emails, the built-ins are used for the rest
+func loadSystemEmailsFromDB(database *db.DB, mail *mailer.Mailer) {
+	settings, err := database.GetAllSettings()
+	if err != nil {
+		log.Printf("Warning: failed to load settings from DB: %v", err)
+		return
+	}
+
+	mail.SetSystemEmails(mailer.SystemEmailsFromSettings(settings))
+}
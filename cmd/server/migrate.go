@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/zhisme/tinylist/internal/config"
+)
+
+// runMigrate applies pending migrations, then prints every migration and when
+// it was applied. With "status" it only prints them.
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) > 1 || (len(args) == 1 && args[0] != "status") {
+		log.Fatalf("Usage: tinylist migrate [status]")
+	}
+
+	database := openDB(cfg, false)
+	defer database.Close()
+
+	if len(args) == 0 {
+		if err := database.Migrate(); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
+
+	migrations, err := database.Migrations()
+	if err != nil {
+		log.Fatalf("Failed to read migration status: %v", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+	for _, m := range migrations {
+		applied := "pending"
+		if m.AppliedAt != nil {
+			applied = m.AppliedAt.Format("2006-01-02 15:04:05") + " UTC"
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", m.Version, m.Name, applied)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+	"github.com/zhisme/tinylist/internal/config"
+	"github.com/zhisme/tinylist/internal/handlers/private"
+	"github.com/zhisme/tinylist/internal/handlers/public"
+	authmw "github.com/zhisme/tinylist/internal/middleware"
+	"github.com/zhisme/tinylist/internal/worker"
+)
+
+// runServe starts the HTTP server along with the campaign scheduler and
+// bounce processing, and runs until it receives SIGINT or SIGTERM
+func runServe(cfg *config.Config, args []string) {
+	if len(args) > 0 {
+		log.Fatalf("Usage: tinylist serve")
+	}
+
+	database := openDB(cfg, true)
+	defer database.Close()
+
+	mail := newMailer(database)
+
+	// Determine API base path (e.g., "" for /api/*, "/tinylist" for /tinylist/api/*)
+	basePath := cfg.Server.APIBasePath
+
+	// Public URL with base path for generating links in emails (e.g., verification links)
+	publicURLWithBasePath := publicURL(cfg)
+
+	// Initialize campaign worker
+	campaignWorker := worker.NewCampaignWorker(database, mail, cfg.Sending, publicURLWithBasePath)
+
+	if !mail.IsConfigured() {
+		log.Println("Email delivery not configured - scheduled and interrupted campaigns will wait until it is")
+	}
+
+	// Start scheduler for campaigns scheduled to be sent later, which also
+	// resumes campaigns interrupted by a restart
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	scheduler := worker.NewScheduler(database, campaignWorker, time.Duration(cfg.Sending.ScheduleInterval)*time.Second)
+	go scheduler.Run(schedulerCtx)
+
+	// Start collecting bounces from the mailbox, if one is configured
+	bounceProcessor := worker.NewBounceProcessor(database, cfg.Bounce)
+	go bounceProcessor.Run(schedulerCtx)
+
+	// Write periodic database snapshots, if a backup directory is configured
+	backupJob := worker.NewBackupJob(database, cfg.Backup)
+	go backupJob.Run(schedulerCtx)
+
+	// Initialize router
+	r := chi.NewRouter()
+
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins:   []string{"http://localhost:5173", "http://localhost:8080", cfg.Server.PublicURL},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           300,
+	}))
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP)
+	r.Use(middleware.Timeout(60 * time.Second))
+
+	// Health check
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"status":"healthy"}`)
+	})
+
+	// Public API routes
+	subscribeHandler := public.NewSubscribeHandler(database, mail, publicURLWithBasePath, cfg.Subscribe.Attributes)
+	verifyHandler := public.NewVerifyHandler(database, mail, publicURLWithBasePath)
+	unsubscribeHandler := public.NewUnsubscribeHandler(database, mail)
+	trackHandler := public.NewTrackHandler(database)
+
+	r.Route(basePath+"/api", func(r chi.Router) {
+		r.Post("/subscribe", subscribeHandler.Subscribe)
+		r.Get("/verify/{token}", verifyHandler.Verify)
+		r.Get("/unsubscribe/{token}", unsubscribeHandler.Unsubscribe)
+		r.Post("/unsubscribe/{token}", unsubscribeHandler.OneClick)
+		r.Get("/track/open/{campaign}/{subscriber}", trackHandler.Open)
+		r.Get("/track/click/{link}/{subscriber}", trackHandler.Click)
+	})
+
+	// Private API routes (protected by Basic Auth)
+	subscriberHandler := private.NewSubscriberHandler(database, mail, publicURLWithBasePath)
+	campaignHandler := private.NewCampaignHandler(database, campaignWorker, mail)
+	settingsHandler := private.NewSettingsHandler(database, mail)
+	statsHandler := private.NewStatsHandler(database)
+	listHandler := private.NewListHandler(database)
+	segmentHandler := private.NewSegmentHandler(database)
+	bounceHandler := private.NewBounceHandler(database, bounceProcessor)
+	templateHandler := private.NewTemplateHandler(database)
+	txHandler := private.NewTxHandler(database, mail)
+	// A restored database brings its own settings
+	backupHandler := private.NewBackupHandler(database, func() {
+		loadSMTPFromDB(database, mail)
+		loadDeliveryFromDB(database, mail)
+		loadSystemEmailsFromDB(database, mail)
+	})
+	r.Route(basePath+"/api/private", func(r chi.Router) {
+		r.Use(authmw.BasicAuth(cfg.Auth))
+		r.Get("/stats", statsHandler.GetStats)
+		r.Mount("/subscribers", subscriberHandler.Routes())
+		r.Mount("/campaigns", campaignHandler.Routes())
+		r.Mount("/lists", listHandler.Routes())
+		r.Mount("/segments", segmentHandler.Routes())
+		r.Mount("/bounces", bounceHandler.Routes())
+		r.Mount("/templates", templateHandler.Routes())
+		r.Mount("/tx", txHandler.Routes())
+		r.Mount("/settings", settingsHandler.Routes())
+		r.Mount("/backup", backupHandler.Routes())
+	})
+
+	if basePath != "" {
+		log.Printf("API routes mounted at %s/api/*", basePath)
+	}
+	log.Printf("Basic Auth enabled for %s/api/private (user: %s)", basePath, cfg.Auth.Username)
+
+	// Server configuration
+	port := cfg.Server.Port
+  // TODO: maybe move to config yaml
+	if envPort := os.Getenv("PORT"); envPort != "" {
+		fmt.Sscanf(envPort, "%d", &port)
+	}
+
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, port),
+		Handler:      r,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in goroutine
+	go func() {
+		log.Printf("Starting TinyList server on %s:%d", cfg.Server.Host, port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down server...")
+
+	stopScheduler()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+
+	log.Println("Server stopped")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/zhisme/tinylist/internal/config"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/private"
+)
+
+// stringList is a flag that may be given more than once
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runImport imports subscribers from a CSV file, or stdin for "-", with the
+// same columns and options as POST /api/private/subscribers/import
+func runImport(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	existing := flags.String("existing", "skip", "what to do with existing subscribers: skip or update")
+	listID := flags.String("list", "", "add imported subscribers to the list with this id")
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tinylist import [flags] <file.csv | ->")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts := db.ImportOptions{DryRun: *dryRun}
+	switch *existing {
+	case "skip":
+	case "update":
+		opts.UpdateExisting = true
+	default:
+		log.Fatalf("Invalid -existing %q: must be skip or update", *existing)
+	}
+
+	var body io.Reader = os.Stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open import file: %v", err)
+		}
+		defer file.Close()
+		body = file
+	}
+
+	database := openDB(cfg, true)
+	defer database.Close()
+
+	if *listID != "" {
+		list, err := database.GetListByUUID(*listID)
+		if err != nil {
+			log.Fatalf("Unknown list %s", *listID)
+		}
+		opts.ListID = list.ID
+	}
+
+	report, err := private.ImportCSV(database, body, opts)
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	for _, row := range report.Rows {
+		if row.Error != "" {
+			fmt.Printf("line %d: %s: %s\n", row.Line, row.Email, row.Error)
+		}
+	}
+	if report.DryRun {
+		fmt.Print("Dry run, nothing was written. ")
+	}
+	fmt.Printf("Created %d, updated %d, skipped %d, invalid %d\n", report.Created, report.Updated, report.Skipped, report.Invalid)
+}
+
+// runExport writes subscribers to stdout or a file, filtered like
+// GET /api/private/subscribers/export
+func runExport(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "csv", "output format: csv or ndjson")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	query := url.Values{}
+	var tags stringList
+	for _, name := range []string{"status", "list", "q", "created_after", "created_before"} {
+		flags.Func(name, "filter by "+name+", see the export API", func(value string) error {
+			query.Set(name, value)
+			return nil
+		})
+	}
+	flags.Var(&tags, "tag", "filter by tag, may be repeated")
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	query["tag"] = []string(tags)
+
+	database := openDB(cfg, true)
+	defer database.Close()
+
+	filter, err := private.ParseSubscriberFilter(database, query)
+	if err != nil {
+		log.Fatalf("Invalid filter: %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create export file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	count, err := private.WriteSubscribers(context.Background(), database, out, *format, filter, nil)
+	if err != nil {
+		log.Fatalf("Export failed after %d subscribers: %v", count, err)
+	}
+	log.Printf("Exported %d subscribers", count)
+}
@@ -34,8 +34,40 @@ export const subscribers = {
   },
   get: (id) => request(`/subscribers/${id}`),
   create: (data) => request('/subscribers', { method: 'POST', body: JSON.stringify(data) }),
+  update: (id, data) => request(`/subscribers/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
   delete: (id) => request(`/subscribers/${id}`, { method: 'DELETE' }),
   sendVerification: (id) => request(`/subscribers/${id}/send-verification`, { method: 'POST' }),
+  tags: () => request('/subscribers/tags'),
+  addTags: (id, tags) => request(`/subscribers/${id}/tags`, { method: 'POST', body: JSON.stringify({ tags }) }),
+  removeTag: (id, tag) => request(`/subscribers/${id}/tags/${encodeURIComponent(tag)}`, { method: 'DELETE' }),
+  bulkTag: (tag, params = {}) => {
+    const query = new URLSearchParams(params).toString();
+    return request(`/subscribers/tags/${encodeURIComponent(tag)}${query ? `?${query}` : ''}`, { method: 'POST' });
+  },
+  bulkUntag: (tag, params = {}) => {
+    const query = new URLSearchParams(params).toString();
+    return request(`/subscribers/tags/${encodeURIComponent(tag)}${query ? `?${query}` : ''}`, { method: 'DELETE' });
+  },
+  import: (csv, params = {}) => {
+    const query = new URLSearchParams(params).toString();
+    return request(`/subscribers/import${query ? `?${query}` : ''}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'text/csv' },
+      body: csv,
+    });
+  },
+  exportUrl: (params = {}) => {
+    const query = new URLSearchParams(params).toString();
+    return `${API_BASE}/subscribers/export${query ? `?${query}` : ''}`;
+  },
+};
+
+// Bounces API
+export const bounces = {
+  list: (params = {}) => {
+    const query = new URLSearchParams(params).toString();
+    return request(`/bounces${query ? `?${query}` : ''}`);
+  },
 };
 
 // Campaigns API
@@ -46,8 +78,51 @@ export const campaigns = {
   update: (id, data) => request(`/campaigns/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
   delete: (id) => request(`/campaigns/${id}`, { method: 'DELETE' }),
   send: (id) => request(`/campaigns/${id}/send`, { method: 'POST' }),
+  schedule: (id, scheduledAt) => request(`/campaigns/${id}/schedule`, { method: 'POST', body: JSON.stringify({ scheduled_at: scheduledAt }) }),
+  unschedule: (id) => request(`/campaigns/${id}/unschedule`, { method: 'POST' }),
   cancel: (id) => request(`/campaigns/${id}/cancel`, { method: 'POST' }),
   journal: (id) => request(`/campaigns/${id}/journal`),
+  logs: (id, cursor = '', perPage = 50) => request(`/campaigns/${id}/logs?${new URLSearchParams({ cursor, per_page: perPage })}`),
+  preview: (id, subscriber) => request(`/campaigns/${id}/preview${subscriber ? `?subscriber=${subscriber}` : ''}`),
+  test: (id, emails, subscriber) => request(`/campaigns/${id}/test`, { method: 'POST', body: JSON.stringify({ emails, subscriber }) }),
+};
+
+// Templates API
+export const templates = {
+  list: (type) => request(`/templates${type ? `?type=${type}` : ''}`),
+  get: (id) => request(`/templates/${id}`),
+  create: (data) => request('/templates', { method: 'POST', body: JSON.stringify(data) }),
+  update: (id, data) => request(`/templates/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
+  delete: (id) => request(`/templates/${id}`, { method: 'DELETE' }),
+};
+
+// Transactional email API
+export const tx = {
+  list: (params = {}) => {
+    const query = new URLSearchParams(params).toString();
+    return request(`/tx${query ? `?${query}` : ''}`);
+  },
+};
+
+// Lists API
+export const lists = {
+  list: () => request('/lists'),
+  get: (id) => request(`/lists/${id}`),
+  create: (data) => request('/lists', { method: 'POST', body: JSON.stringify(data) }),
+  update: (id, data) => request(`/lists/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
+  delete: (id) => request(`/lists/${id}`, { method: 'DELETE' }),
+  addSubscriber: (id, subscriberId) => request(`/lists/${id}/subscribers`, { method: 'POST', body: JSON.stringify({ subscriber_id: subscriberId }) }),
+  removeSubscriber: (id, subscriberId) => request(`/lists/${id}/subscribers/${subscriberId}`, { method: 'DELETE' }),
+};
+
+// Segments API
+export const segments = {
+  list: () => request('/segments'),
+  get: (id) => request(`/segments/${id}`),
+  create: (data) => request('/segments', { method: 'POST', body: JSON.stringify(data) }),
+  update: (id, data) => request(`/segments/${id}`, { method: 'PUT', body: JSON.stringify(data) }),
+  delete: (id) => request(`/segments/${id}`, { method: 'DELETE' }),
+  count: (id) => request(`/segments/${id}/count`),
 };
 
 // Stats API
@@ -60,4 +135,9 @@ export const settings = {
   getSMTP: () => request('/settings/smtp'),
   updateSMTP: (data) => request('/settings/smtp', { method: 'PUT', body: JSON.stringify(data) }),
   testSMTP: (email) => request('/settings/smtp/test', { method: 'POST', body: JSON.stringify({ email }) }),
+  getDelivery: () => request('/settings/delivery'),
+  updateDelivery: (data) => request('/settings/delivery', { method: 'PUT', body: JSON.stringify(data) }),
+  getEmails: () => request('/settings/emails'),
+  updateEmail: (kind, data) => request(`/settings/emails/${kind}`, { method: 'PUT', body: JSON.stringify(data) }),
+  previewEmail: (kind, data) => request(`/settings/emails/${kind}/preview`, { method: 'POST', body: JSON.stringify(data) }),
 };
@@ -1,5 +1,5 @@
 import { useState, useEffect } from 'preact/hooks';
-import { campaigns } from '../api';
+import { campaigns, templates, segments } from '../api';
 
 export function Campaigns() {
   const [data, setData] = useState([]);
@@ -214,6 +214,25 @@ function CampaignModal({ campaign, onClose, onSave }) {
   const [subject, setSubject] = useState(campaign?.subject || '');
   const [bodyText, setBodyText] = useState(campaign?.body_text || '');
   const [bodyHtml, setBodyHtml] = useState(campaign?.body_html || '');
+  const [trackOpens, setTrackOpens] = useState(campaign?.track_opens || false);
+  const [trackClicks, setTrackClicks] = useState(campaign?.track_clicks || false);
+  const [templateId, setTemplateId] = useState(campaign?.template_id || '');
+  const [layouts, setLayouts] = useState([]);
+  const [segmentId, setSegmentId] = useState(campaign?.segment_id || '');
+  const [segmentList, setSegmentList] = useState([]);
+  const [audience, setAudience] = useState(null);
+
+  useEffect(() => {
+    templates.list('layout').then(setLayouts).catch(() => setLayouts([]));
+    segments.list().then(setSegmentList).catch(() => setSegmentList([]));
+  }, []);
+
+  useEffect(() => {
+    setAudience(null);
+    if (segmentId) {
+      segments.count(segmentId).then(setAudience).catch(() => setAudience(null));
+    }
+  }, [segmentId]);
 
   function handleSubmit(e) {
     e.preventDefault();
@@ -221,6 +240,10 @@ function CampaignModal({ campaign, onClose, onSave }) {
       subject,
       body_text: bodyText,
       body_html: bodyHtml || null,
+      track_opens: trackOpens,
+      track_clicks: trackClicks,
+      template_id: templateId,
+      segment_id: segmentId,
     });
   }
 
@@ -244,7 +267,7 @@ function CampaignModal({ campaign, onClose, onSave }) {
           </div>
           <div class="mb-4">
             <label class="block text-sm font-medium mb-1">
-              Body (Plain Text) <span class="text-gray-400">- Use {'{{name}}'} and {'{{email}}'} for personalization</span>
+              Body (Plain Text) <span class="text-gray-400">- Go template syntax, e.g. {'{{ .Name | default "there" }}'}, {'{{ .Email }}'}, {'{{ if .Name }}…{{ end }}'}</span>
             </label>
             <textarea
               value={bodyText}
@@ -252,7 +275,7 @@ function CampaignModal({ campaign, onClose, onSave }) {
               required
               rows={6}
               class="w-full border rounded px-3 py-2 font-mono text-sm"
-              placeholder="Hi {{name}},&#10;&#10;Welcome to our newsletter..."
+              placeholder='Hi {{ .Name | default "there" }},&#10;&#10;Welcome to our newsletter...'
             />
           </div>
           <div class="mb-4">
@@ -264,9 +287,61 @@ function CampaignModal({ campaign, onClose, onSave }) {
               onInput={(e) => setBodyHtml(e.target.value)}
               rows={6}
               class="w-full border rounded px-3 py-2 font-mono text-sm"
-              placeholder="<p>Hi {{name}},</p>&#10;<p>Welcome to our newsletter...</p>"
+              placeholder='<p>Hi {{ .Name | default "there" }},</p>&#10;<p>Welcome to our newsletter...</p>'
             />
           </div>
+          <div class="mb-4">
+            <label class="block text-sm font-medium mb-1">
+              Layout <span class="text-gray-400">- Wraps the bodies at its {'{{ content }}'} slot</span>
+            </label>
+            <select
+              value={templateId}
+              onChange={(e) => setTemplateId(e.target.value)}
+              class="w-full border rounded px-3 py-2"
+            >
+              <option value="">None</option>
+              {layouts.map((layout) => (
+                <option key={layout.id} value={layout.id}>{layout.name}</option>
+              ))}
+            </select>
+          </div>
+          <div class="mb-4">
+            <label class="block text-sm font-medium mb-1">
+              Segment <span class="text-gray-400">- Only send to subscribers matching its rules</span>
+            </label>
+            <select
+              value={segmentId}
+              onChange={(e) => setSegmentId(e.target.value)}
+              class="w-full border rounded px-3 py-2"
+            >
+              <option value="">All verified subscribers</option>
+              {segmentList.map((segment) => (
+                <option key={segment.id} value={segment.id}>{segment.name}</option>
+              ))}
+            </select>
+            {audience && (
+              <p class="text-sm text-gray-500 mt-1">{audience.count} verified subscribers match this segment</p>
+            )}
+          </div>
+          <div class="mb-4 flex gap-6 text-sm">
+            <label class="flex items-center gap-2">
+              <input
+                type="checkbox"
+                checked={trackOpens}
+                onChange={(e) => setTrackOpens(e.target.checked)}
+              />
+              Track opens
+            </label>
+            <label class="flex items-center gap-2">
+              <input
+                type="checkbox"
+                checked={trackClicks}
+                onChange={(e) => setTrackClicks(e.target.checked)}
+              />
+              Track clicks
+            </label>
+            <span class="text-gray-400">- HTML body only</span>
+          </div>
           <div class="flex justify-end gap-2">
             <button
               type="button"
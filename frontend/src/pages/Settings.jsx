@@ -11,6 +11,10 @@ export function Settings() {
     from_name: '',
     tls: true,
   });
+  const [delivery, setDelivery] = useState({ backend: 'smtp', dir: '', url: '', token: '' });
+  const [emails, setEmails] = useState([]);
+  const [emailKind, setEmailKind] = useState('verification');
+  const [emailPreview, setEmailPreview] = useState(null);
   const [loading, setLoading] = useState(true);
   const [saving, setSaving] = useState(false);
   const [testing, setTesting] = useState(false);
@@ -23,8 +27,14 @@ export function Settings() {
 
   async function loadSettings() {
     try {
-      const data = await settings.getSMTP();
+      const [data, deliveryData, emailsData] = await Promise.all([
+        settings.getSMTP(),
+        settings.getDelivery(),
+        settings.getEmails(),
+      ]);
       setSmtpConfig(data);
+      setDelivery({ dir: '', url: '', token: '', ...deliveryData });
+      setEmails(emailsData);
     } catch (err) {
       setMessage({ type: 'error', text: 'Failed to load settings: ' + err.message });
     } finally {
@@ -52,6 +62,66 @@ export function Settings() {
     }
   }
 
+  async function handleDeliverySubmit(e) {
+    e.preventDefault();
+    setSaving(true);
+    setMessage(null);
+    try {
+      await settings.updateDelivery(delivery);
+      setMessage({ type: 'success', text: 'Delivery settings saved successfully' });
+      loadSettings();
+    } catch (err) {
+      setMessage({ type: 'error', text: 'Failed to save delivery settings: ' + err.message });
+    } finally {
+      setSaving(false);
+    }
+  }
+
+  const currentEmail = emails.find(email => email.kind === emailKind);
+
+  function handleEmailChange(field, value) {
+    setEmails(prev => prev.map(email => email.kind === emailKind ? { ...email, [field]: value } : email));
+  }
+
+  async function handleEmailSubmit(e) {
+    e.preventDefault();
+    setSaving(true);
+    setMessage(null);
+    try {
+      await settings.updateEmail(emailKind, currentEmail);
+      setMessage({ type: 'success', text: 'Email template saved successfully' });
+      loadSettings();
+    } catch (err) {
+      setMessage({ type: 'error', text: 'Failed to save email template: ' + err.message });
+    } finally {
+      setSaving(false);
+    }
+  }
+
+  async function handleEmailReset() {
+    if (!confirm('Restore the built-in template?')) return;
+    setSaving(true);
+    setMessage(null);
+    try {
+      await settings.updateEmail(emailKind, { enabled: currentEmail.enabled, subject: '', body_text: '', body_html: '' });
+      setMessage({ type: 'success', text: 'Built-in template restored' });
+      loadSettings();
+    } catch (err) {
+      setMessage({ type: 'error', text: 'Failed to restore template: ' + err.message });
+    } finally {
+      setSaving(false);
+    }
+  }
+
+  async function handleEmailPreview() {
+    setMessage(null);
+    try {
+      setEmailPreview(await settings.previewEmail(emailKind, currentEmail));
+    } catch (err) {
+      setMessage({ type: 'error', text: 'Failed to preview email: ' + err.message });
+    }
+  }
+
   async function handleTestEmail(e) {
     e.preventDefault();
     if (!testEmail) {
@@ -70,7 +140,8 @@ export function Settings() {
     }
   }
 
-  const isConfigured = smtpConfig.host && smtpConfig.from_email;
+  const usesSMTP = delivery.backend === 'smtp';
+  const isConfigured = !usesSMTP || (smtpConfig.host && smtpConfig.from_email);
 
   if (loading) {
     return (
@@ -182,10 +253,178 @@ export function Settings() {
         </form>
       </div>
 
+      <div class="bg-white rounded-lg shadow p-6 mt-6 max-w-2xl">
+        <h2 class="text-lg font-semibold mb-4">Delivery Backend</h2>
+        <p class="text-gray-600 text-sm mb-4">
+          Deliver through SMTP, or use another backend for staging and development so no real emails go out.
+        </p>
+        <form onSubmit={handleDeliverySubmit}>
+          <div class="grid grid-cols-2 gap-4">
+            <div>
+              <label class="block text-sm font-medium mb-1">Backend</label>
+              <select
+                value={delivery.backend}
+                onChange={(e) => setDelivery(prev => ({ ...prev, backend: e.target.value }))}
+                class="w-full border rounded px-3 py-2"
+              >
+                <option value="smtp">SMTP</option>
+                <option value="file">File (.eml files)</option>
+                <option value="log">Log only</option>
+                <option value="http">HTTP API</option>
+              </select>
+            </div>
+            {delivery.backend === 'file' && (
+              <div>
+                <label class="block text-sm font-medium mb-1">Directory</label>
+                <input
+                  type="text"
+                  value={delivery.dir}
+                  onInput={(e) => setDelivery(prev => ({ ...prev, dir: e.target.value }))}
+                  class="w-full border rounded px-3 py-2"
+                  placeholder="/var/lib/tinylist/outbox"
+                />
+              </div>
+            )}
+            {delivery.backend === 'http' && (
+              <>
+                <div>
+                  <label class="block text-sm font-medium mb-1">URL</label>
+                  <input
+                    type="url"
+                    value={delivery.url}
+                    onInput={(e) => setDelivery(prev => ({ ...prev, url: e.target.value }))}
+                    class="w-full border rounded px-3 py-2"
+                    placeholder="https://mail.example.com/send"
+                  />
+                </div>
+                <div>
+                  <label class="block text-sm font-medium mb-1">Token</label>
+                  <input
+                    type="password"
+                    value={delivery.token}
+                    onInput={(e) => setDelivery(prev => ({ ...prev, token: e.target.value }))}
+                    class="w-full border rounded px-3 py-2"
+                    placeholder={delivery.token === '***' ? '(unchanged)' : ''}
+                  />
+                </div>
+              </>
+            )}
+          </div>
+          <div class="mt-6">
+            <button
+              type="submit"
+              disabled={saving}
+              class="bg-blue-500 text-white px-4 py-2 rounded hover:bg-blue-600 disabled:opacity-50"
+            >
+              {saving ? 'Saving...' : 'Save Backend'}
+            </button>
+          </div>
+        </form>
+      </div>
+
+      <div class="bg-white rounded-lg shadow p-6 mt-6 max-w-2xl">
+        <h2 class="text-lg font-semibold mb-4">System Emails</h2>
+        <p class="text-gray-600 text-sm mb-4">
+          Emails sent when someone subscribes, verifies their address or unsubscribes. Use <code>{'{{ .Name }}'}</code>,
+          <code>{'{{ .Data.verify_url }}'}</code>, <code>{'{{ .Data.unsubscribe_url }}'}</code> and <code>{'{{ .Data.from_name }}'}</code>.
+        </p>
+        <div class="mb-4">
+          <select
+            value={emailKind}
+            onChange={(e) => { setEmailKind(e.target.value); setEmailPreview(null); }}
+            class="border rounded px-3 py-2"
+          >
+            <option value="verification">Verification</option>
+            <option value="welcome">Welcome (after verification)</option>
+            <option value="goodbye">Goodbye (after unsubscribing)</option>
+          </select>
+          {currentEmail && !currentEmail.custom && (
+            <span class="ml-3 text-sm text-gray-500">Built-in template</span>
+          )}
+        </div>
+        {currentEmail && (
+          <form onSubmit={handleEmailSubmit}>
+            {emailKind !== 'verification' && (
+              <label class="flex items-center gap-2 mb-4">
+                <input
+                  type="checkbox"
+                  checked={currentEmail.enabled}
+                  onChange={(e) => handleEmailChange('enabled', e.target.checked)}
+                />
+                <span class="text-sm font-medium">Send this email</span>
+              </label>
+            )}
+            <div class="mb-4">
+              <label class="block text-sm font-medium mb-1">Subject</label>
+              <input
+                type="text"
+                value={currentEmail.subject}
+                onInput={(e) => handleEmailChange('subject', e.target.value)}
+                class="w-full border rounded px-3 py-2"
+              />
+            </div>
+            <div class="mb-4">
+              <label class="block text-sm font-medium mb-1">Text Body</label>
+              <textarea
+                value={currentEmail.body_text}
+                onInput={(e) => handleEmailChange('body_text', e.target.value)}
+                class="w-full border rounded px-3 py-2 font-mono text-sm"
+                rows={8}
+              />
+            </div>
+            <div class="mb-4">
+              <label class="block text-sm font-medium mb-1">HTML Body (optional)</label>
+              <textarea
+                value={currentEmail.body_html}
+                onInput={(e) => handleEmailChange('body_html', e.target.value)}
+                class="w-full border rounded px-3 py-2 font-mono text-sm"
+                rows={8}
+              />
+            </div>
+            <div class="flex gap-2">
+              <button
+                type="submit"
+                disabled={saving}
+                class="bg-blue-500 text-white px-4 py-2 rounded hover:bg-blue-600 disabled:opacity-50"
+              >
+                {saving ? 'Saving...' : 'Save Email'}
+              </button>
+              <button
+                type="button"
+                onClick={handleEmailPreview}
+                class="border px-4 py-2 rounded hover:bg-gray-50"
+              >
+                Preview
+              </button>
+              {currentEmail.custom && (
+                <button
+                  type="button"
+                  onClick={handleEmailReset}
+                  disabled={saving}
+                  class="text-red-600 px-4 py-2 rounded hover:bg-red-50 disabled:opacity-50"
+                >
+                  Restore Built-in
+                </button>
+              )}
+            </div>
+          </form>
+        )}
+        {emailPreview && (
+          <div class="mt-6 border rounded p-4">
+            <div class="text-sm text-gray-500 mb-2">Subject: <span class="text-gray-900">{emailPreview.subject}</span></div>
+            {emailPreview.body_html ? (
+              <iframe srcDoc={emailPreview.body_html} sandbox="" class="w-full h-96 border rounded" title="Email preview" />
+            ) : (
+              <pre class="whitespace-pre-wrap text-sm">{emailPreview.body_text}</pre>
+            )}
+          </div>
+        )}
+      </div>
+
       <div class="bg-white rounded-lg shadow p-6 mt-6 max-w-2xl">
         <h2 class="text-lg font-semibold mb-4">Test Email</h2>
         <p class="text-gray-600 text-sm mb-4">
-          Send a test email to verify your SMTP configuration is working correctly.
+          Send a test email through the active delivery backend to verify it is working correctly.
         </p>
         <form onSubmit={handleTestEmail} class="flex gap-4">
           <input
@@ -197,13 +436,13 @@ export function Settings() {
           />
           <button
             type="submit"
-            disabled={testing || !smtpConfig.host}
+            disabled={testing || !isConfigured}
             class="bg-green-500 text-white px-4 py-2 rounded hover:bg-green-600 disabled:opacity-50"
           >
             {testing ? 'Sending...' : 'Send Test Email'}
           </button>
         </form>
-        {!smtpConfig.host && (
+        {!isConfigured && (
           <p class="text-yellow-600 text-sm mt-2">
             Configure SMTP settings above before sending a test email.
           </p>
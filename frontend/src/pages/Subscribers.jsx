@@ -6,19 +6,42 @@ export function Subscribers() {
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState(null);
   const [statusFilter, setStatusFilter] = useState('');
+  const [tagFilter, setTagFilter] = useState('');
+  const [search, setSearch] = useState('');
+  const [query, setQuery] = useState('');
+  const [sort, setSort] = useState('created_at:desc');
+  const [tags, setTags] = useState([]);
   const [showAddModal, setShowAddModal] = useState(false);
 
   useEffect(() => {
     loadSubscribers();
-  }, [data.page, statusFilter]);
+  }, [data.page, statusFilter, tagFilter, query, sort]);
+
+  // Search once typing pauses
+  useEffect(() => {
+    const timer = setTimeout(() => {
+      setQuery(search.trim());
+      setData(d => ({ ...d, page: 1 }));
+    }, 300);
+    return () => clearTimeout(timer);
+  }, [search]);
+
+  function filterParams() {
+    const params = {};
+    if (statusFilter) params.status = statusFilter;
+    if (tagFilter) params.tag = tagFilter;
+    if (query) params.q = query;
+    return params;
+  }
 
   async function loadSubscribers() {
     try {
       setLoading(true);
-      const params = { page: data.page, per_page: data.per_page };
-      if (statusFilter) params.status = statusFilter;
-      const result = await subscribers.list(params);
+      const [sortField, order] = sort.split(':');
+      const params = { ...filterParams(), sort: sortField, order, page: data.page, per_page: data.per_page };
+      const [result, tagList] = await Promise.all([subscribers.list(params), subscribers.tags()]);
       setData(result);
+      setTags(tagList);
     } catch (err) {
       setError(err.message);
     } finally {
@@ -36,9 +59,9 @@ export function Subscribers() {
     }
   }
 
-  async function handleAdd(email, name) {
+  async function handleAdd(email, name, attributes) {
     try {
-      await subscribers.create({ email, name });
+      await subscribers.create({ email, name, attributes });
       setShowAddModal(false);
       loadSubscribers();
     } catch (err) {
@@ -46,6 +69,40 @@ export function Subscribers() {
     }
   }
 
+  async function handleAddTag(id) {
+    const tag = prompt('Tag to add');
+    if (!tag) return;
+    try {
+      await subscribers.addTags(id, [tag]);
+      loadSubscribers();
+    } catch (err) {
+      alert('Failed to tag: ' + err.message);
+    }
+  }
+
+  async function handleRemoveTag(id, tag) {
+    try {
+      await subscribers.removeTag(id, tag);
+      loadSubscribers();
+    } catch (err) {
+      alert('Failed to untag: ' + err.message);
+    }
+  }
+
+  async function handleBulkTag(remove) {
+    const tag = prompt(remove ? 'Tag to remove from all matching subscribers' : 'Tag to add to all matching subscribers');
+    if (!tag) return;
+    try {
+      const result = remove
+        ? await subscribers.bulkUntag(tag, filterParams())
+        : await subscribers.bulkTag(tag, filterParams());
+      alert(`${result.count} subscribers ${remove ? 'untagged' : 'tagged'}`);
+      loadSubscribers();
+    } catch (err) {
+      alert('Failed to update tags: ' + err.message);
+    }
+  }
+
   async function handleSendVerification(id) {
     try {
       await subscribers.sendVerification(id);
@@ -68,7 +125,14 @@ export function Subscribers() {
       </div>
 
       {/* Filters */}
-      <div class="mb-4">
+      <div class="mb-4 flex gap-2">
+        <input
+          type="search"
+          value={search}
+          onInput={(e) => setSearch(e.target.value)}
+          placeholder="Search email or name"
+          class="border rounded px-3 py-2 flex-1"
+        />
         <select
           value={statusFilter}
           onChange={(e) => {
@@ -81,7 +145,47 @@ export function Subscribers() {
           <option value="pending">Pending</option>
           <option value="verified">Verified</option>
           <option value="unsubscribed">Unsubscribed</option>
+          <option value="bounced">Bounced</option>
+        </select>
+        <select
+          value={tagFilter}
+          onChange={(e) => {
+            setTagFilter(e.target.value);
+            setData(d => ({ ...d, page: 1 }));
+          }}
+          class="border rounded px-3 py-2"
+        >
+          <option value="">All tags</option>
+          {tags.map(tag => (
+            <option key={tag.name} value={tag.name}>{tag.name} ({tag.subscriber_count})</option>
+          ))}
         </select>
+        <select
+          value={sort}
+          onChange={(e) => {
+            setSort(e.target.value);
+            setData(d => ({ ...d, page: 1 }));
+          }}
+          class="border rounded px-3 py-2"
+        >
+          <option value="created_at:desc">Newest first</option>
+          <option value="created_at:asc">Oldest first</option>
+          <option value="email:asc">Email A-Z</option>
+          <option value="name:asc">Name A-Z</option>
+          <option value="verified_at:desc">Recently verified</option>
+        </select>
+        <button
+          onClick={() => handleBulkTag(false)}
+          class="border rounded px-3 py-2 hover:bg-gray-50"
+        >
+          Tag matching
+        </button>
+        <button
+          onClick={() => handleBulkTag(true)}
+          class="border rounded px-3 py-2 hover:bg-gray-50"
+        >
+          Untag matching
+        </button>
       </div>
 
       {error && <div class="text-red-500 mb-4">Error: {error}</div>}
@@ -94,15 +198,16 @@ export function Subscribers() {
               <th class="px-4 py-3 text-left text-sm font-medium text-gray-500">Email</th>
               <th class="px-4 py-3 text-left text-sm font-medium text-gray-500">Name</th>
               <th class="px-4 py-3 text-left text-sm font-medium text-gray-500">Status</th>
+              <th class="px-4 py-3 text-left text-sm font-medium text-gray-500">Tags</th>
               <th class="px-4 py-3 text-left text-sm font-medium text-gray-500">Created</th>
               <th class="px-4 py-3 text-left text-sm font-medium text-gray-500">Actions</th>
             </tr>
           </thead>
           <tbody class="divide-y">
             {loading ? (
-              <tr><td colspan="5" class="px-4 py-8 text-center text-gray-500">Loading...</td></tr>
+              <tr><td colspan="6" class="px-4 py-8 text-center text-gray-500">Loading...</td></tr>
             ) : data.data.length === 0 ? (
-              <tr><td colspan="5" class="px-4 py-8 text-center text-gray-500">No subscribers found</td></tr>
+              <tr><td colspan="6" class="px-4 py-8 text-center text-gray-500">No subscribers found</td></tr>
             ) : (
               data.data.map(sub => (
                 <tr key={sub.id}>
@@ -111,6 +216,29 @@ export function Subscribers() {
                   <td class="px-4 py-3">
                     <StatusBadge status={sub.status} />
                   </td>
+                  <td class="px-4 py-3">
+                    <div class="flex flex-wrap gap-1">
+                      {sub.tags.map(tag => (
+                        <span key={tag} class="px-2 py-1 rounded-full text-xs bg-blue-100 text-blue-800">
+                          {tag}
+                          <button
+                            onClick={() => handleRemoveTag(sub.id, tag)}
+                            class="ml-1 hover:text-blue-900"
+                            title="Remove tag"
+                          >
+                            ×
+                          </button>
+                        </span>
+                      ))}
+                      <button
+                        onClick={() => handleAddTag(sub.id)}
+                        class="px-2 py-1 rounded-full text-xs border text-gray-500 hover:text-gray-700"
+                        title="Add tag"
+                      >
+                        +
+                      </button>
+                    </div>
+                  </td>
                   <td class="px-4 py-3 text-sm text-gray-500">
                     {new Date(sub.created_at).toLocaleDateString()}
                   </td>
@@ -176,6 +304,7 @@ function StatusBadge({ status }) {
     pending: 'bg-yellow-100 text-yellow-800',
     verified: 'bg-green-100 text-green-800',
     unsubscribed: 'bg-gray-100 text-gray-800',
+    bounced: 'bg-red-100 text-red-800',
   };
 
   return (
@@ -188,10 +317,20 @@ function StatusBadge({ status }) {
 function AddSubscriberModal({ onClose, onAdd }) {
   const [email, setEmail] = useState('');
   const [name, setName] = useState('');
+  const [attributes, setAttributes] = useState('');
 
   function handleSubmit(e) {
     e.preventDefault();
-    onAdd(email, name);
+    let parsed;
+    if (attributes.trim()) {
+      try {
+        parsed = JSON.parse(attributes);
+      } catch {
+        alert('Attributes must be a JSON object');
+        return;
+      }
+    }
+    onAdd(email, name, parsed);
   }
 
   return (
@@ -218,6 +357,16 @@ function AddSubscriberModal({ onClose, onAdd }) {
               class="w-full border rounded px-3 py-2"
             />
           </div>
+          <div class="mb-4">
+            <label class="block text-sm font-medium mb-1">Attributes (optional JSON)</label>
+            <textarea
+              value={attributes}
+              onInput={(e) => setAttributes(e.target.value)}
+              class="w-full border rounded px-3 py-2 font-mono text-sm"
+              rows={3}
+              placeholder='{"city": "Berlin"}'
+            />
+          </div>
           <div class="flex justify-end gap-2">
             <button
               type="button"
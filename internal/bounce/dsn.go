@@ -0,0 +1,187 @@
+// Package bounce parses delivery status notifications and reads them from mailboxes
+package bounce
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// CampaignHeader identifies the campaign of an outgoing email, so bounces can be
+// traced back to it from the returned original message
+const CampaignHeader = "X-TinyList-Campaign"
+
+// ErrNotDSN is returned for messages that are not delivery status notifications
+var ErrNotDSN = errors.New("message is not a delivery status notification")
+
+// Parse reads a DSN (RFC 3464) message and returns a bounce for every failed or
+// delayed recipient. Type, Email, Reason and, if the original message is
+// included, CampaignUUID are set; the caller sets Source.
+func Parse(r io.Reader) ([]*models.Bounce, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	var report dsnReport
+	if err := report.walk(textproto.MIMEHeader(msg.Header), msg.Body); err != nil {
+		return nil, err
+	}
+	if !report.found {
+		return nil, ErrNotDSN
+	}
+
+	bounces := make([]*models.Bounce, 0, len(report.recipients))
+	for _, rcpt := range report.recipients {
+		rcpt.CampaignUUID = report.campaign
+		bounces = append(bounces, rcpt)
+	}
+	return bounces, nil
+}
+
+// dsnReport collects the parts of a DSN while walking its MIME tree
+type dsnReport struct {
+	found      bool
+	recipients []*models.Bounce
+	campaign   *string
+}
+
+// walk descends into multipart bodies and picks up the delivery status and
+// original message parts
+func (d *dsnReport) walk(header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// Not being able to parse a part's type shouldn't discard the whole report
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid multipart message: %w", err)
+			}
+			if err := d.walk(part.Header, part); err != nil {
+				return err
+			}
+		}
+
+	case mediaType == "message/delivery-status":
+		d.found = true
+		return d.parseStatus(body)
+
+	case mediaType == "message/rfc822" || mediaType == "text/rfc822-headers":
+		original, err := textproto.NewReader(bufio.NewReader(body)).ReadMIMEHeader()
+		if err != nil && len(original) == 0 {
+			return nil
+		}
+		if campaign := strings.TrimSpace(original.Get(CampaignHeader)); campaign != "" {
+			d.campaign = &campaign
+		}
+	}
+	return nil
+}
+
+// parseStatus reads the per-message and per-recipient field groups of a
+// message/delivery-status part
+func (d *dsnReport) parseStatus(body io.Reader) error {
+	reader := textproto.NewReader(bufio.NewReader(body))
+
+	// The first group describes the message, the following ones a recipient each
+	if _, err := reader.ReadMIMEHeader(); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return fmt.Errorf("invalid delivery status: %w", err)
+	}
+
+	for {
+		fields, err := reader.ReadMIMEHeader()
+		if len(fields) > 0 {
+			if bounce := recipientBounce(fields); bounce != nil {
+				d.recipients = append(d.recipients, bounce)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid delivery status: %w", err)
+		}
+	}
+}
+
+// recipientBounce classifies a per-recipient field group, returning nil for
+// recipients that did not fail
+func recipientBounce(fields textproto.MIMEHeader) *models.Bounce {
+	email := addressField(fields.Get("Final-Recipient"))
+	if email == "" {
+		email = addressField(fields.Get("Original-Recipient"))
+	}
+	if email == "" {
+		return nil
+	}
+
+	action := strings.ToLower(strings.TrimSpace(fields.Get("Action")))
+	status := strings.TrimSpace(fields.Get("Status"))
+	if i := strings.IndexAny(status, " \t("); i != -1 {
+		status = status[:i]
+	}
+
+	var bounceType string
+	switch action {
+	case "failed":
+		bounceType = Classify(status)
+	case "delayed":
+		bounceType = models.BounceSoft
+	default:
+		// delivered, relayed and expanded are not failures
+		return nil
+	}
+
+	reason := status
+	if diagnostic := strings.TrimSpace(fields.Get("Diagnostic-Code")); diagnostic != "" {
+		reason = strings.TrimSpace(reason + " " + diagnostic)
+	}
+
+	return &models.Bounce{
+		Email:  email,
+		Type:   bounceType,
+		Reason: reason,
+	}
+}
+
+// Classify maps an enhanced status code (RFC 3463) of a failed delivery to a bounce type.
+// Permanent failures are hard bounces, except for a full mailbox, which usually clears up.
+func Classify(status string) string {
+	switch {
+	case strings.HasPrefix(status, "4."):
+		return models.BounceSoft
+	case strings.HasPrefix(status, "5.") && strings.HasSuffix(status, ".2.2"):
+		return models.BounceSoft
+	default:
+		return models.BounceHard
+	}
+}
+
+// addressField extracts the address from a "type; address" recipient field
+func addressField(value string) string {
+	if i := strings.Index(value, ";"); i != -1 {
+		value = value[i+1:]
+	}
+	value = strings.Trim(strings.TrimSpace(value), "<>")
+	return strings.ToLower(value)
+}
@@ -0,0 +1,131 @@
+package bounce
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadMaildir hands every message in the new/ folder of a Maildir to fn. Each
+// message is moved to cur/ before fn reads it, so a message is never handled
+// twice, even if something goes wrong afterwards. If fn returns an error the
+// message is moved back to new/ and reading stops.
+func ReadMaildir(dir string, fn func(io.Reader) error) error {
+	entries, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		return fmt.Errorf("failed to read maildir: %w", err)
+	}
+	// Some delivery agents only create cur/ once a client needs it
+	if err := os.MkdirAll(filepath.Join(dir, "cur"), 0o755); err != nil {
+		return fmt.Errorf("failed to create maildir cur/: %w", err)
+	}
+
+	// Oldest first, Maildir names start with the delivery timestamp
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// Mark as seen, as a mail client would
+		path := filepath.Join(dir, "new", name)
+		seen := filepath.Join(dir, "cur", name+":2,S")
+		if err := os.Rename(path, seen); err != nil {
+			return fmt.Errorf("failed to move message %s: %w", name, err)
+		}
+
+		file, err := os.Open(seen)
+		if err == nil {
+			err = fn(file)
+			file.Close()
+		} else {
+			err = fmt.Errorf("failed to open message %s: %w", name, err)
+		}
+		if err != nil {
+			if moveErr := os.Rename(seen, path); moveErr != nil {
+				return fmt.Errorf("%w (and failed to move message %s back: %v)", err, name, moveErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadMbox hands every message in an mbox file after offset to fn and returns the
+// offset to continue from next time. The file itself is never modified. If the file
+// is shorter than offset it is assumed to have been rotated and is read from the
+// start. If fn returns an error, the returned offset points at that message.
+func ReadMbox(path string, offset int64, fn func(io.Reader) error) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return offset, fmt.Errorf("failed to open mbox: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return offset, fmt.Errorf("failed to stat mbox: %w", err)
+	}
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, fmt.Errorf("failed to seek mbox: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	var message bytes.Buffer
+	start, pos := offset, offset
+	inMessage := false
+
+	flush := func() error {
+		if inMessage && message.Len() > 0 {
+			if err := fn(bytes.NewReader(message.Bytes())); err != nil {
+				return err
+			}
+		}
+		message.Reset()
+		return nil
+	}
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if len(line) > 0 {
+			lineStart := pos
+			pos += int64(len(line))
+			if strings.HasPrefix(line, "From ") {
+				// Separator line, the previous message is complete
+				if err := flush(); err != nil {
+					return start, err
+				}
+				start = lineStart
+				inMessage = true
+			} else if inMessage {
+				// Lines starting with "From " are escaped as ">From " in message bodies
+				if strings.HasPrefix(line, ">") && strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+					line = line[1:]
+				}
+				message.WriteString(line)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return start, fmt.Errorf("failed to read mbox: %w", readErr)
+		}
+	}
+
+	if err := flush(); err != nil {
+		return start, err
+	}
+	return pos, nil
+}
@@ -5,16 +5,20 @@ import (
 	"os"
 	"time"
 
+	"github.com/zhisme/tinylist/internal/models"
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds all configuration for the application
 // Note: SMTP settings are configured via admin UI and stored in database
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Sending  SendingConfig  `yaml:"sending"`
-	Auth     AuthConfig     `yaml:"auth"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Sending   SendingConfig   `yaml:"sending"`
+	Bounce    BounceConfig    `yaml:"bounce"`
+	Backup    BackupConfig    `yaml:"backup"`
+	Subscribe SubscribeConfig `yaml:"subscribe"`
+	Auth      AuthConfig      `yaml:"auth"`
 }
 
 type AuthConfig struct {
@@ -30,14 +34,49 @@ type ServerConfig struct {
 }
 
 type DatabaseConfig struct {
-	Path string `yaml:"path"`
+	Path            string `yaml:"path"`
+	JournalMode     string `yaml:"journal_mode"`     // SQLite journal mode: wal, delete, truncate or persist
+	Synchronous     string `yaml:"synchronous"`      // SQLite synchronous level: off, normal, full or extra
+	BusyTimeout     int    `yaml:"busy_timeout"`     // Milliseconds to wait for a lock held by another connection or process
+	ReadConnections int    `yaml:"read_connections"` // Connections serving reads, writes share a single one
+}
+
+// DefaultDatabase returns the database configuration for path with the
+// default tuning: WAL journaling, which lets reads run during a write, and
+// synchronous NORMAL, which is durable enough in WAL mode.
+func DefaultDatabase(path string) DatabaseConfig {
+	return DatabaseConfig{
+		Path:            path,
+		JournalMode:     "wal",
+		Synchronous:     "normal",
+		BusyTimeout:     5000,
+		ReadConnections: 4,
+	}
 }
 
 type SendingConfig struct {
-	RateLimit  int           `yaml:"rate_limit"`  // Emails per second
-	MaxRetries int           `yaml:"max_retries"` // Max retry attempts for failed sends
-	RetryDelay time.Duration `yaml:"-"`           // Delay between retries (parsed from seconds)
-	BatchSize  int           `yaml:"batch_size"`  // Number of subscribers to process at once
+	RateLimit        int           `yaml:"rate_limit"`        // Emails per second
+	MaxRetries       int           `yaml:"max_retries"`       // Max retry attempts for failed sends
+	RetryDelay       time.Duration `yaml:"-"`                 // Delay between retries (parsed from seconds)
+	BatchSize        int           `yaml:"batch_size"`        // Campaign logs saved per transaction, along with the progress counts
+	ScheduleInterval int           `yaml:"schedule_interval"` // Seconds between checks for due scheduled campaigns
+	Connections      int           `yaml:"connections"`       // Parallel SMTP connections, all sharing the rate limit
+}
+
+type BounceConfig struct {
+	Mailbox       string `yaml:"mailbox"`        // Maildir directory or mbox file receiving bounces, empty to disable
+	Interval      int    `yaml:"interval"`       // Seconds between mailbox checks
+	SoftThreshold int    `yaml:"soft_threshold"` // Soft bounces after which a subscriber is marked bounced
+}
+
+type BackupConfig struct {
+	Dir      string `yaml:"dir"`      // Directory receiving periodic database snapshots, empty to disable
+	Interval int    `yaml:"interval"` // Seconds between snapshots
+	Keep     int    `yaml:"keep"`     // Number of snapshots kept, older ones are removed
+}
+
+type SubscribeConfig struct {
+	Attributes []string `yaml:"attributes"` // Attribute names the public subscribe form may set
 }
 
 // Load loads configuration from YAML file
@@ -75,6 +114,48 @@ func (c *Config) Validate() error {
 	if c.Auth.Username == "" {
 		return fmt.Errorf("auth.username is required")
 	}
+	switch c.Database.JournalMode {
+	case "wal", "delete", "truncate", "persist":
+	default:
+		return fmt.Errorf("database.journal_mode must be wal, delete, truncate or persist")
+	}
+	switch c.Database.Synchronous {
+	case "off", "normal", "full", "extra":
+	default:
+		return fmt.Errorf("database.synchronous must be off, normal, full or extra")
+	}
+	if c.Database.BusyTimeout < 0 {
+		return fmt.Errorf("database.busy_timeout must not be negative")
+	}
+	if c.Database.ReadConnections <= 0 {
+		return fmt.Errorf("database.read_connections must be greater than 0")
+	}
+	if c.Sending.BatchSize <= 0 {
+		return fmt.Errorf("sending.batch_size must be greater than 0")
+	}
+	if c.Sending.ScheduleInterval <= 0 {
+		return fmt.Errorf("sending.schedule_interval must be greater than 0")
+	}
+	if c.Sending.Connections <= 0 {
+		return fmt.Errorf("sending.connections must be greater than 0")
+	}
+	if c.Bounce.Interval <= 0 {
+		return fmt.Errorf("bounce.interval must be greater than 0")
+	}
+	if c.Bounce.SoftThreshold <= 0 {
+		return fmt.Errorf("bounce.soft_threshold must be greater than 0")
+	}
+	if c.Backup.Interval <= 0 {
+		return fmt.Errorf("backup.interval must be greater than 0")
+	}
+	if c.Backup.Keep <= 0 {
+		return fmt.Errorf("backup.keep must be greater than 0")
+	}
+	for _, name := range c.Subscribe.Attributes {
+		if !models.ValidAttributeKey(name) {
+			return fmt.Errorf("subscribe.attributes: invalid attribute name %q", name)
+		}
+	}
 	return nil
 }
 
@@ -87,14 +168,22 @@ func defaultConfig() *Config {
 			PublicURL:   "http://localhost:8080",
 			APIBasePath: "", // Empty = routes at /api/*, set to "/tinylist" for /tinylist/api/*
 		},
-		Database: DatabaseConfig{
-			Path: "./data/tinylist.db",
-		},
+		Database: DefaultDatabase("./data/tinylist.db"),
 		Sending: SendingConfig{
-			RateLimit:  10,
-			MaxRetries: 3,
-			RetryDelay: 5 * time.Second,
-			BatchSize:  100,
+			RateLimit:        10,
+			MaxRetries:       3,
+			RetryDelay:       5 * time.Second,
+			BatchSize:        100,
+			ScheduleInterval: 30,
+			Connections:      1,
+		},
+		Bounce: BounceConfig{
+			Interval:      300,
+			SoftThreshold: 3,
+		},
+		Backup: BackupConfig{
+			Interval: 86400,
+			Keep:     7,
 		},
 		Auth: AuthConfig{
 			Username: "admin",
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"modernc.org/sqlite"
+)
+
+// Backup writes a consistent copy of the database to path while it stays in
+// use. The file must not exist yet. VACUUM INTO only reads the database, but
+// the read pool refuses it, so it runs on a connection of its own rather than
+// holding up writes on the writer.
+func (db *DB) Backup(path string) error {
+	conn, err := sql.Open("sqlite", db.path+"?"+db.params)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// BackupTemp writes a copy of the database to a temporary file next to it,
+// where there is room for a file its size, and returns the path. The caller
+// must remove the file.
+func (db *DB) BackupTemp() (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(db.Path()), ".backup-*.db")
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup file: %w", err)
+	}
+	file.Close()
+	// VACUUM INTO refuses to overwrite, the name was only reserved
+	os.Remove(file.Name())
+
+	if err := db.Backup(file.Name()); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// ValidateSnapshot checks that the file at path is an intact TinyList database
+// that this build can migrate. The file is opened read-only.
+func ValidateSnapshot(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	sqlDB, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	snapshot := &DB{DB: sqlDB, reader: sqlDB, path: path}
+	defer snapshot.Close()
+
+	var result string
+	if err := snapshot.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("snapshot is not a SQLite database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("snapshot is corrupt: %s", result)
+	}
+
+	if err := snapshot.CheckTables(); err != nil {
+		return fmt.Errorf("snapshot is not a TinyList database: %w", err)
+	}
+
+	// Snapshots taken before migrations were versioned have no schema_version table
+	versioned, err := snapshot.tableSQL("schema_version")
+	if err != nil {
+		return err
+	}
+	if versioned != "" {
+		var version int
+		if err := snapshot.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+			return fmt.Errorf("failed to get snapshot schema version: %w", err)
+		}
+		migrations, err := loadMigrations()
+		if err != nil {
+			return err
+		}
+		if version > len(migrations) {
+			return fmt.Errorf("snapshot schema version %d is newer than this build supports (%d)", version, len(migrations))
+		}
+	}
+	return nil
+}
+
+// ErrCampaignsSending is returned by Restore while campaigns are being sent
+var ErrCampaignsSending = errors.New("campaigns are being sent")
+
+// restoreLockKey is the setting that keeps campaigns from starting while the
+// database is restored. The restored data replaces it.
+const restoreLockKey = "restore_started_at"
+
+// notRestoring is the condition under which a campaign may start sending. A
+// lock older than a minute was left by a process that died mid-restore.
+const notRestoring = `NOT EXISTS (SELECT 1 FROM settings
+		WHERE key = '` + restoreLockKey + `' AND value > datetime('now', '-1 minute'))`
+
+// Restore replaces the contents of the database with the snapshot at path
+// using the SQLite backup API, so open connections see the restored data right
+// away. Check the snapshot with ValidateSnapshot first. A copy of the snapshot
+// is migrated before it replaces the database, so the schema is never older
+// than this build expects. It returns ErrCampaignsSending if a campaign is
+// being sent, by any process, and no campaign can start until it's done.
+func (db *DB) Restore(path string) error {
+	prepared, err := db.prepareSnapshot(path)
+	if err != nil {
+		return err
+	}
+	defer removeDatabaseFiles(prepared)
+
+	if err := db.lockForRestore(); err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := db.Exec("DELETE FROM settings WHERE key = ?", restoreLockKey); err != nil {
+			log.Printf("Warning: failed to clear restore lock: %v", err)
+		}
+	}()
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		restorer, ok := driverConn.(interface {
+			NewRestore(srcURI string) (*sqlite.Backup, error)
+		})
+		if !ok {
+			return errors.New("database driver doesn't support restoring")
+		}
+
+		backup, err := restorer.NewRestore(prepared)
+		if err != nil {
+			return fmt.Errorf("failed to start restore: %w", err)
+		}
+		// Copy all pages in one step, so nobody sees a half restored database
+		if _, err := backup.Step(-1); err != nil {
+			backup.Finish()
+			return fmt.Errorf("failed to restore database: %w", err)
+		}
+		if err := backup.Finish(); err != nil {
+			return fmt.Errorf("failed to finish restore: %w", err)
+		}
+		return nil
+	})
+}
+
+// lockForRestore keeps campaigns from starting until the restore lock is
+// cleared, unless one is sending already. Checking and locking is a single
+// statement, so no campaign can start in between.
+func (db *DB) lockForRestore() error {
+	query := `
+		INSERT INTO settings (key, value, updated_at)
+		SELECT ?, datetime('now'), datetime('now')
+		WHERE NOT EXISTS (SELECT 1 FROM campaigns WHERE status = 'sending')
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`
+	result, err := db.Exec(query, restoreLockKey)
+	if err != nil {
+		return fmt.Errorf("failed to lock database for restore: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrCampaignsSending
+	}
+
+	return nil
+}
+
+// prepareSnapshot copies the snapshot at path to a temporary file next to the
+// database, leaving the original untouched, migrates the copy and returns its
+// path. The caller must remove the copy.
+func (db *DB) prepareSnapshot(path string) (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(db.Path()), ".restore-*.db")
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot copy: %w", err)
+	}
+	file.Close()
+	// VACUUM INTO refuses to overwrite, the name was only reserved
+	os.Remove(file.Name())
+
+	snapshot, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return "", fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	_, err = snapshot.Exec("VACUUM INTO ?", file.Name())
+	snapshot.Close()
+	if err != nil {
+		removeDatabaseFiles(file.Name())
+		return "", fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+
+	copied, err := New(file.Name())
+	if err != nil {
+		removeDatabaseFiles(file.Name())
+		return "", err
+	}
+	err = copied.Migrate()
+	copied.Close()
+	if err != nil {
+		removeDatabaseFiles(file.Name())
+		return "", fmt.Errorf("failed to migrate snapshot: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// removeDatabaseFiles removes a database file along with its WAL files
+func removeDatabaseFiles(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(path + suffix)
+	}
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// Bounce queries
+
+// RecordBounce stores a bounce for the subscriber with the bounce's email address
+// and marks the subscriber as bounced on a hard bounce, or once softThreshold soft
+// bounces have been recorded. It returns sql.ErrNoRows if no subscriber has the
+// address, and whether the subscriber was marked as bounced.
+func (db *DB) RecordBounce(bounce *models.Bounce, softThreshold int) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(
+		"SELECT id, uuid, status FROM subscribers WHERE email = ? COLLATE NOCASE", bounce.Email,
+	).Scan(&bounce.SubscriberID, &bounce.SubscriberUUID, &status)
+	if err == sql.ErrNoRows {
+		return false, sql.ErrNoRows
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get subscriber: %w", err)
+	}
+
+	// Unknown campaigns are stored as NULL rather than rejecting the bounce
+	var createdAt string
+	err = tx.QueryRow(`
+		INSERT INTO bounces (subscriber_id, campaign_id, type, source, reason, created_at)
+		VALUES (?, (SELECT id FROM campaigns WHERE uuid = ?), ?, ?, ?, datetime('now'))
+		RETURNING id, created_at
+	`, bounce.SubscriberID, bounce.CampaignUUID, bounce.Type, bounce.Source, bounce.Reason).Scan(&bounce.ID, &createdAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to create bounce: %w", err)
+	}
+	bounce.CreatedAt = parseTime(createdAt)
+
+	// Unsubscribed and already bounced subscribers keep their status
+	marked := false
+	if status == models.StatusPending || status == models.StatusVerified {
+		exceeded := bounce.Type == models.BounceHard
+		if !exceeded {
+			// Bounces from before the address was (re)verified don't count
+			var soft int
+			err := tx.QueryRow(`
+				SELECT COUNT(*) FROM bounces b
+				JOIN subscribers s ON s.id = b.subscriber_id
+				WHERE b.subscriber_id = ? AND b.type = 'soft' AND b.created_at >= COALESCE(s.verified_at, '')
+			`, bounce.SubscriberID).Scan(&soft)
+			if err != nil {
+				return false, fmt.Errorf("failed to count soft bounces: %w", err)
+			}
+			exceeded = soft >= softThreshold
+		}
+
+		if exceeded {
+			_, err := tx.Exec(
+				"UPDATE subscribers SET status = 'bounced', updated_at = datetime('now') WHERE id = ?", bounce.SubscriberID,
+			)
+			if err != nil {
+				return false, fmt.Errorf("failed to mark subscriber as bounced: %w", err)
+			}
+			marked = true
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit bounce: %w", err)
+	}
+	return marked, nil
+}
+
+// ListBounces retrieves bounces with pagination, newest first
+func (db *DB) ListBounces(page, perPage int) ([]*models.Bounce, int, error) {
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM bounces").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count bounces: %w", err)
+	}
+
+	offset := (page - 1) * perPage
+	query := `
+		SELECT b.id, s.id, s.uuid, s.email, c.uuid, b.type, b.source, b.reason, b.created_at
+		FROM bounces b
+		JOIN subscribers s ON s.id = b.subscriber_id
+		LEFT JOIN campaigns c ON c.id = b.campaign_id
+		ORDER BY b.id DESC
+		LIMIT ? OFFSET ?
+	`
+	rows, err := db.Query(query, perPage, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list bounces: %w", err)
+	}
+	defer rows.Close()
+
+	var bounces []*models.Bounce
+	for rows.Next() {
+		var b models.Bounce
+		var createdAt string
+		if err := rows.Scan(
+			&b.ID, &b.SubscriberID, &b.SubscriberUUID, &b.Email, &b.CampaignUUID,
+			&b.Type, &b.Source, &b.Reason, &createdAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan bounce: %w", err)
+		}
+		b.CreatedAt = parseTime(createdAt)
+		bounces = append(bounces, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating bounces: %w", err)
+	}
+
+	return bounces, total, nil
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned for pagination cursors that weren't issued by this package
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the position of the last row of a page. Value holds the sort
+// column of that row, the ID breaks ties between rows sharing a value. Sort
+// names the order the page was listed in, as Value means nothing in another.
+type cursor struct {
+	Sort  string `json:"s,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    int    `json:"id"`
+}
+
+// encodeCursor returns the opaque form of a cursor handed to API clients
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor from encodeCursor that was issued for the
+// sort. An empty string is the start of the first page.
+func decodeCursor(s, sort string) (*cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
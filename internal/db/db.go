@@ -1,51 +1,126 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 
+	"github.com/zhisme/tinylist/internal/config"
 	_ "modernc.org/sqlite"
 )
 
-// DB wraps the database connection
+// DB wraps the database connections. SQLite allows one writer at a time, so
+// Exec, Begin and Conn use a pool of a single connection where writes queue
+// instead of failing with "database is locked". Query and QueryRow use a
+// separate read-only pool, which runs alongside the writer in WAL mode.
 type DB struct {
 	*sql.DB
+	reader *sql.DB
+	path   string
+	params string // Pragmas of both pools, for connections opened outside them
 }
 
-// New creates a new database connection
+// New creates a new database connection with the default tuning
 func New(dbPath string) (*DB, error) {
+	return Open(config.DefaultDatabase(dbPath))
+}
+
+// Open creates a new database connection tuned by cfg
+func Open(cfg config.DatabaseConfig) (*DB, error) {
 	// Ensure directory exists
-	dir := filepath.Dir(dbPath)
+	dir := filepath.Dir(cfg.Path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	// Open database connection
-	sqlDB, err := sql.Open("sqlite", dbPath)
+	// Pragmas are set in the DSN so every pooled connection gets them
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", cfg.BusyTimeout))
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "synchronous("+cfg.Synchronous+")")
+	shared := params.Encode()
+
+	// The writer takes the lock when a transaction begins, a transaction that
+	// reads first could otherwise fail to upgrade without waiting
+	writerParams := url.Values{"_txlock": {"immediate"}}
+	for key, values := range params {
+		writerParams[key] = append([]string(nil), values...)
+	}
+	writerParams.Add("_pragma", "journal_mode("+cfg.JournalMode+")")
+
+	writer, err := openPool(cfg.Path+"?"+writerParams.Encode(), 1)
+	if err != nil {
+		return nil, err
+	}
+
+	// The journal mode is stored in the file, the writer has set it already
+	params.Add("_pragma", "query_only(1)")
+	reader, err := openPool(cfg.Path+"?"+params.Encode(), cfg.ReadConnections)
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+
+	return &DB{DB: writer, reader: reader, path: cfg.Path, params: shared}, nil
+}
+
+// openPool opens a connection pool of at most size connections
+func openPool(dsn string, size int) (*sql.DB, error) {
+	sqlDB, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Test connection
 	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Enable foreign keys
-	if _, err := sqlDB.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
+	sqlDB.SetMaxOpenConns(size)
+	sqlDB.SetMaxIdleConns(size)
+	return sqlDB, nil
+}
+
+// Query runs a query on the read pool
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.reader.Query(query, args...)
+}
+
+// QueryContext runs a query on the read pool
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.reader.QueryContext(ctx, query, args...)
+}
 
-	// Set connection pool settings
-	sqlDB.SetMaxOpenConns(25)
-	sqlDB.SetMaxIdleConns(5)
+// QueryRow runs a query returning at most one row on the read pool
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.reader.QueryRow(query, args...)
+}
+
+// QueryRowContext runs a query returning at most one row on the read pool
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.reader.QueryRowContext(ctx, query, args...)
+}
 
-	return &DB{sqlDB}, nil
+// writeRow runs a statement returning a row, such as an INSERT ... RETURNING,
+// on the writer
+func (db *DB) writeRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(query, args...)
 }
 
-// Close closes the database connection
+// Path returns the path of the database file
+func (db *DB) Path() string {
+	return db.path
+}
+
+// Close closes the database connections
 func (db *DB) Close() error {
-	return db.DB.Close()
+	readErr := db.reader.Close()
+	if err := db.DB.Close(); err != nil {
+		return err
+	}
+	return readErr
 }
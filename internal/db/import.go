@@ -0,0 +1,114 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Import results for a single row
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+)
+
+// ImportRow is a validated subscriber row to import
+type ImportRow struct {
+	Email      string
+	Name       string
+	Status     string                 // Empty keeps the current status of existing rows, new rows become pending
+	Attributes map[string]interface{} // Merged into the attributes of existing rows
+}
+
+// ImportOptions controls how ImportSubscribers treats the rows
+type ImportOptions struct {
+	UpdateExisting bool // Update name, status and attributes of existing subscribers instead of skipping them
+	DryRun         bool // Roll back instead of committing
+	ListID         int  // Add created and updated subscribers to this list, zero for none
+}
+
+// ImportSubscribers creates or updates subscribers in a single transaction and
+// returns the result for each row, in order. With DryRun set the transaction is
+// rolled back, so the results show what a real import would do.
+func (db *DB) ImportSubscribers(rows []ImportRow, opts ImportOptions) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]string, 0, len(rows))
+	for _, row := range rows {
+		attributes, err := marshalAttributes(row.Attributes)
+		if err != nil {
+			return nil, err
+		}
+
+		var id int
+		var status string
+		err = tx.QueryRow("SELECT id, status FROM subscribers WHERE email = ? COLLATE NOCASE", row.Email).Scan(&id, &status)
+		switch {
+		case err == sql.ErrNoRows:
+			status = row.Status
+			if status == "" {
+				status = "pending"
+			}
+			err = tx.QueryRow(`
+				INSERT INTO subscribers (uuid, email, name, status, verify_token, unsubscribe_token, attributes, created_at, verified_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'), CASE WHEN ? = 'verified' THEN datetime('now') END, datetime('now'))
+				RETURNING id
+			`, uuid.New().String(), row.Email, row.Name, status, uuid.New().String(), uuid.New().String(), attributes, status).Scan(&id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to import subscriber %s: %w", row.Email, err)
+			}
+			results = append(results, ImportCreated)
+
+		case err != nil:
+			return nil, fmt.Errorf("failed to look up subscriber %s: %w", row.Email, err)
+
+		case !opts.UpdateExisting:
+			results = append(results, ImportSkipped)
+			continue
+
+		default:
+			if row.Status != "" {
+				status = row.Status
+			}
+			_, err = tx.Exec(`
+				UPDATE subscribers
+				SET name = CASE WHEN ? != '' THEN ? ELSE name END,
+				    status = ?,
+				    attributes = json_patch(attributes, ?),
+				    verified_at = CASE WHEN ? = 'verified' AND status != 'verified' THEN datetime('now') ELSE verified_at END,
+				    updated_at = datetime('now')
+				WHERE id = ?
+			`, row.Name, row.Name, status, attributes, status, id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update subscriber %s: %w", row.Email, err)
+			}
+			results = append(results, ImportUpdated)
+		}
+
+		// Bounced subscribers can't join lists until they have verified again
+		if opts.ListID != 0 && status != "bounced" {
+			_, err = tx.Exec(`
+				INSERT INTO subscriber_lists (subscriber_id, list_id, status, created_at, updated_at)
+				VALUES (?, ?, ?, datetime('now'), datetime('now'))
+				ON CONFLICT(subscriber_id, list_id) DO UPDATE SET status = excluded.status, updated_at = datetime('now')
+			`, id, opts.ListID, status)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add subscriber %s to list: %w", row.Email, err)
+			}
+		}
+	}
+
+	if opts.DryRun {
+		return results, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return results, nil
+}
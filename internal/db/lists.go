@@ -0,0 +1,264 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// List queries
+
+// listColumns lists the columns read by scanList, in order
+const listColumns = `l.id, l.uuid, l.slug, l.name, l.description,
+		       (SELECT COUNT(*) FROM subscriber_lists sl
+		        JOIN subscribers s ON s.id = sl.subscriber_id
+		        WHERE sl.list_id = l.id AND sl.status = 'verified' AND s.status = 'verified'),
+		       l.created_at, l.updated_at`
+
+// scanList scans a row selected with listColumns
+func scanList(row scanner) (*models.List, error) {
+	var l models.List
+	var createdAt, updatedAt string
+	if err := row.Scan(
+		&l.ID, &l.UUID, &l.Slug, &l.Name, &l.Description,
+		&l.SubscriberCount, &createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+	l.CreatedAt = parseTime(createdAt)
+	l.UpdatedAt = parseTime(updatedAt)
+	return &l, nil
+}
+
+// CreateList inserts a new list
+func (db *DB) CreateList(list *models.List) error {
+	query := `
+		INSERT INTO lists (uuid, slug, name, description, created_at, updated_at)
+		VALUES (?, ?, ?, ?, datetime('now'), datetime('now'))
+		RETURNING id, created_at, updated_at
+	`
+	var createdAt, updatedAt string
+	err := db.writeRow(query, list.UUID, list.Slug, list.Name, list.Description).Scan(&list.ID, &createdAt, &updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create list: %w", err)
+	}
+	list.CreatedAt = parseTime(createdAt)
+	list.UpdatedAt = parseTime(updatedAt)
+	return nil
+}
+
+// GetListByUUID retrieves a list by UUID
+func (db *DB) GetListByUUID(uuid string) (*models.List, error) {
+	query := `
+		SELECT ` + listColumns + `
+		FROM lists l
+		WHERE l.uuid = ?
+	`
+	l, err := scanList(db.QueryRow(query, uuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list: %w", err)
+	}
+	return l, nil
+}
+
+// GetListByIdentifier retrieves a list by UUID or slug, as used by public endpoints
+func (db *DB) GetListByIdentifier(identifier string) (*models.List, error) {
+	query := `
+		SELECT ` + listColumns + `
+		FROM lists l
+		WHERE l.uuid = ? OR l.slug = ? COLLATE NOCASE
+	`
+	l, err := scanList(db.QueryRow(query, identifier, identifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list: %w", err)
+	}
+	return l, nil
+}
+
+// ListLists retrieves all lists
+func (db *DB) ListLists() ([]*models.List, error) {
+	query := `
+		SELECT ` + listColumns + `
+		FROM lists l
+		ORDER BY l.name ASC
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lists: %w", err)
+	}
+	defer rows.Close()
+
+	var lists []*models.List
+	for rows.Next() {
+		l, err := scanList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan list: %w", err)
+		}
+		lists = append(lists, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating lists: %w", err)
+	}
+
+	return lists, nil
+}
+
+// UpdateList updates list slug, name, and description
+func (db *DB) UpdateList(list *models.List) error {
+	query := `
+		UPDATE lists
+		SET slug = ?, name = ?, description = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`
+	result, err := db.Exec(query, list.Slug, list.Name, list.Description, list.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update list: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteList permanently deletes a list and its memberships
+func (db *DB) DeleteList(id int) error {
+	query := "DELETE FROM lists WHERE id = ?"
+	result, err := db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete list: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// CountPendingCampaignsForList returns how many unsent campaigns target a list
+func (db *DB) CountPendingCampaignsForList(listID int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM campaign_lists cl
+		JOIN campaigns c ON c.id = cl.campaign_id
+		WHERE cl.list_id = ? AND c.status IN ('draft', 'scheduled', 'sending')
+	`
+	var count int
+	if err := db.QueryRow(query, listID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count campaigns for list: %w", err)
+	}
+	return count, nil
+}
+
+// Membership queries
+
+// GetListMembershipStatus returns a subscriber's status on a list, or "" if not a member
+func (db *DB) GetListMembershipStatus(subscriberID, listID int) (string, error) {
+	query := "SELECT status FROM subscriber_lists WHERE subscriber_id = ? AND list_id = ?"
+	var status string
+	err := db.QueryRow(query, subscriberID, listID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get list membership: %w", err)
+	}
+	return status, nil
+}
+
+// SetListMembership adds a subscriber to a list or updates the membership status
+func (db *DB) SetListMembership(subscriberID, listID int, status string) error {
+	query := `
+		INSERT INTO subscriber_lists (subscriber_id, list_id, status, created_at, updated_at)
+		VALUES (?, ?, ?, datetime('now'), datetime('now'))
+		ON CONFLICT(subscriber_id, list_id) DO UPDATE SET status = excluded.status, updated_at = datetime('now')
+	`
+	if _, err := db.Exec(query, subscriberID, listID, status); err != nil {
+		return fmt.Errorf("failed to set list membership: %w", err)
+	}
+	return nil
+}
+
+// DeleteListMembership removes a subscriber from a list
+func (db *DB) DeleteListMembership(subscriberID, listID int) error {
+	query := "DELETE FROM subscriber_lists WHERE subscriber_id = ? AND list_id = ?"
+	result, err := db.Exec(query, subscriberID, listID)
+	if err != nil {
+		return fmt.Errorf("failed to delete list membership: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdateSubscriberListsStatus moves all of a subscriber's memberships in fromStatus to toStatus
+// and returns how many were changed
+func (db *DB) UpdateSubscriberListsStatus(subscriberID int, fromStatus, toStatus string) (int, error) {
+	query := `
+		UPDATE subscriber_lists
+		SET status = ?, updated_at = datetime('now')
+		WHERE subscriber_id = ? AND status = ?
+	`
+	result, err := db.Exec(query, toStatus, subscriberID, fromStatus)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update list memberships: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(rows), nil
+}
+
+// Campaign target queries
+
+// SetCampaignLists replaces the lists targeted by a campaign
+func (db *DB) SetCampaignLists(campaignID int, listIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM campaign_lists WHERE campaign_id = ?", campaignID); err != nil {
+		return fmt.Errorf("failed to clear campaign lists: %w", err)
+	}
+	if err := setCampaignLists(tx, campaignID, listIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit campaign lists: %w", err)
+	}
+	return nil
+}
+
+// setCampaignLists adds the lists targeted by a campaign within tx
+func setCampaignLists(tx *sql.Tx, campaignID int, listIDs []int) error {
+	for _, listID := range listIDs {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO campaign_lists (campaign_id, list_id) VALUES (?, ?)", campaignID, listID); err != nil {
+			return fmt.Errorf("failed to add campaign list: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,47 +1,223 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
+	"embed"
 	"fmt"
+	"io/fs"
+	"strconv"
 	"strings"
+	"time"
 )
 
-//go:embed schema.sql
-var schemaSQL string
+// Migrations are numbered NNNN_description.sql files, applied in order.
+// Each runs in its own transaction with foreign keys disabled, so a migration
+// can rebuild a table to change its constraints: create the new table, copy
+// the rows, drop the old table, rename the new one and recreate its indexes.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
 
-// Migrate runs database migrations
+// migration is a single embedded schema change
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// MigrationStatus describes a known migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // nil while the migration is pending
+}
+
+// loadMigrations reads the embedded migrations in version order
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		// Versions must be consecutive, so a missing file can't be skipped silently
+		if version != len(migrations)+1 {
+			return nil, fmt.Errorf("migration %s is out of sequence, expected version %d", entry.Name(), len(migrations)+1)
+		}
+
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, migration{version: version, name: name, sql: string(content)})
+	}
+	return migrations, nil
+}
+
+// Migrate applies all migrations newer than the recorded schema version
 func (db *DB) Migrate() error {
-	// Remove SQL comments first
-	lines := strings.Split(schemaSQL, "\n")
-	var cleanLines []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "--") {
-			continue
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	current, err := db.GetSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, len(migrations))
+	}
+
+	// Databases created before migrations were versioned have tables but no
+	// recorded version
+	if current == 0 {
+		if current, err = db.adoptLegacy(migrations); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range migrations[current:] {
+		if err := db.applyMigration(m); err != nil {
+			return err
 		}
-		cleanLines = append(cleanLines, line)
 	}
-	cleanSQL := strings.Join(cleanLines, "\n")
+	return nil
+}
+
+// Migrations returns every known migration with the time it was applied
+func (db *DB) Migrations() ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.GetSchemaVersion(); err != nil {
+		return nil, err
+	}
 
-	// Split schema into individual statements
-	statements := strings.Split(cleanSQL, ";")
+	rows, err := db.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
 
-	// Execute each statement
-	for _, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt == "" {
-			continue
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
 		}
+		applied[version] = parseTime(appliedAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = MigrationStatus{Version: m.version, Name: m.name}
+		if at, ok := applied[m.version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
 
-		if _, err := db.Exec(stmt); err != nil {
-			return fmt.Errorf("migration failed: %w\nStatement: %s", err, stmt)
+// applyMigration runs a migration and records its version in one transaction
+func (db *DB) applyMigration(m migration) error {
+	return db.withoutForeignKeys(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(m.sql); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", m.version, m.name, err)
 		}
+		if _, err := tx.Exec("INSERT INTO schema_version (version) VALUES (?)", m.version); err != nil {
+			return fmt.Errorf("failed to record migration %04d_%s: %w", m.version, m.name, err)
+		}
+		return nil
+	})
+}
+
+// withoutForeignKeys runs fn in a transaction with foreign key enforcement off,
+// which SQLite requires for dropping a table that others reference. Foreign
+// keys are checked before committing, so nothing is left dangling.
+func (db *DB) withoutForeignKeys(fn func(tx *sql.Tx) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Foreign keys must be disabled outside of a transaction, otherwise dropping
+	// the old table would cascade into the tables referencing it
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("failed to disable foreign keys: %w", err)
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	violations := rows.Next()
+	rows.Close()
+	if violations {
+		return fmt.Errorf("schema change would violate foreign keys")
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit schema change: %w", err)
+	}
 	return nil
 }
 
+// adoptLegacy records the baseline as applied to a database created before
+// migrations were versioned and returns its version. Such a database always
+// has the baseline schema, so the numbered migrations after it then run as
+// usual. A new database is left at version 0.
+func (db *DB) adoptLegacy(migrations []migration) (int, error) {
+	existing, err := db.tableSQL("subscribers")
+	if err != nil || existing == "" {
+		return 0, err
+	}
+
+	// The baseline only creates what is missing
+	if err := db.applyMigration(migrations[0]); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// tableSQL returns the CREATE statement of an existing table, or "" if it doesn't exist
+func (db *DB) tableSQL(table string) (string, error) {
+	var stmt string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name=?", table).Scan(&stmt)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read definition of table %s: %w", table, err)
+	}
+	return stmt, nil
+}
+
 // GetSchemaVersion returns the current schema version
 func (db *DB) GetSchemaVersion() (int, error) {
 	// Create schema_version table if it doesn't exist
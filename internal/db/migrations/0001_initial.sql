@@ -1,5 +1,6 @@
 -- TinyList Database Schema
--- SQLite3 schema with 4 simple tables
+-- Baseline schema, as it was before migrations were versioned. Tables use
+-- IF NOT EXISTS so databases created before then can be adopted at this version.
 
 -- subscribers table
 CREATE TABLE IF NOT EXISTS subscribers (
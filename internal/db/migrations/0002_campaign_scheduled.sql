@@ -0,0 +1,27 @@
+-- Campaigns can be scheduled for later sending. SQLite can't change a CHECK
+-- constraint in place, so the table is rebuilt.
+CREATE TABLE campaigns_new (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    subject         TEXT NOT NULL,
+    body_text       TEXT NOT NULL,
+    body_html       TEXT,
+    status          TEXT NOT NULL CHECK(status IN ('draft', 'scheduled', 'sending', 'sent', 'failed', 'cancelled')) DEFAULT 'draft',
+    total_count     INTEGER NOT NULL DEFAULT 0,
+    sent_count      INTEGER NOT NULL DEFAULT 0,
+    failed_count    INTEGER NOT NULL DEFAULT 0,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    scheduled_at    TEXT,
+    started_at      TEXT,
+    completed_at    TEXT
+);
+
+INSERT INTO campaigns_new (id, uuid, subject, body_text, body_html, status, total_count, sent_count, failed_count, created_at, started_at, completed_at)
+SELECT id, uuid, subject, body_text, body_html, status, total_count, sent_count, failed_count, created_at, started_at, completed_at FROM campaigns;
+
+DROP TABLE campaigns;
+ALTER TABLE campaigns_new RENAME TO campaigns;
+
+CREATE INDEX idx_campaigns_status ON campaigns(status);
+CREATE INDEX idx_campaigns_created_at ON campaigns(created_at);
+CREATE INDEX idx_campaigns_scheduled_at ON campaigns(scheduled_at);
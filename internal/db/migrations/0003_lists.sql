@@ -0,0 +1,31 @@
+-- lists table (named mailing lists)
+CREATE TABLE lists (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    slug            TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    name            TEXT NOT NULL,
+    description     TEXT NOT NULL DEFAULT '',
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+-- subscriber_lists (list memberships, each with its own opt-in status)
+CREATE TABLE subscriber_lists (
+    subscriber_id   INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+    list_id         INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+    status          TEXT NOT NULL CHECK(status IN ('pending', 'verified', 'unsubscribed')) DEFAULT 'pending',
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (subscriber_id, list_id)
+);
+
+CREATE INDEX idx_subscriber_lists_list_id ON subscriber_lists(list_id);
+
+-- campaign_lists (lists targeted by a campaign, none means all verified subscribers)
+CREATE TABLE campaign_lists (
+    campaign_id     INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    list_id         INTEGER NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+    PRIMARY KEY (campaign_id, list_id)
+);
+
+CREATE INDEX idx_campaign_lists_list_id ON campaign_lists(list_id);
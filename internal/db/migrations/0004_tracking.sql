@@ -0,0 +1,25 @@
+-- Open and click tracking toggles
+ALTER TABLE campaigns ADD COLUMN track_opens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE campaigns ADD COLUMN track_clicks INTEGER NOT NULL DEFAULT 0;
+
+-- campaign_links (links rewritten for click tracking)
+CREATE TABLE campaign_links (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    campaign_id     INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    url             TEXT NOT NULL,
+    UNIQUE(campaign_id, url)
+);
+
+-- campaign_events (tracked opens and clicks)
+CREATE TABLE campaign_events (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    campaign_id     INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    subscriber_id   INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+    type            TEXT NOT NULL CHECK(type IN ('open', 'click')),
+    link_id         INTEGER REFERENCES campaign_links(id) ON DELETE CASCADE,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX idx_campaign_events_campaign_id ON campaign_events(campaign_id, type);
+CREATE INDEX idx_campaign_events_link_id ON campaign_events(link_id);
@@ -0,0 +1,39 @@
+-- Subscribers can be marked bounced. SQLite can't change a CHECK constraint
+-- in place, so the table is rebuilt.
+CREATE TABLE subscribers_new (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    email           TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    name            TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL CHECK(status IN ('pending', 'verified', 'unsubscribed', 'bounced')) DEFAULT 'pending',
+    verify_token    TEXT UNIQUE,
+    unsubscribe_token TEXT NOT NULL UNIQUE,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    verified_at     TEXT,
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+INSERT INTO subscribers_new (id, uuid, email, name, status, verify_token, unsubscribe_token, created_at, verified_at, updated_at)
+SELECT id, uuid, email, name, status, verify_token, unsubscribe_token, created_at, verified_at, updated_at FROM subscribers;
+
+DROP TABLE subscribers;
+ALTER TABLE subscribers_new RENAME TO subscribers;
+
+CREATE INDEX idx_subscribers_email ON subscribers(email);
+CREATE INDEX idx_subscribers_status ON subscribers(status);
+CREATE INDEX idx_subscribers_verify_token ON subscribers(verify_token);
+CREATE INDEX idx_subscribers_unsubscribe_token ON subscribers(unsubscribe_token);
+
+-- bounces (delivery failures reported after sending)
+CREATE TABLE bounces (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    subscriber_id   INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+    campaign_id     INTEGER REFERENCES campaigns(id) ON DELETE SET NULL,
+    type            TEXT NOT NULL CHECK(type IN ('hard', 'soft')),
+    source          TEXT NOT NULL CHECK(source IN ('webhook', 'mailbox')),
+    reason          TEXT NOT NULL DEFAULT '',
+    created_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX idx_bounces_subscriber_id ON bounces(subscriber_id);
+CREATE INDEX idx_bounces_created_at ON bounces(created_at);
@@ -0,0 +1,29 @@
+-- templates table (stored email templates)
+CREATE TABLE templates (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    name            TEXT NOT NULL,
+    type            TEXT NOT NULL CHECK(type IN ('tx')) DEFAULT 'tx',
+    subject         TEXT NOT NULL DEFAULT '',
+    body_text       TEXT NOT NULL,
+    body_html       TEXT,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+-- tx_logs (transactional emails, keyed by the Idempotency-Key of the request)
+CREATE TABLE tx_logs (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    template_id     INTEGER REFERENCES templates(id) ON DELETE SET NULL,
+    subscriber_id   INTEGER REFERENCES subscribers(id) ON DELETE SET NULL,
+    email           TEXT NOT NULL,
+    idempotency_key TEXT UNIQUE,
+    request_hash    TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL CHECK(status IN ('sending', 'sent', 'failed')),
+    error           TEXT,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX idx_tx_logs_created_at ON tx_logs(created_at);
@@ -0,0 +1,22 @@
+-- Layout templates wrap campaign content. SQLite can't change a CHECK
+-- constraint in place, so the templates table is rebuilt.
+CREATE TABLE templates_new (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    name            TEXT NOT NULL,
+    type            TEXT NOT NULL CHECK(type IN ('tx', 'layout')) DEFAULT 'tx',
+    subject         TEXT NOT NULL DEFAULT '',
+    body_text       TEXT NOT NULL,
+    body_html       TEXT,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+INSERT INTO templates_new (id, uuid, name, type, subject, body_text, body_html, created_at, updated_at)
+SELECT id, uuid, name, type, subject, body_text, body_html, created_at, updated_at FROM templates;
+
+DROP TABLE templates;
+ALTER TABLE templates_new RENAME TO templates;
+
+-- Layout wrapping a campaign
+ALTER TABLE campaigns ADD COLUMN template_id INTEGER REFERENCES templates(id) ON DELETE SET NULL;
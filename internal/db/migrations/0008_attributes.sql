@@ -0,0 +1,2 @@
+-- Custom subscriber fields, a JSON object
+ALTER TABLE subscribers ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}';
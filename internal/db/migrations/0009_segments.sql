@@ -0,0 +1,12 @@
+-- segments table (named subscriber selections, rules is a JSON object)
+CREATE TABLE segments (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    name            TEXT NOT NULL,
+    rules           TEXT NOT NULL DEFAULT '{}',
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Segment targeted by a campaign
+ALTER TABLE campaigns ADD COLUMN segment_id INTEGER REFERENCES segments(id) ON DELETE SET NULL;
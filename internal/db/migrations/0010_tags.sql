@@ -0,0 +1,16 @@
+-- tags table (labels attached to subscribers)
+CREATE TABLE tags (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    name            TEXT NOT NULL UNIQUE,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+-- subscriber_tags (tags attached to each subscriber)
+CREATE TABLE subscriber_tags (
+    subscriber_id   INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+    tag_id          INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (subscriber_id, tag_id)
+);
+
+CREATE INDEX idx_subscriber_tags_tag_id ON subscriber_tags(tag_id);
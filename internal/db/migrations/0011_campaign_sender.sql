@@ -0,0 +1,4 @@
+-- Ownership of sending campaigns: the process sending a campaign keeps
+-- heartbeat_at fresh, others only take it over once it has gone stale
+ALTER TABLE campaigns ADD COLUMN sender_id TEXT;
+ALTER TABLE campaigns ADD COLUMN heartbeat_at TEXT;
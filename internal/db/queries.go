@@ -1,8 +1,13 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/zhisme/tinylist/internal/models"
@@ -23,17 +28,95 @@ func parseTimePtr(s sql.NullString) *time.Time {
 	return &t
 }
 
+// formatTime formats a time.Time as a SQLite datetime string in UTC
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// nullID maps a zero ID to NULL, for optional foreign keys
+func nullID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
 // Subscriber queries
 
+// subscriberColumns lists the columns read by scanSubscriber, in order
+const subscriberColumns = `s.id, s.uuid, s.email, s.name, s.status, s.verify_token, s.unsubscribe_token,
+		       s.attributes, s.created_at, s.verified_at, s.updated_at,
+		       (SELECT GROUP_CONCAT(t.name) FROM subscriber_tags st
+		        JOIN tags t ON t.id = st.tag_id
+		        WHERE st.subscriber_id = s.id)`
+
+// scanSubscriber scans a row selected with subscriberColumns
+func scanSubscriber(row scanner) (*models.Subscriber, error) {
+	var sub models.Subscriber
+	var attributes, createdAt, updatedAt string
+	var verifiedAt, tags sql.NullString
+	if err := row.Scan(
+		&sub.ID, &sub.UUID, &sub.Email, &sub.Name, &sub.Status,
+		&sub.VerifyToken, &sub.UnsubscribeToken,
+		&attributes, &createdAt, &verifiedAt, &updatedAt,
+		&tags,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(attributes), &sub.Attributes); err != nil {
+		return nil, fmt.Errorf("invalid attributes of subscriber %s: %w", sub.UUID, err)
+	}
+	if sub.Attributes == nil {
+		sub.Attributes = map[string]interface{}{}
+	}
+	sub.CreatedAt = parseTime(createdAt)
+	sub.UpdatedAt = parseTime(updatedAt)
+	sub.VerifiedAt = parseTimePtr(verifiedAt)
+	sub.Tags = []string{}
+	if tags.Valid && tags.String != "" {
+		sub.Tags = strings.Split(tags.String, ",")
+		sort.Strings(sub.Tags)
+	}
+	return &sub, nil
+}
+
+// marshalAttributes encodes subscriber attributes for the attributes column
+func marshalAttributes(attributes map[string]interface{}) (string, error) {
+	if len(attributes) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode attributes: %w", err)
+	}
+	return string(data), nil
+}
+
 // CreateSubscriber inserts a new subscriber
 func (db *DB) CreateSubscriber(sub *models.Subscriber) error {
+	attributes, err := marshalAttributes(sub.Attributes)
+	if err != nil {
+		return err
+	}
+	if sub.Attributes == nil {
+		sub.Attributes = map[string]interface{}{}
+	}
+	if sub.Tags == nil {
+		sub.Tags = []string{}
+	}
+
 	query := `
-		INSERT INTO subscribers (uuid, email, name, status, verify_token, unsubscribe_token, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+		INSERT INTO subscribers (uuid, email, name, status, verify_token, unsubscribe_token, attributes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
 		RETURNING id, created_at, updated_at
 	`
 	var createdAt, updatedAt string
-	err := db.QueryRow(query, sub.UUID, sub.Email, sub.Name, sub.Status, sub.VerifyToken, sub.UnsubscribeToken).Scan(&sub.ID, &createdAt, &updatedAt)
+	err = db.writeRow(query, sub.UUID, sub.Email, sub.Name, sub.Status, sub.VerifyToken, sub.UnsubscribeToken, attributes).Scan(&sub.ID, &createdAt, &updatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create subscriber: %w", err)
 	}
@@ -45,137 +128,216 @@ func (db *DB) CreateSubscriber(sub *models.Subscriber) error {
 // GetSubscriberByID retrieves a subscriber by ID
 func (db *DB) GetSubscriberByID(id int) (*models.Subscriber, error) {
 	query := `
-		SELECT id, uuid, email, name, status, verify_token, unsubscribe_token,
-		       created_at, verified_at, updated_at
-		FROM subscribers
+		SELECT ` + subscriberColumns + `
+		FROM subscribers s
 		WHERE id = ?
 	`
-	var sub models.Subscriber
-	var createdAt, updatedAt string
-	var verifiedAt sql.NullString
-	err := db.QueryRow(query, id).Scan(
-		&sub.ID, &sub.UUID, &sub.Email, &sub.Name, &sub.Status,
-		&sub.VerifyToken, &sub.UnsubscribeToken,
-		&createdAt, &verifiedAt, &updatedAt,
-	)
+	sub, err := scanSubscriber(db.QueryRow(query, id))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get subscriber: %w", err)
 	}
-	sub.CreatedAt = parseTime(createdAt)
-	sub.UpdatedAt = parseTime(updatedAt)
-	sub.VerifiedAt = parseTimePtr(verifiedAt)
-	return &sub, nil
+	return sub, nil
 }
 
 // GetSubscriberByUUID retrieves a subscriber by UUID
 func (db *DB) GetSubscriberByUUID(uuid string) (*models.Subscriber, error) {
 	query := `
-		SELECT id, uuid, email, name, status, verify_token, unsubscribe_token,
-		       created_at, verified_at, updated_at
-		FROM subscribers
+		SELECT ` + subscriberColumns + `
+		FROM subscribers s
 		WHERE uuid = ?
 	`
-	var sub models.Subscriber
-	var createdAt, updatedAt string
-	var verifiedAt sql.NullString
-	err := db.QueryRow(query, uuid).Scan(
-		&sub.ID, &sub.UUID, &sub.Email, &sub.Name, &sub.Status,
-		&sub.VerifyToken, &sub.UnsubscribeToken,
-		&createdAt, &verifiedAt, &updatedAt,
-	)
+	sub, err := scanSubscriber(db.QueryRow(query, uuid))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get subscriber: %w", err)
 	}
-	sub.CreatedAt = parseTime(createdAt)
-	sub.UpdatedAt = parseTime(updatedAt)
-	sub.VerifiedAt = parseTimePtr(verifiedAt)
-	return &sub, nil
+	return sub, nil
 }
 
 // GetSubscriberByEmail retrieves a subscriber by email
 func (db *DB) GetSubscriberByEmail(email string) (*models.Subscriber, error) {
 	query := `
-		SELECT id, uuid, email, name, status, verify_token, unsubscribe_token,
-		       created_at, verified_at, updated_at
-		FROM subscribers
+		SELECT ` + subscriberColumns + `
+		FROM subscribers s
 		WHERE email = ? COLLATE NOCASE
 	`
-	var sub models.Subscriber
-	var createdAt, updatedAt string
-	var verifiedAt sql.NullString
-	err := db.QueryRow(query, email).Scan(
-		&sub.ID, &sub.UUID, &sub.Email, &sub.Name, &sub.Status,
-		&sub.VerifyToken, &sub.UnsubscribeToken,
-		&createdAt, &verifiedAt, &updatedAt,
-	)
+	sub, err := scanSubscriber(db.QueryRow(query, email))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get subscriber: %w", err)
 	}
-	sub.CreatedAt = parseTime(createdAt)
-	sub.UpdatedAt = parseTime(updatedAt)
-	sub.VerifiedAt = parseTimePtr(verifiedAt)
-	return &sub, nil
+	return sub, nil
 }
 
 // GetSubscriberByVerifyToken retrieves a subscriber by verification token
 func (db *DB) GetSubscriberByVerifyToken(token string) (*models.Subscriber, error) {
 	query := `
-		SELECT id, uuid, email, name, status, verify_token, unsubscribe_token,
-		       created_at, verified_at, updated_at
-		FROM subscribers
+		SELECT ` + subscriberColumns + `
+		FROM subscribers s
 		WHERE verify_token = ?
 	`
-	var sub models.Subscriber
-	var createdAt, updatedAt string
-	var verifiedAt sql.NullString
-	err := db.QueryRow(query, token).Scan(
-		&sub.ID, &sub.UUID, &sub.Email, &sub.Name, &sub.Status,
-		&sub.VerifyToken, &sub.UnsubscribeToken,
-		&createdAt, &verifiedAt, &updatedAt,
-	)
+	sub, err := scanSubscriber(db.QueryRow(query, token))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get subscriber: %w", err)
 	}
-	sub.CreatedAt = parseTime(createdAt)
-	sub.UpdatedAt = parseTime(updatedAt)
-	sub.VerifiedAt = parseTimePtr(verifiedAt)
-	return &sub, nil
+	return sub, nil
 }
 
 // GetSubscriberByUnsubscribeToken retrieves a subscriber by unsubscribe token
 func (db *DB) GetSubscriberByUnsubscribeToken(token string) (*models.Subscriber, error) {
 	query := `
-		SELECT id, uuid, email, name, status, verify_token, unsubscribe_token,
-		       created_at, verified_at, updated_at
-		FROM subscribers
+		SELECT ` + subscriberColumns + `
+		FROM subscribers s
 		WHERE unsubscribe_token = ?
 	`
-	var sub models.Subscriber
-	var createdAt, updatedAt string
-	var verifiedAt sql.NullString
-	err := db.QueryRow(query, token).Scan(
-		&sub.ID, &sub.UUID, &sub.Email, &sub.Name, &sub.Status,
-		&sub.VerifyToken, &sub.UnsubscribeToken,
-		&createdAt, &verifiedAt, &updatedAt,
-	)
+	sub, err := scanSubscriber(db.QueryRow(query, token))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get subscriber: %w", err)
 	}
-	sub.CreatedAt = parseTime(createdAt)
-	sub.UpdatedAt = parseTime(updatedAt)
-	sub.VerifiedAt = parseTimePtr(verifiedAt)
-	return &sub, nil
+	return sub, nil
 }
 
-// ListSubscribers retrieves subscribers with pagination and filtering
-func (db *DB) ListSubscribers(status string, page, perPage int) ([]*models.Subscriber, int, error) {
-	// Build query with optional status filter
-	whereClause := ""
+// SubscriberFilter narrows down the subscribers returned by ListSubscribers
+type SubscriberFilter struct {
+	Status     string            // Empty means any status
+	ListID     int               // Zero means any list
+	Attributes map[string]string // Attribute name to JSON value, all must match
+	Tags       []string          // Tag names, all must match
+	Query      string            // Substring of email or name, case-insensitive
+	// Created at or after and before, nil means unbounded
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// SubscriberSort orders the subscribers returned by ListSubscribers.
+// The zero value sorts by created_at ascending, use Desc for newest first.
+type SubscriberSort struct {
+	Field string // email, name, created_at or verified_at, empty means created_at
+	Desc  bool
+}
+
+// subscriberSortField is a column subscribers can be sorted by
+type subscriberSortField struct {
+	column string                          // Expression to order by
+	value  func(*models.Subscriber) string // Value of the expression for a subscriber, for cursors
+}
+
+// subscriberSortFields maps sort fields to the columns they order by
+var subscriberSortFields = map[string]subscriberSortField{
+	"email":      {"s.email", func(sub *models.Subscriber) string { return sub.Email }},
+	"name":       {"s.name COLLATE NOCASE", func(sub *models.Subscriber) string { return sub.Name }},
+	"created_at": {"s.created_at", func(sub *models.Subscriber) string { return formatTime(sub.CreatedAt) }},
+	// Subscribers that never verified sort as empty, which is where SQLite puts NULLs
+	"verified_at": {"COALESCE(s.verified_at, '')", func(sub *models.Subscriber) string {
+		if sub.VerifiedAt == nil {
+			return ""
+		}
+		return formatTime(*sub.VerifiedAt)
+	}},
+}
+
+// ValidSubscriberSort reports whether field can be used in SubscriberSort
+func ValidSubscriberSort(field string) bool {
+	_, ok := subscriberSortFields[field]
+	return ok
+}
+
+// field returns the sort field, created_at if it isn't set
+func (o SubscriberSort) field() subscriberSortField {
+	field, ok := subscriberSortFields[o.Field]
+	if !ok {
+		field = subscriberSortFields["created_at"]
+	}
+	return field
+}
+
+// key identifies the sort in cursors, e.g. "email:asc"
+func (o SubscriberSort) key() string {
+	field := o.Field
+	if _, ok := subscriberSortFields[field]; !ok {
+		field = "created_at"
+	}
+	if o.Desc {
+		return field + ":desc"
+	}
+	return field + ":asc"
+}
+
+// orderBy builds the ORDER BY clause for the sort, with the ID as tie breaker
+// so pages stay stable when many subscribers share a value
+func (o SubscriberSort) orderBy() string {
+	direction := "ASC"
+	if o.Desc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s, s.id %s", o.field().column, direction, direction)
+}
+
+// after builds the condition selecting the subscribers sorted after the cursor
+func (o SubscriberSort) after(c *cursor) (string, []interface{}) {
+	op := ">"
+	if o.Desc {
+		op = "<"
+	}
+	column := o.field().column
+	condition := fmt.Sprintf("(%s %s ? OR (%s = ? AND s.id %s ?))", column, op, column, op)
+	return condition, []interface{}{c.Value, c.Value, c.ID}
+}
+
+// where builds the WHERE clause and its arguments for the filter
+func (f SubscriberFilter) where() (string, []interface{}) {
+	var conditions []string
 	args := []interface{}{}
-	if status != "" {
-		whereClause = "WHERE status = ?"
-		args = append(args, status)
+	if f.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, f.Status)
 	}
+	if f.ListID != 0 {
+		conditions = append(conditions, "id IN (SELECT subscriber_id FROM subscriber_lists WHERE list_id = ?)")
+		args = append(args, f.ListID)
+	}
+	for _, key := range sortedKeys(f.Attributes) {
+		conditions = append(conditions, "attributes -> ? = json(?)")
+		args = append(args, "$."+key, f.Attributes[key])
+	}
+	if f.Query != "" {
+		pattern := "%" + likeEscaper.Replace(f.Query) + "%"
+		conditions = append(conditions, `(email LIKE ? ESCAPE '\' OR name LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	if f.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, formatTime(*f.CreatedAfter))
+	}
+	if f.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, formatTime(*f.CreatedBefore))
+	}
+	for _, tag := range f.Tags {
+		conditions = append(conditions, "id IN (SELECT st.subscriber_id FROM subscriber_tags st JOIN tags t ON t.id = st.tag_id WHERE t.name = ?)")
+		args = append(args, tag)
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes the LIKE wildcards in a search query
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// sortedKeys returns the keys of m in order, so generated queries are stable
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ListSubscribers retrieves subscribers with pagination, filtering and sorting
+func (db *DB) ListSubscribers(filter SubscriberFilter, order SubscriberSort, page, perPage int) ([]*models.Subscriber, int, error) {
+	// Build query with optional filters
+	whereClause, args := filter.where()
 
 	// Get total count
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM subscribers %s", whereClause)
@@ -187,13 +349,12 @@ func (db *DB) ListSubscribers(status string, page, perPage int) ([]*models.Subsc
 	// Get paginated results
 	offset := (page - 1) * perPage
 	query := fmt.Sprintf(`
-		SELECT id, uuid, email, name, status, verify_token, unsubscribe_token,
-		       created_at, verified_at, updated_at
-		FROM subscribers
+		SELECT `+subscriberColumns+`
+		FROM subscribers s
+		%s
 		%s
-		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
-	`, whereClause)
+	`, whereClause, order.orderBy())
 	args = append(args, perPage, offset)
 
 	rows, err := db.Query(query, args...)
@@ -204,20 +365,11 @@ func (db *DB) ListSubscribers(status string, page, perPage int) ([]*models.Subsc
 
 	var subscribers []*models.Subscriber
 	for rows.Next() {
-		var sub models.Subscriber
-		var createdAt, updatedAt string
-		var verifiedAt sql.NullString
-		if err := rows.Scan(
-			&sub.ID, &sub.UUID, &sub.Email, &sub.Name, &sub.Status,
-			&sub.VerifyToken, &sub.UnsubscribeToken,
-			&createdAt, &verifiedAt, &updatedAt,
-		); err != nil {
+		sub, err := scanSubscriber(rows)
+		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan subscriber: %w", err)
 		}
-		sub.CreatedAt = parseTime(createdAt)
-		sub.UpdatedAt = parseTime(updatedAt)
-		sub.VerifiedAt = parseTimePtr(verifiedAt)
-		subscribers = append(subscribers, &sub)
+		subscribers = append(subscribers, sub)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -227,6 +379,127 @@ func (db *DB) ListSubscribers(status string, page, perPage int) ([]*models.Subsc
 	return subscribers, total, nil
 }
 
+// ListSubscribersAfter retrieves up to limit subscribers following the cursor,
+// which is empty for the first page. Unlike ListSubscribers it doesn't count all
+// matching rows, and pages don't shift when subscribers are added while paging.
+// next is the cursor of the following page, or empty on the last page.
+func (db *DB) ListSubscribersAfter(filter SubscriberFilter, order SubscriberSort, after string, limit int) (subscribers []*models.Subscriber, next string, err error) {
+	c, err := decodeCursor(after, order.key())
+	if err != nil {
+		return nil, "", err
+	}
+
+	whereClause, args := filter.where()
+	if c != nil {
+		condition, cursorArgs := order.after(c)
+		if whereClause == "" {
+			whereClause = "WHERE " + condition
+		} else {
+			whereClause += " AND " + condition
+		}
+		args = append(args, cursorArgs...)
+	}
+
+	// One extra row tells whether there is a next page
+	query := fmt.Sprintf(`
+		SELECT `+subscriberColumns+`
+		FROM subscribers s
+		%s
+		%s
+		LIMIT ?
+	`, whereClause, order.orderBy())
+	args = append(args, limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSubscriber(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan subscriber: %w", err)
+		}
+		subscribers = append(subscribers, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating subscribers: %w", err)
+	}
+
+	if len(subscribers) > limit {
+		subscribers = subscribers[:limit]
+		last := subscribers[limit-1]
+		next = encodeCursor(cursor{Sort: order.key(), Value: order.field().value(last), ID: last.ID})
+	}
+	return subscribers, next, nil
+}
+
+// ExportSubscribers streams all subscribers matching the filter to fn, oldest first.
+// Rows are read from the cursor one at a time instead of being loaded into memory.
+func (db *DB) ExportSubscribers(ctx context.Context, filter SubscriberFilter, fn func(*models.Subscriber) error) error {
+	whereClause, args := filter.where()
+	query := fmt.Sprintf(`
+		SELECT `+subscriberColumns+`
+		FROM subscribers s
+		%s
+		ORDER BY id ASC
+	`, whereClause)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		sub, err := scanSubscriber(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan subscriber: %w", err)
+		}
+		if err := fn(sub); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating subscribers: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSubscriber updates the name and attributes of a subscriber
+func (db *DB) UpdateSubscriber(sub *models.Subscriber) error {
+	attributes, err := marshalAttributes(sub.Attributes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE subscribers
+		SET name = ?,
+		    attributes = ?,
+		    updated_at = datetime('now')
+		WHERE id = ?
+	`
+	result, err := db.Exec(query, sub.Name, attributes, sub.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update subscriber: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
 // UpdateSubscriberStatus updates subscriber status and verified_at timestamp
 func (db *DB) UpdateSubscriberStatus(id int, status string) error {
 	query := `
@@ -301,9 +574,8 @@ func (db *DB) DeleteSubscriber(id int) error {
 // GetVerifiedSubscribers retrieves all verified subscribers for campaign sending
 func (db *DB) GetVerifiedSubscribers() ([]*models.Subscriber, error) {
 	query := `
-		SELECT id, uuid, email, name, status, verify_token, unsubscribe_token,
-		       created_at, verified_at, updated_at
-		FROM subscribers
+		SELECT ` + subscriberColumns + `
+		FROM subscribers s
 		WHERE status = 'verified'
 		ORDER BY created_at ASC
 	`
@@ -315,20 +587,74 @@ func (db *DB) GetVerifiedSubscribers() ([]*models.Subscriber, error) {
 
 	var subscribers []*models.Subscriber
 	for rows.Next() {
-		var sub models.Subscriber
-		var createdAt, updatedAt string
-		var verifiedAt sql.NullString
-		if err := rows.Scan(
-			&sub.ID, &sub.UUID, &sub.Email, &sub.Name, &sub.Status,
-			&sub.VerifyToken, &sub.UnsubscribeToken,
-			&createdAt, &verifiedAt, &updatedAt,
-		); err != nil {
+		sub, err := scanSubscriber(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscriber: %w", err)
+		}
+		subscribers = append(subscribers, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscribers: %w", err)
+	}
+
+	return subscribers, nil
+}
+
+// GetCampaignRecipients retrieves verified subscribers that have no campaign log entry
+// for the given campaign yet, so an interrupted send can pick up where it left off.
+// When the campaign targets lists, only verified members of those lists are included.
+// When it targets a segment, only subscribers matching the segment rules are included.
+func (db *DB) GetCampaignRecipients(campaignID int) ([]*models.Subscriber, error) {
+	segment := "1"
+	var segmentArgs []interface{}
+	var segmentID int
+	if err := db.QueryRow("SELECT COALESCE(segment_id, 0) FROM campaigns WHERE id = ?", campaignID).Scan(&segmentID); err != nil {
+		return nil, fmt.Errorf("failed to get campaign segment: %w", err)
+	}
+	if segmentID != 0 {
+		seg, err := scanSegment(db.QueryRow("SELECT "+segmentColumns+" FROM segments WHERE id = ?", segmentID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get campaign segment: %w", err)
+		}
+		if segment, segmentArgs, err = segmentCondition(seg.Rules); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		SELECT ` + subscriberColumns + `
+		FROM subscribers s
+		WHERE s.status = 'verified'
+		  AND (
+		      NOT EXISTS (SELECT 1 FROM campaign_lists cl WHERE cl.campaign_id = ?)
+		      OR EXISTS (
+		          SELECT 1 FROM campaign_lists cl
+		          JOIN subscriber_lists sl ON sl.list_id = cl.list_id
+		          WHERE cl.campaign_id = ? AND sl.subscriber_id = s.id AND sl.status = 'verified'
+		      )
+		  )
+		  AND NOT EXISTS (
+		      SELECT 1 FROM campaign_logs l
+		      WHERE l.campaign_id = ? AND l.subscriber_id = s.id
+		  )
+		  AND (` + segment + `)
+		ORDER BY s.created_at ASC
+	`
+	args := append([]interface{}{campaignID, campaignID, campaignID}, segmentArgs...)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var subscribers []*models.Subscriber
+	for rows.Next() {
+		sub, err := scanSubscriber(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan subscriber: %w", err)
 		}
-		sub.CreatedAt = parseTime(createdAt)
-		sub.UpdatedAt = parseTime(updatedAt)
-		sub.VerifiedAt = parseTimePtr(verifiedAt)
-		subscribers = append(subscribers, &sub)
+		subscribers = append(subscribers, sub)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -340,78 +666,111 @@ func (db *DB) GetVerifiedSubscribers() ([]*models.Subscriber, error) {
 
 // Campaign queries
 
+// campaignColumns lists the columns read by scanCampaign, in order
+const campaignColumns = `id, uuid, subject, body_text, body_html, status,
+		       total_count, sent_count, failed_count,
+		       created_at, scheduled_at, started_at, completed_at,
+		       track_opens, track_clicks,
+		       COALESCE(template_id, 0), (SELECT uuid FROM templates WHERE id = campaigns.template_id),
+		       COALESCE(segment_id, 0), (SELECT uuid FROM segments WHERE id = campaigns.segment_id),
+		       (SELECT GROUP_CONCAT(l.uuid) FROM campaign_lists cl
+		        JOIN lists l ON l.id = cl.list_id
+		        WHERE cl.campaign_id = campaigns.id)`
+
+// scanCampaign scans a row selected with campaignColumns
+func scanCampaign(row scanner) (*models.Campaign, error) {
+	var c models.Campaign
+	var createdAt string
+	var scheduledAt, startedAt, completedAt, listIDs sql.NullString
+	if err := row.Scan(
+		&c.ID, &c.UUID, &c.Subject, &c.BodyText, &c.BodyHTML, &c.Status,
+		&c.TotalCount, &c.SentCount, &c.FailedCount,
+		&createdAt, &scheduledAt, &startedAt, &completedAt,
+		&c.TrackOpens, &c.TrackClicks,
+		&c.TemplateID, &c.TemplateUUID,
+		&c.SegmentID, &c.SegmentUUID,
+		&listIDs,
+	); err != nil {
+		return nil, err
+	}
+	c.CreatedAt = parseTime(createdAt)
+	c.ScheduledAt = parseTimePtr(scheduledAt)
+	c.StartedAt = parseTimePtr(startedAt)
+	c.CompletedAt = parseTimePtr(completedAt)
+	c.ListIDs = []string{}
+	if listIDs.Valid && listIDs.String != "" {
+		c.ListIDs = strings.Split(listIDs.String, ",")
+	}
+	return &c, nil
+}
+
 // CreateCampaign inserts a new campaign
 func (db *DB) CreateCampaign(campaign *models.Campaign) error {
+	return db.CreateCampaignWithLists(campaign, nil)
+}
+
+// CreateCampaignWithLists inserts a new campaign targeting the given lists.
+// The campaign and its lists are saved together, a campaign without lists
+// goes to every verified subscriber.
+func (db *DB) CreateCampaignWithLists(campaign *models.Campaign, listIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO campaigns (uuid, subject, body_text, body_html, status, created_at)
-		VALUES (?, ?, ?, ?, ?, datetime('now'))
+		INSERT INTO campaigns (uuid, subject, body_text, body_html, status, track_opens, track_clicks, template_id, segment_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
 		RETURNING id
 	`
-	err := db.QueryRow(query, campaign.UUID, campaign.Subject, campaign.BodyText, campaign.BodyHTML, campaign.Status).Scan(&campaign.ID)
+	err = tx.QueryRow(query, campaign.UUID, campaign.Subject, campaign.BodyText, campaign.BodyHTML, campaign.Status,
+		campaign.TrackOpens, campaign.TrackClicks, nullID(campaign.TemplateID), nullID(campaign.SegmentID)).Scan(&campaign.ID)
 	if err != nil {
 		return fmt.Errorf("failed to create campaign: %w", err)
 	}
+	if err := setCampaignLists(tx, campaign.ID, listIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit campaign: %w", err)
+	}
 	return nil
 }
 
 // GetCampaignByID retrieves a campaign by ID
 func (db *DB) GetCampaignByID(id int) (*models.Campaign, error) {
 	query := `
-		SELECT id, uuid, subject, body_text, body_html, status,
-		       total_count, sent_count, failed_count,
-		       created_at, started_at, completed_at
+		SELECT ` + campaignColumns + `
 		FROM campaigns
 		WHERE id = ?
 	`
-	var c models.Campaign
-	var createdAt string
-	var startedAt, completedAt sql.NullString
-	err := db.QueryRow(query, id).Scan(
-		&c.ID, &c.UUID, &c.Subject, &c.BodyText, &c.BodyHTML, &c.Status,
-		&c.TotalCount, &c.SentCount, &c.FailedCount,
-		&createdAt, &startedAt, &completedAt,
-	)
+	c, err := scanCampaign(db.QueryRow(query, id))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get campaign: %w", err)
 	}
-	c.CreatedAt = parseTime(createdAt)
-	c.StartedAt = parseTimePtr(startedAt)
-	c.CompletedAt = parseTimePtr(completedAt)
-	return &c, nil
+	return c, nil
 }
 
 // GetCampaignByUUID retrieves a campaign by UUID
 func (db *DB) GetCampaignByUUID(uuid string) (*models.Campaign, error) {
 	query := `
-		SELECT id, uuid, subject, body_text, body_html, status,
-		       total_count, sent_count, failed_count,
-		       created_at, started_at, completed_at
+		SELECT ` + campaignColumns + `
 		FROM campaigns
 		WHERE uuid = ?
 	`
-	var c models.Campaign
-	var createdAt string
-	var startedAt, completedAt sql.NullString
-	err := db.QueryRow(query, uuid).Scan(
-		&c.ID, &c.UUID, &c.Subject, &c.BodyText, &c.BodyHTML, &c.Status,
-		&c.TotalCount, &c.SentCount, &c.FailedCount,
-		&createdAt, &startedAt, &completedAt,
-	)
+	c, err := scanCampaign(db.QueryRow(query, uuid))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get campaign: %w", err)
 	}
-	c.CreatedAt = parseTime(createdAt)
-	c.StartedAt = parseTimePtr(startedAt)
-	c.CompletedAt = parseTimePtr(completedAt)
-	return &c, nil
+	return c, nil
 }
 
 // ListCampaigns retrieves all campaigns
 func (db *DB) ListCampaigns() ([]*models.Campaign, error) {
 	query := `
-		SELECT id, uuid, subject, body_text, body_html, status,
-		       total_count, sent_count, failed_count,
-		       created_at, started_at, completed_at
+		SELECT ` + campaignColumns + `
 		FROM campaigns
 		ORDER BY created_at DESC
 	`
@@ -423,20 +782,93 @@ func (db *DB) ListCampaigns() ([]*models.Campaign, error) {
 
 	var campaigns []*models.Campaign
 	for rows.Next() {
-		var c models.Campaign
-		var createdAt string
-		var startedAt, completedAt sql.NullString
-		if err := rows.Scan(
-			&c.ID, &c.UUID, &c.Subject, &c.BodyText, &c.BodyHTML, &c.Status,
-			&c.TotalCount, &c.SentCount, &c.FailedCount,
-			&createdAt, &startedAt, &completedAt,
-		); err != nil {
+		c, err := scanCampaign(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan campaign: %w", err)
 		}
-		c.CreatedAt = parseTime(createdAt)
-		c.StartedAt = parseTimePtr(startedAt)
-		c.CompletedAt = parseTimePtr(completedAt)
-		campaigns = append(campaigns, &c)
+		campaigns = append(campaigns, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// ListCampaignsAfter retrieves up to limit campaigns, newest first, following
+// the cursor, which is empty for the first page. next is the cursor of the
+// following page, or empty on the last page.
+func (db *DB) ListCampaignsAfter(after string, limit int) (campaigns []*models.Campaign, next string, err error) {
+	c, err := decodeCursor(after, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	whereClause := ""
+	var args []interface{}
+	if c != nil {
+		whereClause = "WHERE (created_at < ? OR (created_at = ? AND id < ?))"
+		args = append(args, c.Value, c.Value, c.ID)
+	}
+
+	// One extra row tells whether there is a next page
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		` + whereClause + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+	args = append(args, limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		campaigns = append(campaigns, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating campaigns: %w", err)
+	}
+
+	if len(campaigns) > limit {
+		campaigns = campaigns[:limit]
+		last := campaigns[limit-1]
+		next = encodeCursor(cursor{Value: formatTime(last.CreatedAt), ID: last.ID})
+	}
+	return campaigns, next, nil
+}
+
+// ListCampaignsByStatus retrieves all campaigns with the given status
+func (db *DB) ListCampaignsByStatus(status string) ([]*models.Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		WHERE status = ?
+		ORDER BY created_at ASC
+	`
+	rows, err := db.Query(query, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	var campaigns []*models.Campaign
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		campaigns = append(campaigns, c)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -471,14 +903,249 @@ func (db *DB) UpdateCampaignStatus(id int, status string) error {
 	return nil
 }
 
-// UpdateCampaign updates campaign subject, body_text, and body_html
+// GetCampaignStatus returns just the status of a campaign
+func (db *DB) GetCampaignStatus(id int) (string, error) {
+	var status string
+	if err := db.QueryRow("SELECT status FROM campaigns WHERE id = ?", id).Scan(&status); err != nil {
+		return "", fmt.Errorf("failed to get campaign status: %w", err)
+	}
+	return status, nil
+}
+
+// StartCampaign moves a draft or scheduled campaign to sending, owned by
+// senderID. It returns sql.ErrNoRows if the campaign is in any other status,
+// so two processes can't both start sending it, or the database is being
+// restored.
+func (db *DB) StartCampaign(id int, senderID string) error {
+	return db.startCampaign(id, senderID, "status IN ('draft', 'scheduled')")
+}
+
+// StartScheduledCampaign moves a scheduled campaign that is due to sending,
+// owned by senderID. It returns sql.ErrNoRows if the campaign has been
+// unscheduled, rescheduled for later or started since it was found due.
+func (db *DB) StartScheduledCampaign(id int, senderID string) error {
+	return db.startCampaign(id, senderID, "status = 'scheduled' AND scheduled_at <= datetime('now')")
+}
+
+// startCampaign moves a campaign matching condition to sending
+func (db *DB) startCampaign(id int, senderID, condition string) error {
+	query := `
+		UPDATE campaigns
+		SET status = 'sending', started_at = COALESCE(started_at, datetime('now')),
+		    sender_id = ?, heartbeat_at = datetime('now')
+		WHERE id = ? AND ` + notRestoring + ` AND ` + condition
+	result, err := db.Exec(query, senderID, id)
+	if err != nil {
+		return fmt.Errorf("failed to start campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ClaimCampaign takes over a sending campaign for senderID if its sender's
+// last heartbeat is older than staleBefore. It returns sql.ErrNoRows if the
+// campaign isn't sending, its sender is still alive, so only one process can
+// take it over, or the database is being restored.
+func (db *DB) ClaimCampaign(id int, senderID string, staleBefore time.Time) error {
+	query := `
+		UPDATE campaigns
+		SET sender_id = ?, heartbeat_at = datetime('now')
+		WHERE id = ? AND status = 'sending'
+		  AND (heartbeat_at IS NULL OR heartbeat_at < ?)
+		  AND ` + notRestoring + `
+	`
+	result, err := db.Exec(query, senderID, id, formatTime(staleBefore))
+	if err != nil {
+		return fmt.Errorf("failed to claim campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// HeartbeatCampaign records that senderID is still sending a campaign. It
+// returns sql.ErrNoRows if another process has taken the campaign over.
+func (db *DB) HeartbeatCampaign(id int, senderID string) error {
+	result, err := db.Exec("UPDATE campaigns SET heartbeat_at = datetime('now') WHERE id = ? AND sender_id = ?", id, senderID)
+	if err != nil {
+		return fmt.Errorf("failed to update campaign heartbeat: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetAbandonedCampaigns retrieves sending campaigns whose sender's last
+// heartbeat is older than staleBefore, e.g. because it was restarted mid-send
+func (db *DB) GetAbandonedCampaigns(staleBefore time.Time) ([]*models.Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		WHERE status = 'sending' AND (heartbeat_at IS NULL OR heartbeat_at < ?)
+		ORDER BY started_at ASC
+	`
+	rows, err := db.Query(query, formatTime(staleBefore))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get abandoned campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	var campaigns []*models.Campaign
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		campaigns = append(campaigns, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// CancelSendingCampaign marks a sending campaign as cancelled. The process
+// sending it notices and stops, see CampaignWorker. It returns sql.ErrNoRows
+// if the campaign isn't sending.
+func (db *DB) CancelSendingCampaign(id int) error {
+	result, err := db.Exec("UPDATE campaigns SET status = 'cancelled' WHERE id = ? AND status = 'sending'", id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ScheduleCampaign marks a draft or scheduled campaign to be sent at the given time
+func (db *DB) ScheduleCampaign(id int, at time.Time) error {
+	query := `
+		UPDATE campaigns
+		SET status = 'scheduled', scheduled_at = ?
+		WHERE id = ? AND status IN ('draft', 'scheduled')
+	`
+	result, err := db.Exec(query, formatTime(at), id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UnscheduleCampaign moves a scheduled campaign back to draft
+func (db *DB) UnscheduleCampaign(id int) error {
+	query := `
+		UPDATE campaigns
+		SET status = 'draft', scheduled_at = NULL
+		WHERE id = ? AND status = 'scheduled'
+	`
+	result, err := db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to unschedule campaign: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetDueCampaigns retrieves scheduled campaigns whose send time has passed
+func (db *DB) GetDueCampaigns() ([]*models.Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		WHERE status = 'scheduled' AND scheduled_at <= datetime('now')
+		ORDER BY scheduled_at ASC
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	var campaigns []*models.Campaign
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		campaigns = append(campaigns, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating campaigns: %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// UpdateCampaign updates campaign content and tracking options
 func (db *DB) UpdateCampaign(campaign *models.Campaign) error {
+	return db.UpdateCampaignWithLists(campaign, nil)
+}
+
+// UpdateCampaignWithLists updates campaign content and tracking options and
+// replaces the lists it targets, together. Nil listIDs leave the lists as they
+// are, an empty slice removes them.
+func (db *DB) UpdateCampaignWithLists(campaign *models.Campaign, listIDs []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE campaigns
-		SET subject = ?, body_text = ?, body_html = ?
+		SET subject = ?, body_text = ?, body_html = ?, track_opens = ?, track_clicks = ?, template_id = ?, segment_id = ?
 		WHERE id = ?
 	`
-	result, err := db.Exec(query, campaign.Subject, campaign.BodyText, campaign.BodyHTML, campaign.ID)
+	result, err := tx.Exec(query, campaign.Subject, campaign.BodyText, campaign.BodyHTML,
+		campaign.TrackOpens, campaign.TrackClicks, nullID(campaign.TemplateID), nullID(campaign.SegmentID), campaign.ID)
 	if err != nil {
 		return fmt.Errorf("failed to update campaign: %w", err)
 	}
@@ -491,6 +1158,18 @@ func (db *DB) UpdateCampaign(campaign *models.Campaign) error {
 		return sql.ErrNoRows
 	}
 
+	if listIDs != nil {
+		if _, err := tx.Exec("DELETE FROM campaign_lists WHERE campaign_id = ?", campaign.ID); err != nil {
+			return fmt.Errorf("failed to clear campaign lists: %w", err)
+		}
+		if err := setCampaignLists(tx, campaign.ID, listIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit campaign: %w", err)
+	}
 	return nil
 }
 
@@ -547,43 +1226,144 @@ func (db *DB) CreateCampaignLog(log *models.CampaignLog) error {
 		VALUES (?, ?, ?, ?, datetime('now'))
 		RETURNING id
 	`
-	err := db.QueryRow(query, log.CampaignID, log.SubscriberID, log.Status, log.Error).Scan(&log.ID)
+	err := db.writeRow(query, log.CampaignID, log.SubscriberID, log.Status, log.Error).Scan(&log.ID)
 	if err != nil {
 		return fmt.Errorf("failed to create campaign log: %w", err)
 	}
 	return nil
 }
 
-// GetCampaignLogs retrieves all logs for a campaign
-func (db *DB) GetCampaignLogs(campaignID int) ([]*models.CampaignLog, error) {
+// ErrTakenOver is returned by CreateCampaignLogs when another process has
+// taken over sending the campaign
+var ErrTakenOver = errors.New("campaign was taken over by another sender")
+
+// CreateCampaignLogs inserts a batch of campaign log entries and updates the
+// campaign counters in a single transaction, so the counts never run ahead of
+// the logs. A subscriber already logged for the campaign keeps the first entry.
+// The counters are only updated while senderID sends the campaign, otherwise
+// the logs are saved alone and ErrTakenOver is returned.
+func (db *DB) CreateCampaignLogs(campaignID int, senderID string, logs []*models.CampaignLog, totalCount, sentCount, failedCount int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, log := range logs {
+		sentAt := log.SentAt
+		if sentAt.IsZero() {
+			sentAt = time.Now()
+		}
+		err := tx.QueryRow(`
+			INSERT INTO campaign_logs (campaign_id, subscriber_id, status, error, sent_at)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(campaign_id, subscriber_id) DO NOTHING
+			RETURNING id
+		`, log.CampaignID, log.SubscriberID, log.Status, log.Error, formatTime(sentAt)).Scan(&log.ID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to create campaign log: %w", err)
+		}
+	}
+
+	result, err := tx.Exec(`
+		UPDATE campaigns
+		SET total_count = ?,
+		    sent_count = ?,
+		    failed_count = ?
+		WHERE id = ? AND sender_id = ?
+	`, totalCount, sentCount, failedCount, campaignID, senderID)
+	if err != nil {
+		return fmt.Errorf("failed to update campaign counts: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	// The counts belong to the new sender, who rebuilt them from the logs
+	takenOver := false
+	if rows == 0 {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM campaigns WHERE id = ?)", campaignID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check campaign: %w", err)
+		}
+		if !exists {
+			return sql.ErrNoRows
+		}
+		takenOver = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit campaign logs: %w", err)
+	}
+	if takenOver {
+		return ErrTakenOver
+	}
+	return nil
+}
+
+// GetCampaignLogs retrieves up to limit logs of a campaign, newest first,
+// following the cursor, which is empty for the first page. next is the cursor
+// of the following page, or empty on the last page.
+func (db *DB) GetCampaignLogs(campaignID int, after string, limit int) (logs []*models.CampaignLog, next string, err error) {
+	c, err := decodeCursor(after, "")
+	if err != nil {
+		return nil, "", err
+	}
+	afterID := 0
+	if c != nil {
+		afterID = c.ID
+	}
+
+	// Logs are only ever appended, so the ID alone orders them
 	query := `
-		SELECT id, campaign_id, subscriber_id, status, error, sent_at
-		FROM campaign_logs
-		WHERE campaign_id = ?
-		ORDER BY sent_at DESC
+		SELECT l.id, l.campaign_id, l.subscriber_id, s.uuid, s.email, l.status, l.error, l.sent_at
+		FROM campaign_logs l
+		JOIN subscribers s ON s.id = l.subscriber_id
+		WHERE l.campaign_id = ? AND (? = 0 OR l.id < ?)
+		ORDER BY l.id DESC
+		LIMIT ?
 	`
-	rows, err := db.Query(query, campaignID)
+	rows, err := db.Query(query, campaignID, afterID, afterID, limit+1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get campaign logs: %w", err)
+		return nil, "", fmt.Errorf("failed to get campaign logs: %w", err)
 	}
 	defer rows.Close()
 
-	var logs []*models.CampaignLog
 	for rows.Next() {
 		var log models.CampaignLog
 		var sentAt string
-		if err := rows.Scan(&log.ID, &log.CampaignID, &log.SubscriberID, &log.Status, &log.Error, &sentAt); err != nil {
-			return nil, fmt.Errorf("failed to scan campaign log: %w", err)
+		if err := rows.Scan(&log.ID, &log.CampaignID, &log.SubscriberID, &log.SubscriberUUID, &log.Email, &log.Status, &log.Error, &sentAt); err != nil {
+			return nil, "", fmt.Errorf("failed to scan campaign log: %w", err)
 		}
 		log.SentAt = parseTime(sentAt)
 		logs = append(logs, &log)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating campaign logs: %w", err)
+		return nil, "", fmt.Errorf("error iterating campaign logs: %w", err)
 	}
 
-	return logs, nil
+	if len(logs) > limit {
+		logs = logs[:limit]
+		next = encodeCursor(cursor{ID: logs[limit-1].ID})
+	}
+	return logs, next, nil
+}
+
+// GetCampaignLogCounts returns the number of sent and failed log entries for a campaign
+func (db *DB) GetCampaignLogCounts(campaignID int) (sent, failed int, err error) {
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN status = 'sent' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0)
+		FROM campaign_logs
+		WHERE campaign_id = ?
+	`
+	if err := db.QueryRow(query, campaignID).Scan(&sent, &failed); err != nil {
+		return 0, 0, fmt.Errorf("failed to count campaign logs: %w", err)
+	}
+	return sent, failed, nil
 }
 
 // Settings queries
@@ -648,7 +1428,7 @@ func (db *DB) CreateCampaignJournal(journal *models.CampaignJournal) error {
 		RETURNING id, created_at
 	`
 	var createdAt string
-	err := db.QueryRow(query, journal.CampaignID, journal.EventType, journal.Message).Scan(&journal.ID, &createdAt)
+	err := db.writeRow(query, journal.CampaignID, journal.EventType, journal.Message).Scan(&journal.ID, &createdAt)
 	if err != nil {
 		return fmt.Errorf("failed to create campaign journal: %w", err)
 	}
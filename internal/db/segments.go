@@ -0,0 +1,240 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// Segment queries
+
+// segmentColumns lists the columns read by scanSegment, in order
+const segmentColumns = `id, uuid, name, rules, created_at, updated_at`
+
+// scanSegment scans a row selected with segmentColumns
+func scanSegment(row scanner) (*models.Segment, error) {
+	var seg models.Segment
+	var rules, createdAt, updatedAt string
+	if err := row.Scan(&seg.ID, &seg.UUID, &seg.Name, &rules, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(rules), &seg.Rules); err != nil {
+		return nil, fmt.Errorf("invalid rules of segment %s: %w", seg.UUID, err)
+	}
+	seg.CreatedAt = parseTime(createdAt)
+	seg.UpdatedAt = parseTime(updatedAt)
+	return &seg, nil
+}
+
+// CreateSegment inserts a new segment
+func (db *DB) CreateSegment(seg *models.Segment) error {
+	rules, err := json.Marshal(seg.Rules)
+	if err != nil {
+		return fmt.Errorf("failed to encode segment rules: %w", err)
+	}
+
+	query := `
+		INSERT INTO segments (uuid, name, rules, created_at, updated_at)
+		VALUES (?, ?, ?, datetime('now'), datetime('now'))
+		RETURNING id, created_at, updated_at
+	`
+	var createdAt, updatedAt string
+	if err := db.writeRow(query, seg.UUID, seg.Name, string(rules)).Scan(&seg.ID, &createdAt, &updatedAt); err != nil {
+		return fmt.Errorf("failed to create segment: %w", err)
+	}
+	seg.CreatedAt = parseTime(createdAt)
+	seg.UpdatedAt = parseTime(updatedAt)
+	return nil
+}
+
+// GetSegmentByUUID retrieves a segment by UUID
+func (db *DB) GetSegmentByUUID(uuid string) (*models.Segment, error) {
+	query := "SELECT " + segmentColumns + " FROM segments WHERE uuid = ?"
+	seg, err := scanSegment(db.QueryRow(query, uuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segment: %w", err)
+	}
+	return seg, nil
+}
+
+// ListSegments retrieves all segments
+func (db *DB) ListSegments() ([]*models.Segment, error) {
+	rows, err := db.Query("SELECT " + segmentColumns + " FROM segments ORDER BY name ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list segments: %w", err)
+	}
+	defer rows.Close()
+
+	var segments []*models.Segment
+	for rows.Next() {
+		seg, err := scanSegment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan segment: %w", err)
+		}
+		segments = append(segments, seg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating segments: %w", err)
+	}
+
+	return segments, nil
+}
+
+// UpdateSegment updates segment name and rules
+func (db *DB) UpdateSegment(seg *models.Segment) error {
+	rules, err := json.Marshal(seg.Rules)
+	if err != nil {
+		return fmt.Errorf("failed to encode segment rules: %w", err)
+	}
+
+	query := `
+		UPDATE segments
+		SET name = ?, rules = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`
+	result, err := db.Exec(query, seg.Name, string(rules), seg.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update segment: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteSegment permanently deletes a segment
+func (db *DB) DeleteSegment(id int) error {
+	result, err := db.Exec("DELETE FROM segments WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete segment: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// CountPendingCampaignsForSegment returns how many unsent campaigns target a segment
+func (db *DB) CountPendingCampaignsForSegment(segmentID int) (int, error) {
+	query := "SELECT COUNT(*) FROM campaigns WHERE segment_id = ? AND status IN ('draft', 'scheduled', 'sending')"
+	var count int
+	if err := db.QueryRow(query, segmentID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count campaigns for segment: %w", err)
+	}
+	return count, nil
+}
+
+// CountSegment returns how many subscribers match the rules, and how many of
+// those are verified and would receive a campaign targeting the segment
+func (db *DB) CountSegment(rules models.SegmentRules) (total, verified int, err error) {
+	condition, args, err := segmentCondition(rules)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(s.status = 'verified'), 0)
+		FROM subscribers s
+		WHERE ` + condition
+	if err := db.QueryRow(query, args...).Scan(&total, &verified); err != nil {
+		return 0, 0, fmt.Errorf("failed to count segment: %w", err)
+	}
+	return total, verified, nil
+}
+
+// segmentCondition compiles segment rules into a parameterized SQL condition
+// on the subscribers table, which must be aliased s
+func segmentCondition(rules models.SegmentRules) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+
+	if len(rules.Status) > 0 {
+		conditions = append(conditions, "s.status IN ("+placeholders(len(rules.Status))+")")
+		for _, status := range rules.Status {
+			args = append(args, status)
+		}
+	}
+
+	if len(rules.Lists) > 0 {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM subscriber_lists sl
+			JOIN lists l ON l.id = sl.list_id
+			WHERE sl.subscriber_id = s.id AND sl.status = 'verified'
+			  AND l.uuid IN (`+placeholders(len(rules.Lists))+`))`)
+		for _, list := range rules.Lists {
+			args = append(args, list)
+		}
+	}
+
+	if rules.SubscribedAfter != nil {
+		conditions = append(conditions, "s.created_at >= ?")
+		args = append(args, formatTime(*rules.SubscribedAfter))
+	}
+	if rules.SubscribedBefore != nil {
+		conditions = append(conditions, "s.created_at < ?")
+		args = append(args, formatTime(*rules.SubscribedBefore))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(rules.Attributes)) {
+		if !models.ValidAttributeKey(key) {
+			return "", nil, fmt.Errorf("invalid attribute name %q", key)
+		}
+		value, err := json.Marshal(rules.Attributes[key])
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid value of attribute %s: %w", key, err)
+		}
+		conditions = append(conditions, "s.attributes -> ? = json(?)")
+		args = append(args, "$."+key, string(value))
+	}
+
+	for _, tag := range rules.Tags {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM subscriber_tags st
+			JOIN tags t ON t.id = st.tag_id
+			WHERE st.subscriber_id = s.id AND t.name = ?)`)
+		args = append(args, tag)
+	}
+
+	events := []struct{ eventType, campaign string }{
+		{models.CampaignEventOpen, rules.OpenedCampaign},
+		{models.CampaignEventClick, rules.ClickedCampaign},
+	}
+	for _, event := range events {
+		if event.campaign == "" {
+			continue
+		}
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM campaign_events e
+			JOIN campaigns c ON c.id = e.campaign_id
+			WHERE e.subscriber_id = s.id AND e.type = ? AND c.uuid = ?)`)
+		args = append(args, event.eventType, event.campaign)
+	}
+
+	if len(conditions) == 0 {
+		return "1", args, nil
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// placeholders returns n comma separated query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// Tag queries
+
+// ListTags retrieves all tags with their subscriber counts
+func (db *DB) ListTags() ([]*models.Tag, error) {
+	query := `
+		SELECT t.id, t.name, COUNT(st.subscriber_id), t.created_at
+		FROM tags t
+		LEFT JOIN subscriber_tags st ON st.tag_id = t.id
+		GROUP BY t.id
+		ORDER BY t.name ASC
+	`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []*models.Tag
+	for rows.Next() {
+		var tag models.Tag
+		var createdAt string
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.SubscriberCount, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tag.CreatedAt = parseTime(createdAt)
+		tags = append(tags, &tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// ensureTag returns the ID of the named tag, creating it if needed
+func ensureTag(tx *sql.Tx, name string) (int, error) {
+	if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name, created_at) VALUES (?, datetime('now'))", name); err != nil {
+		return 0, fmt.Errorf("failed to create tag: %w", err)
+	}
+	var id int
+	if err := tx.QueryRow("SELECT id FROM tags WHERE name = ?", name).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get tag: %w", err)
+	}
+	return id, nil
+}
+
+// pruneTags deletes tags no subscriber has anymore
+func pruneTags(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM subscriber_tags)"); err != nil {
+		return fmt.Errorf("failed to prune tags: %w", err)
+	}
+	return nil
+}
+
+// AddSubscriberTags attaches tags to a subscriber. Tags it already has are ignored.
+func (db *DB) AddSubscriberTags(subscriberID int, names []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, name := range names {
+		tagID, err := ensureTag(tx, name)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO subscriber_tags (subscriber_id, tag_id) VALUES (?, ?)", subscriberID, tagID); err != nil {
+			return fmt.Errorf("failed to tag subscriber: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit subscriber tags: %w", err)
+	}
+	return nil
+}
+
+// RemoveSubscriberTag detaches a tag from a subscriber.
+// It returns sql.ErrNoRows if the subscriber doesn't have the tag.
+func (db *DB) RemoveSubscriberTag(subscriberID int, name string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := "DELETE FROM subscriber_tags WHERE subscriber_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)"
+	result, err := tx.Exec(query, subscriberID, name)
+	if err != nil {
+		return fmt.Errorf("failed to untag subscriber: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := pruneTags(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit subscriber tags: %w", err)
+	}
+	return nil
+}
+
+// TagSubscribers attaches a tag to every subscriber matching the filter and
+// returns how many subscribers were newly tagged
+func (db *DB) TagSubscribers(filter SubscriberFilter, name string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	tagID, err := ensureTag(tx, name)
+	if err != nil {
+		return 0, err
+	}
+
+	whereClause, args := filter.where()
+	query := fmt.Sprintf(`
+		INSERT OR IGNORE INTO subscriber_tags (subscriber_id, tag_id)
+		SELECT id, ? FROM subscribers s %s
+	`, whereClause)
+	result, err := tx.Exec(query, append([]interface{}{tagID}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to tag subscribers: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	// A filter matching nobody must not leave an unused tag behind
+	if err := pruneTags(tx); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit subscriber tags: %w", err)
+	}
+	return count, nil
+}
+
+// UntagSubscribers detaches a tag from every subscriber matching the filter and
+// returns how many subscribers had it
+func (db *DB) UntagSubscribers(filter SubscriberFilter, name string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	whereClause, args := filter.where()
+	query := fmt.Sprintf(`
+		DELETE FROM subscriber_tags
+		WHERE tag_id = (SELECT id FROM tags WHERE name = ?)
+		  AND subscriber_id IN (SELECT id FROM subscribers s %s)
+	`, whereClause)
+	result, err := tx.Exec(query, append([]interface{}{name}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to untag subscribers: %w", err)
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := pruneTags(tx); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit subscriber tags: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// Template queries
+
+// templateColumns lists the columns read by scanTemplate, in order
+const templateColumns = `id, uuid, name, type, subject, body_text, body_html, created_at, updated_at`
+
+// scanTemplate scans a row selected with templateColumns
+func scanTemplate(row scanner) (*models.Template, error) {
+	var t models.Template
+	var bodyHTML sql.NullString
+	var createdAt, updatedAt string
+	if err := row.Scan(
+		&t.ID, &t.UUID, &t.Name, &t.Type, &t.Subject, &t.BodyText, &bodyHTML,
+		&createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if bodyHTML.Valid {
+		t.BodyHTML = &bodyHTML.String
+	}
+	t.CreatedAt = parseTime(createdAt)
+	t.UpdatedAt = parseTime(updatedAt)
+	return &t, nil
+}
+
+// CreateTemplate inserts a new template
+func (db *DB) CreateTemplate(t *models.Template) error {
+	query := `
+		INSERT INTO templates (uuid, name, type, subject, body_text, body_html, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+		RETURNING id, created_at, updated_at
+	`
+	var createdAt, updatedAt string
+	err := db.writeRow(query, t.UUID, t.Name, t.Type, t.Subject, t.BodyText, t.BodyHTML).Scan(&t.ID, &createdAt, &updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+	t.CreatedAt = parseTime(createdAt)
+	t.UpdatedAt = parseTime(updatedAt)
+	return nil
+}
+
+// GetTemplateByUUID retrieves a template by UUID
+func (db *DB) GetTemplateByUUID(uuid string) (*models.Template, error) {
+	query := "SELECT " + templateColumns + " FROM templates WHERE uuid = ?"
+	t, err := scanTemplate(db.QueryRow(query, uuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return t, nil
+}
+
+// GetTemplateByID retrieves a template by ID
+func (db *DB) GetTemplateByID(id int) (*models.Template, error) {
+	query := "SELECT " + templateColumns + " FROM templates WHERE id = ?"
+	t, err := scanTemplate(db.QueryRow(query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return t, nil
+}
+
+// ListTemplates retrieves all templates, optionally only those of one type
+func (db *DB) ListTemplates(templateType string) ([]*models.Template, error) {
+	query := "SELECT " + templateColumns + " FROM templates WHERE ? = '' OR type = ? ORDER BY name ASC"
+	rows, err := db.Query(query, templateType, templateType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []*models.Template
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// UpdateTemplate updates the name, subject and bodies of a template
+func (db *DB) UpdateTemplate(t *models.Template) error {
+	query := `
+		UPDATE templates
+		SET name = ?, subject = ?, body_text = ?, body_html = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`
+	result, err := db.Exec(query, t.Name, t.Subject, t.BodyText, t.BodyHTML, t.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// CountPendingCampaignsForTemplate returns how many unsent campaigns use a template as layout
+func (db *DB) CountPendingCampaignsForTemplate(templateID int) (int, error) {
+	query := "SELECT COUNT(*) FROM campaigns WHERE template_id = ? AND status IN ('draft', 'scheduled', 'sending')"
+	var count int
+	if err := db.QueryRow(query, templateID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count campaigns for template: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteTemplate permanently deletes a template. Logs of emails sent with it are kept.
+func (db *DB) DeleteTemplate(id int) error {
+	result, err := db.Exec("DELETE FROM templates WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// Tracking queries
+
+// RegisterCampaignLinks stores the tracked links of a campaign and returns a map
+// of URL to link UUID. Links registered by an earlier run keep their UUID.
+func (db *DB) RegisterCampaignLinks(campaignID int, urls []string) (map[string]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, url := range urls {
+		_, err := tx.Exec(
+			"INSERT OR IGNORE INTO campaign_links (uuid, campaign_id, url) VALUES (?, ?, ?)",
+			uuid.New().String(), campaignID, url,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register campaign link: %w", err)
+		}
+	}
+
+	rows, err := tx.Query("SELECT uuid, url FROM campaign_links WHERE campaign_id = ?", campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign links: %w", err)
+	}
+	defer rows.Close()
+
+	links := make(map[string]string)
+	for rows.Next() {
+		var linkUUID, url string
+		if err := rows.Scan(&linkUUID, &url); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign link: %w", err)
+		}
+		links[url] = linkUUID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating campaign links: %w", err)
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit campaign links: %w", err)
+	}
+	return links, nil
+}
+
+// GetCampaignLinkByUUID retrieves a tracked link by UUID
+func (db *DB) GetCampaignLinkByUUID(uuid string) (*models.CampaignLink, error) {
+	var link models.CampaignLink
+	err := db.QueryRow(
+		"SELECT id, uuid, campaign_id, url FROM campaign_links WHERE uuid = ?", uuid,
+	).Scan(&link.ID, &link.UUID, &link.CampaignID, &link.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign link: %w", err)
+	}
+	return &link, nil
+}
+
+// CreateCampaignEvent records an open or click. linkID is zero for opens.
+func (db *DB) CreateCampaignEvent(campaignID, subscriberID int, eventType string, linkID int) error {
+	query := `
+		INSERT INTO campaign_events (campaign_id, subscriber_id, type, link_id, created_at)
+		VALUES (?, ?, ?, NULLIF(?, 0), datetime('now'))
+	`
+	if _, err := db.Exec(query, campaignID, subscriberID, eventType, linkID); err != nil {
+		return fmt.Errorf("failed to create campaign event: %w", err)
+	}
+	return nil
+}
+
+// GetCampaignStats returns open and click statistics of a campaign.
+// Opens and clicks count distinct subscribers, repeated events are ignored.
+func (db *DB) GetCampaignStats(campaignID, sentCount int) (*models.CampaignStats, error) {
+	stats := &models.CampaignStats{Links: []*models.LinkStats{}}
+	query := `
+		SELECT
+		    COUNT(DISTINCT CASE WHEN type = 'open' THEN subscriber_id END),
+		    COUNT(DISTINCT CASE WHEN type = 'click' THEN subscriber_id END)
+		FROM campaign_events
+		WHERE campaign_id = ?
+	`
+	if err := db.QueryRow(query, campaignID).Scan(&stats.Opens, &stats.Clicks); err != nil {
+		return nil, fmt.Errorf("failed to get campaign stats: %w", err)
+	}
+	if sentCount > 0 {
+		stats.OpenRate = float64(stats.Opens) / float64(sentCount)
+		stats.ClickRate = float64(stats.Clicks) / float64(sentCount)
+	}
+
+	query = `
+		SELECT l.url, COUNT(DISTINCT e.subscriber_id) AS clicks
+		FROM campaign_links l
+		LEFT JOIN campaign_events e ON e.link_id = l.id
+		WHERE l.campaign_id = ?
+		GROUP BY l.id
+		ORDER BY clicks DESC, l.id ASC
+	`
+	rows, err := db.Query(query, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get link stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link models.LinkStats
+		if err := rows.Scan(&link.URL, &link.Clicks); err != nil {
+			return nil, fmt.Errorf("failed to scan link stats: %w", err)
+		}
+		stats.Links = append(stats.Links, &link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating link stats: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// Transactional email queries
+
+// ErrIdempotencyMismatch is returned when an idempotency key is reused for a different request
+var ErrIdempotencyMismatch = errors.New("idempotency key was used for a different request")
+
+// txLogColumns lists the columns read by scanTxLog, in order
+const txLogColumns = `x.id, x.uuid, COALESCE(x.template_id, 0), t.uuid, COALESCE(x.subscriber_id, 0), s.uuid,
+		       x.email, x.status, x.error, x.created_at, x.updated_at`
+
+// txLogFrom joins the tables read by txLogColumns
+const txLogFrom = `
+		FROM tx_logs x
+		LEFT JOIN templates t ON t.id = x.template_id
+		LEFT JOIN subscribers s ON s.id = x.subscriber_id`
+
+// scanTxLog scans a row selected with txLogColumns
+func scanTxLog(row scanner) (*models.TxLog, error) {
+	var l models.TxLog
+	var createdAt, updatedAt string
+	if err := row.Scan(
+		&l.ID, &l.UUID, &l.TemplateID, &l.TemplateUUID, &l.SubscriberID, &l.SubscriberUUID,
+		&l.Email, &l.Status, &l.Error, &createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+	l.CreatedAt = parseTime(createdAt)
+	l.UpdatedAt = parseTime(updatedAt)
+	return &l, nil
+}
+
+// StartTxLog records a transactional email about to be sent, in sending status.
+//
+// With an idempotency key, only the first request using it gets a new entry.
+// Later ones get the existing entry with created set to false, or
+// ErrIdempotencyMismatch if requestHash differs from the first request's.
+func (db *DB) StartTxLog(l *models.TxLog, idempotencyKey, requestHash string) (*models.TxLog, bool, error) {
+	var key interface{}
+	if idempotencyKey != "" {
+		key = idempotencyKey
+	}
+
+	query := `
+		INSERT INTO tx_logs (uuid, template_id, subscriber_id, email, idempotency_key, request_hash, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'sending', datetime('now'), datetime('now'))
+		ON CONFLICT(idempotency_key) DO NOTHING
+		RETURNING id
+	`
+	var id int
+	err := db.writeRow(query, l.UUID, nullID(l.TemplateID), nullID(l.SubscriberID), l.Email, key, requestHash).Scan(&id)
+	if err == nil {
+		created, err := db.getTxLog("x.id = ?", id)
+		return created, true, err
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to create tx log: %w", err)
+	}
+
+	// The key is taken, hand back what the first request did
+	var hash string
+	if err := db.QueryRow("SELECT request_hash FROM tx_logs WHERE idempotency_key = ?", idempotencyKey).Scan(&hash); err != nil {
+		return nil, false, fmt.Errorf("failed to get tx log: %w", err)
+	}
+	if hash != requestHash {
+		return nil, false, ErrIdempotencyMismatch
+	}
+	existing, err := db.getTxLog("x.idempotency_key = ?", idempotencyKey)
+	return existing, false, err
+}
+
+// FinishTxLog sets the final status of a transactional email
+func (db *DB) FinishTxLog(l *models.TxLog, status string, errStr *string) error {
+	query := `
+		UPDATE tx_logs
+		SET status = ?, error = ?, updated_at = datetime('now')
+		WHERE id = ?
+		RETURNING updated_at
+	`
+	var updatedAt string
+	if err := db.writeRow(query, status, errStr, l.ID).Scan(&updatedAt); err != nil {
+		return fmt.Errorf("failed to update tx log: %w", err)
+	}
+	l.Status = status
+	l.Error = errStr
+	l.UpdatedAt = parseTime(updatedAt)
+	return nil
+}
+
+// getTxLog retrieves a single transactional email log matching where
+func (db *DB) getTxLog(where string, arg interface{}) (*models.TxLog, error) {
+	query := "SELECT " + txLogColumns + txLogFrom + " WHERE " + where
+	l, err := scanTxLog(db.QueryRow(query, arg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tx log: %w", err)
+	}
+	return l, nil
+}
+
+// ListTxLogs retrieves transactional email logs with pagination, newest first
+func (db *DB) ListTxLogs(page, perPage int) ([]*models.TxLog, int, error) {
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM tx_logs").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count tx logs: %w", err)
+	}
+
+	offset := (page - 1) * perPage
+	query := "SELECT " + txLogColumns + txLogFrom + " ORDER BY x.id DESC LIMIT ? OFFSET ?"
+	rows, err := db.Query(query, perPage, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list tx logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*models.TxLog
+	for rows.Next() {
+		l, err := scanTxLog(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan tx log: %w", err)
+		}
+		logs = append(logs, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating tx logs: %w", err)
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,154 @@
+package private
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+)
+
+// BackupHandler handles database backup and restore requests
+type BackupHandler struct {
+	db       *db.DB
+	restored func() // Called after a restore, e.g. to reload settings
+}
+
+// NewBackupHandler creates a new backup handler
+func NewBackupHandler(database *db.DB, restored func()) *BackupHandler {
+	return &BackupHandler{db: database, restored: restored}
+}
+
+// Backup handles POST /api/private/backup
+//
+// The response is a consistent copy of the database file, taken while the
+// server keeps running.
+func (h *BackupHandler) Backup(w http.ResponseWriter, r *http.Request) {
+	path, err := h.db.BackupTemp()
+	if err != nil {
+		response.InternalError(w, "failed to back up database")
+		return
+	}
+	defer os.Remove(path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		response.InternalError(w, "failed to read backup")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		response.InternalError(w, "failed to read backup")
+		return
+	}
+
+	// Large databases take longer than the server write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Warning: failed to clear write deadline for backup: %v", err)
+	}
+
+	filename := fmt.Sprintf("tinylist-%s.db", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	// Headers are already sent at this point, so all we can do is log
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Database backup download aborted: %v", err)
+	}
+}
+
+// Restore handles POST /api/private/backup/restore
+//
+// The body is a snapshot as returned by Backup, either raw or as the "file"
+// field of a multipart form. It replaces the database only after it has been
+// checked, and is migrated if it is older than the schema. Restoring is
+// refused while campaigns are sending, and campaigns can't start meanwhile.
+func (h *BackupHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	// Large uploads take longer than the server read timeout
+	if err := http.NewResponseController(w).SetReadDeadline(time.Time{}); err != nil {
+		log.Printf("Warning: failed to clear read deadline for restore: %v", err)
+	}
+
+	body, err := restoreBody(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	// The upload is kept next to the database, where there is room for a file its size
+	upload, err := os.CreateTemp(filepath.Dir(h.db.Path()), ".restore-*.db")
+	if err != nil {
+		response.InternalError(w, "failed to store snapshot")
+		return
+	}
+	defer os.Remove(upload.Name())
+	_, err = io.Copy(upload, body)
+	if closeErr := upload.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		response.BadRequest(w, "failed to read snapshot")
+		return
+	}
+
+	if err := db.ValidateSnapshot(upload.Name()); err != nil {
+		response.BadRequest(w, "invalid snapshot: "+err.Error())
+		return
+	}
+	if err := h.db.Restore(upload.Name()); err != nil {
+		if errors.Is(err, db.ErrCampaignsSending) {
+			response.Conflict(w, "campaigns are being sent, cancel them before restoring")
+			return
+		}
+		response.InternalError(w, "failed to restore database")
+		return
+	}
+	log.Println("Database restored from uploaded snapshot")
+
+	if h.restored != nil {
+		h.restored()
+	}
+
+	response.OK(w, map[string]string{"message": "database restored"})
+}
+
+// restoreBody returns the snapshot payload of a restore request
+func restoreBody(r *http.Request) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("invalid multipart upload")
+	}
+	// Parts are streamed instead of parsed into memory, snapshots can be large
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, errors.New("multipart upload must contain a file field")
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// Routes returns a router with the backup routes
+func (h *BackupHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.Backup)
+	r.Post("/restore", h.Restore)
+	return r
+}
@@ -0,0 +1,143 @@
+package private
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zhisme/tinylist/internal/bounce"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/worker"
+)
+
+// maxBounceSize caps the size of a posted bounce message
+const maxBounceSize = 10 << 20 // 10 MB
+
+// BounceHandler handles bounce requests
+type BounceHandler struct {
+	db        *db.DB
+	processor *worker.BounceProcessor
+}
+
+// NewBounceHandler creates a new bounce handler
+func NewBounceHandler(database *db.DB, processor *worker.BounceProcessor) *BounceHandler {
+	return &BounceHandler{db: database, processor: processor}
+}
+
+// BounceRequest represents a bounce reported as JSON, for providers that parse bounces themselves
+type BounceRequest struct {
+	Email      string  `json:"email"`
+	Type       string  `json:"type"` // hard, soft
+	Reason     string  `json:"reason,omitempty"`
+	CampaignID *string `json:"campaign_id,omitempty"`
+}
+
+// Create handles POST /api/private/bounces
+//
+// The body is either a raw DSN message (RFC 3464), typically piped from an MTA,
+// or a JSON BounceRequest when sent as application/json.
+func (h *BounceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBounceSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		h.createFromJSON(w, r)
+		return
+	}
+
+	parsed, err := bounce.Parse(r.Body)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	bounces, err := h.processor.RecordAll(parsed, models.BounceSourceWebhook)
+	if err != nil {
+		response.InternalError(w, "failed to record bounces")
+		return
+	}
+
+	response.OK(w, bounces)
+}
+
+// createFromJSON records a single bounce from a JSON body
+func (h *BounceHandler) createFromJSON(w http.ResponseWriter, r *http.Request) {
+	var req BounceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Email == "" {
+		response.BadRequest(w, "email is required")
+		return
+	}
+	if req.Type != models.BounceHard && req.Type != models.BounceSoft {
+		response.BadRequest(w, "invalid type: must be hard or soft")
+		return
+	}
+
+	b := &models.Bounce{
+		Email:        req.Email,
+		Type:         req.Type,
+		Source:       models.BounceSourceWebhook,
+		Reason:       strings.TrimSpace(req.Reason),
+		CampaignUUID: req.CampaignID,
+	}
+	if err := h.processor.Record(b); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.NotFound(w, "subscriber not found")
+			return
+		}
+		response.InternalError(w, "failed to record bounce")
+		return
+	}
+
+	response.Created(w, b)
+}
+
+// List handles GET /api/private/bounces
+func (h *BounceHandler) List(w http.ResponseWriter, r *http.Request) {
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	perPage := 20
+	if pp := r.URL.Query().Get("per_page"); pp != "" {
+		if parsed, err := strconv.Atoi(pp); err == nil && parsed > 0 && parsed <= 100 {
+			perPage = parsed
+		}
+	}
+
+	bounces, total, err := h.db.ListBounces(page, perPage)
+	if err != nil {
+		response.InternalError(w, "failed to list bounces")
+		return
+	}
+
+	// Ensure we return an empty array instead of null
+	if bounces == nil {
+		bounces = []*models.Bounce{}
+	}
+
+	response.PaginatedResponse(w, bounces, page, perPage, total)
+}
+
+// Routes returns a router with all bounce routes
+func (h *BounceHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	return r
+}
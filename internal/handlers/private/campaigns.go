@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -14,6 +15,7 @@ import (
 	"github.com/zhisme/tinylist/internal/handlers/response"
 	"github.com/zhisme/tinylist/internal/mailer"
 	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/render"
 	"github.com/zhisme/tinylist/internal/worker"
 )
 
@@ -31,16 +33,37 @@ func NewCampaignHandler(database *db.DB, w *worker.CampaignWorker, m *mailer.Mai
 
 // CreateCampaignRequest represents the request body for creating a campaign
 type CreateCampaignRequest struct {
-	Subject  string  `json:"subject"`
-	BodyText string  `json:"body_text"`
-	BodyHTML *string `json:"body_html,omitempty"`
+	Subject  string   `json:"subject"`
+	BodyText string   `json:"body_text"`
+	BodyHTML *string  `json:"body_html,omitempty"`
+	ListIDs  []string `json:"list_ids,omitempty"` // Target lists, empty means all verified subscribers
+	// Layout template wrapping the bodies, applied at send time
+	TemplateID string `json:"template_id,omitempty"`
+	// Segment narrowing the recipients, combined with the target lists
+	SegmentID string `json:"segment_id,omitempty"`
+
+	TrackOpens  bool `json:"track_opens"`
+	TrackClicks bool `json:"track_clicks"`
+}
+
+// ScheduleCampaignRequest represents the request body for scheduling a campaign
+type ScheduleCampaignRequest struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
 }
 
 // UpdateCampaignRequest represents the request body for updating a campaign
 type UpdateCampaignRequest struct {
-	Subject  *string `json:"subject,omitempty"`
-	BodyText *string `json:"body_text,omitempty"`
-	BodyHTML *string `json:"body_html,omitempty"`
+	Subject  *string   `json:"subject,omitempty"`
+	BodyText *string   `json:"body_text,omitempty"`
+	BodyHTML *string   `json:"body_html,omitempty"`
+	ListIDs  *[]string `json:"list_ids,omitempty"`
+	// Layout template, an empty string removes it
+	TemplateID *string `json:"template_id,omitempty"`
+	// Segment, an empty string removes it
+	SegmentID *string `json:"segment_id,omitempty"`
+
+	TrackOpens  *bool `json:"track_opens,omitempty"`
+	TrackClicks *bool `json:"track_clicks,omitempty"`
 }
 
 // Create handles POST /api/private/campaigns
@@ -79,24 +102,82 @@ func (h *CampaignHandler) Create(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	layout, ok := h.resolveLayout(w, req.TemplateID)
+	if !ok {
+		return
+	}
+
+	if !validateTemplates(w, req.Subject, req.BodyText, req.BodyHTML, layout) {
+		return
+	}
+
+	// Resolve target lists
+	listIDs, ok := h.resolveLists(w, req.ListIDs)
+	if !ok {
+		return
+	}
+
+	segment, ok := h.resolveSegment(w, req.SegmentID)
+	if !ok {
+		return
+	}
+
 	campaign := &models.Campaign{
 		UUID:     uuid.New().String(),
 		Subject:  req.Subject,
 		BodyText: req.BodyText,
 		BodyHTML: req.BodyHTML,
 		Status:   models.CampaignStatusDraft,
+		ListIDs:  []string{},
+
+		TrackOpens:  req.TrackOpens,
+		TrackClicks: req.TrackClicks,
+	}
+	if layout != nil {
+		campaign.TemplateID = layout.ID
+		campaign.TemplateUUID = &layout.UUID
+	}
+	if segment != nil {
+		campaign.SegmentID = segment.ID
+		campaign.SegmentUUID = &segment.UUID
 	}
 
-	if err := h.db.CreateCampaign(campaign); err != nil {
+	if err := h.db.CreateCampaignWithLists(campaign, listIDs); err != nil {
 		response.InternalError(w, "failed to create campaign")
 		return
 	}
+	if len(listIDs) > 0 {
+		campaign.ListIDs = req.ListIDs
+	}
 
 	response.Created(w, campaign)
 }
 
 // List handles GET /api/private/campaigns
+// With a cursor query parameter (empty for the first page) campaigns are
+// returned in pages of per_page, otherwise all at once.
 func (h *CampaignHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("cursor") {
+		perPage := perPageParam(r)
+		campaigns, next, err := h.db.ListCampaignsAfter(r.URL.Query().Get("cursor"), perPage)
+		if err != nil {
+			if errors.Is(err, db.ErrInvalidCursor) {
+				response.BadRequest(w, "invalid cursor")
+				return
+			}
+			response.InternalError(w, "failed to list campaigns")
+			return
+		}
+
+		// Ensure we return an empty array instead of null
+		if campaigns == nil {
+			campaigns = []*models.Campaign{}
+		}
+
+		response.CursorPaginatedResponse(w, campaigns, perPage, next)
+		return
+	}
+
 	campaigns, err := h.db.ListCampaigns()
 	if err != nil {
 		response.InternalError(w, "failed to list campaigns")
@@ -129,6 +210,15 @@ func (h *CampaignHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if campaign.TrackOpens || campaign.TrackClicks {
+		stats, err := h.db.GetCampaignStats(campaign.ID, campaign.SentCount)
+		if err != nil {
+			response.InternalError(w, "failed to get campaign stats")
+			return
+		}
+		campaign.Stats = stats
+	}
+
 	response.OK(w, campaign)
 }
 
@@ -195,10 +285,65 @@ func (h *CampaignHandler) Update(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := h.db.UpdateCampaign(campaign); err != nil {
+	// An empty template_id removes the layout
+	var layout *models.Template
+	if req.TemplateID != nil {
+		var ok bool
+		if layout, ok = h.resolveLayout(w, *req.TemplateID); !ok {
+			return
+		}
+		campaign.TemplateID, campaign.TemplateUUID = 0, nil
+		if layout != nil {
+			campaign.TemplateID = layout.ID
+			campaign.TemplateUUID = &layout.UUID
+		}
+	} else if campaign.TemplateID != 0 {
+		var err error
+		if layout, err = h.db.GetTemplateByID(campaign.TemplateID); err != nil {
+			response.InternalError(w, "failed to get layout")
+			return
+		}
+	}
+
+	if !validateTemplates(w, campaign.Subject, campaign.BodyText, campaign.BodyHTML, layout) {
+		return
+	}
+
+	if req.TrackOpens != nil {
+		campaign.TrackOpens = *req.TrackOpens
+	}
+	if req.TrackClicks != nil {
+		campaign.TrackClicks = *req.TrackClicks
+	}
+
+	var listIDs []int
+	if req.ListIDs != nil {
+		var ok bool
+		if listIDs, ok = h.resolveLists(w, *req.ListIDs); !ok {
+			return
+		}
+	}
+
+	// An empty segment_id removes the segment
+	if req.SegmentID != nil {
+		segment, ok := h.resolveSegment(w, *req.SegmentID)
+		if !ok {
+			return
+		}
+		campaign.SegmentID, campaign.SegmentUUID = 0, nil
+		if segment != nil {
+			campaign.SegmentID = segment.ID
+			campaign.SegmentUUID = &segment.UUID
+		}
+	}
+
+	if err := h.db.UpdateCampaignWithLists(campaign, listIDs); err != nil {
 		response.InternalError(w, "failed to update campaign")
 		return
 	}
+	if req.ListIDs != nil {
+		campaign.ListIDs = append([]string{}, *req.ListIDs...)
+	}
 
 	response.OK(w, campaign)
 }
@@ -268,8 +413,8 @@ func (h *CampaignHandler) Send(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check campaign status
-	if campaign.Status != models.CampaignStatusDraft {
-		response.BadRequest(w, "can only send draft campaigns")
+	if campaign.Status != models.CampaignStatusDraft && campaign.Status != models.CampaignStatusScheduled {
+		response.BadRequest(w, "can only send draft or scheduled campaigns")
 		return
 	}
 
@@ -286,8 +431,60 @@ func (h *CampaignHandler) Send(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Cancel handles POST /api/private/campaigns/{id}/cancel
-func (h *CampaignHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+// Schedule handles POST /api/private/campaigns/{id}/schedule
+func (h *CampaignHandler) Schedule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.BadRequest(w, "campaign id is required")
+		return
+	}
+
+	var req ScheduleCampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body (scheduled_at must be an RFC 3339 timestamp)")
+		return
+	}
+	if req.ScheduledAt.IsZero() {
+		response.BadRequest(w, "scheduled_at is required")
+		return
+	}
+	if !req.ScheduledAt.After(time.Now()) {
+		response.BadRequest(w, "scheduled_at must be in the future")
+		return
+	}
+
+	// Get campaign to find internal ID
+	campaign, err := h.db.GetCampaignByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get campaign") {
+			response.NotFound(w, "campaign not found")
+			return
+		}
+		response.InternalError(w, "failed to get campaign")
+		return
+	}
+
+	// Draft campaigns can be scheduled, scheduled ones rescheduled
+	if campaign.Status != models.CampaignStatusDraft && campaign.Status != models.CampaignStatusScheduled {
+		response.BadRequest(w, "can only schedule draft campaigns")
+		return
+	}
+
+	if err := h.db.ScheduleCampaign(campaign.ID, req.ScheduledAt); err != nil {
+		response.InternalError(w, "failed to schedule campaign")
+		return
+	}
+
+	scheduledAt := req.ScheduledAt.UTC().Truncate(time.Second)
+	h.logJournal(campaign.ID, models.JournalEventInfo, "Scheduled for "+scheduledAt.Format(time.RFC3339))
+
+	campaign.Status = models.CampaignStatusScheduled
+	campaign.ScheduledAt = &scheduledAt
+	response.OK(w, campaign)
+}
+
+// Unschedule handles POST /api/private/campaigns/{id}/unschedule
+func (h *CampaignHandler) Unschedule(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
 		response.BadRequest(w, "campaign id is required")
@@ -305,14 +502,144 @@ func (h *CampaignHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if campaign is sending
-	if !h.worker.IsSending(campaign.ID) {
-		response.BadRequest(w, "campaign is not currently sending")
+	if campaign.Status != models.CampaignStatusScheduled || h.worker.IsSending(campaign.ID) {
+		response.BadRequest(w, "campaign is not scheduled")
+		return
+	}
+
+	if err := h.db.UnscheduleCampaign(campaign.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Picked up by the scheduler in the meantime
+			response.BadRequest(w, "campaign is not scheduled")
+			return
+		}
+		response.InternalError(w, "failed to unschedule campaign")
 		return
 	}
 
-	// Cancel the campaign
-	if err := h.worker.CancelCampaign(campaign.ID); err != nil {
+	h.logJournal(campaign.ID, models.JournalEventInfo, "Schedule cancelled, campaign moved back to draft")
+
+	campaign.Status = models.CampaignStatusDraft
+	campaign.ScheduledAt = nil
+	response.OK(w, campaign)
+}
+
+// validateTemplates checks that the campaign subject and bodies are valid templates,
+// wrapped in layout if it isn't nil. It writes a bad request response and returns false if they are not.
+func validateTemplates(w http.ResponseWriter, subject, bodyText string, bodyHTML *string, layout *models.Template) bool {
+	var html string
+	if bodyHTML != nil {
+		html = *bodyHTML
+	}
+	if err := render.ValidateWithLayout(subject, bodyText, html, layoutOf(layout)); err != nil {
+		response.BadRequest(w, err.Error())
+		return false
+	}
+	return true
+}
+
+// layoutOf returns the render layout of a layout template, or nil if t is nil
+func layoutOf(t *models.Template) *render.Layout {
+	if t == nil {
+		return nil
+	}
+	layout := &render.Layout{Text: t.BodyText}
+	if t.BodyHTML != nil {
+		layout.HTML = *t.BodyHTML
+	}
+	return layout
+}
+
+// resolveLayout looks up the layout template with the given UUID, "" means none.
+// It writes a bad request response and returns false if it is unknown or not a layout.
+func (h *CampaignHandler) resolveLayout(w http.ResponseWriter, templateUUID string) (*models.Template, bool) {
+	if templateUUID == "" {
+		return nil, true
+	}
+	t, err := h.db.GetTemplateByUUID(templateUUID)
+	if err != nil {
+		response.BadRequest(w, "unknown template: "+templateUUID)
+		return nil, false
+	}
+	if t.Type != models.TemplateTypeLayout {
+		response.BadRequest(w, "template is not a layout: "+templateUUID)
+		return nil, false
+	}
+	return t, true
+}
+
+// resolveSegment looks up the segment a campaign targets. An empty UUID means no segment.
+// It writes a bad request response and returns false if the segment is unknown.
+func (h *CampaignHandler) resolveSegment(w http.ResponseWriter, segmentUUID string) (*models.Segment, bool) {
+	if segmentUUID == "" {
+		return nil, true
+	}
+	seg, err := h.db.GetSegmentByUUID(segmentUUID)
+	if err != nil {
+		response.BadRequest(w, "unknown segment: "+segmentUUID)
+		return nil, false
+	}
+	return seg, true
+}
+
+// resolveLists looks up the internal IDs of the given list UUIDs.
+// It writes a bad request response and returns false if any list is unknown.
+func (h *CampaignHandler) resolveLists(w http.ResponseWriter, uuids []string) ([]int, bool) {
+	ids := make([]int, 0, len(uuids))
+	for _, listUUID := range uuids {
+		list, err := h.db.GetListByUUID(listUUID)
+		if err != nil {
+			response.BadRequest(w, "unknown list: "+listUUID)
+			return nil, false
+		}
+		ids = append(ids, list.ID)
+	}
+	return ids, true
+}
+
+// logJournal is a helper to log a journal entry
+func (h *CampaignHandler) logJournal(campaignID int, eventType, message string) {
+	entry := &models.CampaignJournal{
+		CampaignID: campaignID,
+		EventType:  eventType,
+		Message:    message,
+	}
+	if err := h.db.CreateCampaignJournal(entry); err != nil {
+		log.Printf("Warning: failed to create journal entry: %v", err)
+	}
+}
+
+// Cancel handles POST /api/private/campaigns/{id}/cancel
+func (h *CampaignHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.BadRequest(w, "campaign id is required")
+		return
+	}
+
+	// Get campaign to find internal ID
+	campaign, err := h.db.GetCampaignByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get campaign") {
+			response.NotFound(w, "campaign not found")
+			return
+		}
+		response.InternalError(w, "failed to get campaign")
+		return
+	}
+
+	// Campaigns sent by another process, e.g. the CLI, are cancelled through
+	// the database and stop once that process notices
+	if h.worker.IsSending(campaign.ID) {
+		if err := h.worker.CancelCampaign(campaign.ID); err != nil {
+			response.InternalError(w, "failed to cancel campaign")
+			return
+		}
+	} else if err := h.db.CancelSendingCampaign(campaign.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.BadRequest(w, "campaign is not currently sending")
+			return
+		}
 		response.InternalError(w, "failed to cancel campaign")
 		return
 	}
@@ -356,6 +683,44 @@ func (h *CampaignHandler) Journal(w http.ResponseWriter, r *http.Request) {
 	response.OK(w, journal)
 }
 
+// Logs handles GET /api/private/campaigns/{id}/logs
+// Logs are returned newest first in pages of per_page, following cursor.
+func (h *CampaignHandler) Logs(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.BadRequest(w, "campaign id is required")
+		return
+	}
+
+	campaign, err := h.db.GetCampaignByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get campaign") {
+			response.NotFound(w, "campaign not found")
+			return
+		}
+		response.InternalError(w, "failed to get campaign")
+		return
+	}
+
+	perPage := perPageParam(r)
+	logs, next, err := h.db.GetCampaignLogs(campaign.ID, r.URL.Query().Get("cursor"), perPage)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidCursor) {
+			response.BadRequest(w, "invalid cursor")
+			return
+		}
+		response.InternalError(w, "failed to get campaign logs")
+		return
+	}
+
+	// Ensure we return an empty array instead of null
+	if logs == nil {
+		logs = []*models.CampaignLog{}
+	}
+
+	response.CursorPaginatedResponse(w, logs, perPage, next)
+}
+
 // Routes returns a router with all campaign routes
 func (h *CampaignHandler) Routes() chi.Router {
 	r := chi.NewRouter()
@@ -365,7 +730,12 @@ func (h *CampaignHandler) Routes() chi.Router {
 	r.Put("/{id}", h.Update)
 	r.Delete("/{id}", h.Delete)
 	r.Post("/{id}/send", h.Send)
+	r.Post("/{id}/schedule", h.Schedule)
+	r.Post("/{id}/unschedule", h.Unschedule)
 	r.Post("/{id}/cancel", h.Cancel)
 	r.Get("/{id}/journal", h.Journal)
+	r.Get("/{id}/logs", h.Logs)
+	r.Get("/{id}/preview", h.Preview)
+	r.Post("/{id}/test", h.Test)
 	return r
 }
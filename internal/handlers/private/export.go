@@ -0,0 +1,144 @@
+package private
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// exportFlushEvery is the number of rows written between flushes to the client
+const exportFlushEvery = 500
+
+// Export handles GET /api/private/subscribers/export
+//
+// Query parameters:
+//   - format=csv|ndjson (default: csv)
+//   - status=pending|verified|unsubscribed|bounced filters by status
+//   - list=<uuid> filters by list membership
+//   - tag=<name> filters by tag, see List
+//   - q, created_after and created_before search and limit the signup date, see List
+//   - attr.<name>=<value> filters by attribute, see List
+func (h *SubscriberHandler) Export(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ndjson" {
+		response.BadRequest(w, "invalid format: must be csv or ndjson")
+		return
+	}
+
+	filter, ok := subscriberFilter(w, h.db, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	// Large exports take longer than the server write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Warning: failed to clear write deadline for export: %v", err)
+	}
+
+	filename := fmt.Sprintf("subscribers-%s.%s", time.Now().UTC().Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+
+	// Large exports also outlast the request timeout, so only the client
+	// going away stops the query. A client leaving after the timeout has passed
+	// is noticed by the writes failing.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+	stop := context.AfterFunc(r.Context(), func() {
+		if !errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+			cancel()
+		}
+	})
+	defer stop()
+
+	var flushed func()
+	if flusher, ok := w.(http.Flusher); ok {
+		flushed = flusher.Flush
+	}
+	count, err := WriteSubscribers(ctx, h.db, w, format, filter, flushed)
+	if err != nil {
+		log.Printf("Subscriber export aborted after %d rows: %v", count, err)
+		// Headers are already sent, so the connection is cut instead of ending
+		// the response, which lets the client tell the download is incomplete
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// WriteSubscribers writes the subscribers matching filter to out as csv or
+// ndjson and returns the number of rows written. Rows are written out every
+// exportFlushEvery rows, after which flushed is called if it is set.
+func WriteSubscribers(ctx context.Context, database *db.DB, out io.Writer, format string, filter db.SubscriberFilter, flushed func()) (int, error) {
+	var write func(*models.Subscriber) error
+	var flush func() error
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(out)
+		if err := writer.Write([]string{"id", "email", "name", "status", "attributes", "created_at", "verified_at", "updated_at"}); err != nil {
+			return 0, err
+		}
+		write = func(sub *models.Subscriber) error {
+			verifiedAt := ""
+			if sub.VerifiedAt != nil {
+				verifiedAt = sub.VerifiedAt.Format(time.RFC3339)
+			}
+			attributes, err := json.Marshal(sub.Attributes)
+			if err != nil {
+				return err
+			}
+			return writer.Write([]string{
+				sub.UUID, sub.Email, sub.Name, sub.Status, string(attributes),
+				sub.CreatedAt.Format(time.RFC3339), verifiedAt, sub.UpdatedAt.Format(time.RFC3339),
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case "ndjson":
+		encoder := json.NewEncoder(out)
+		write = func(sub *models.Subscriber) error {
+			return encoder.Encode(sub)
+		}
+		flush = func() error { return nil }
+	default:
+		return 0, fmt.Errorf("invalid format %q: must be csv or ndjson", format)
+	}
+
+	count := 0
+	err := database.ExportSubscribers(ctx, filter, func(sub *models.Subscriber) error {
+		if err := write(sub); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			if flushed != nil {
+				flushed()
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	return count, err
+}
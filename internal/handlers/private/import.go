@@ -0,0 +1,259 @@
+package private
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// maxImportSize caps the size of an uploaded CSV file
+const maxImportSize = 10 << 20 // 10 MB
+
+// ImportRowResult is the outcome of importing a single CSV row
+type ImportRowResult struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email"`
+	Result string `json:"result"` // created, updated, skipped, invalid
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport summarizes a CSV import
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Invalid int               `json:"invalid"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// importColumns maps CSV column names to their index
+type importColumns struct {
+	email, name, status, attributes int
+}
+
+// Import handles POST /api/private/subscribers/import
+//
+// The body is either a raw CSV (text/csv) or a multipart form with a "file" field.
+// Columns are email, name, an optional status and optional attributes as a JSON
+// object; a header row naming them may be used to reorder them. Query parameters:
+//   - dry_run=true validates and reports without writing
+//   - existing=update updates name and status of existing subscribers and merges
+//     their attributes (default: skip)
+//   - list=<uuid> adds imported subscribers to a list
+func (h *SubscriberHandler) Import(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := db.ImportOptions{
+		DryRun: query.Get("dry_run") == "true",
+	}
+	switch query.Get("existing") {
+	case "", "skip":
+	case "update":
+		opts.UpdateExisting = true
+	default:
+		response.BadRequest(w, "invalid existing: must be skip or update")
+		return
+	}
+	if listID := query.Get("list"); listID != "" {
+		list, err := h.db.GetListByUUID(listID)
+		if err != nil {
+			response.BadRequest(w, "unknown list")
+			return
+		}
+		opts.ListID = list.ID
+	}
+
+	body, err := importBody(w, r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	report, rows, err := parseImportCSV(body)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	report.DryRun = opts.DryRun
+
+	if err := importRows(h.db, report, rows, opts); err != nil {
+		response.InternalError(w, "failed to import subscribers")
+		return
+	}
+
+	response.OK(w, report)
+}
+
+// ImportCSV validates and imports the subscribers of a CSV file with the
+// columns described at Import. Invalid rows are reported and left out.
+func ImportCSV(database *db.DB, body io.Reader, opts db.ImportOptions) (*ImportReport, error) {
+	report, rows, err := parseImportCSV(body)
+	if err != nil {
+		return nil, err
+	}
+	report.DryRun = opts.DryRun
+
+	if err := importRows(database, report, rows, opts); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// importRows hands the valid rows of a parsed CSV to the database and fills
+// in their results and the totals of report
+func importRows(database *db.DB, report *ImportReport, rows []db.ImportRow, opts db.ImportOptions) error {
+	// Results come back in the same order as the valid rows
+	var valid []db.ImportRow
+	var positions []int
+	for i, row := range report.Rows {
+		if row.Result == "" {
+			valid = append(valid, rows[i])
+			positions = append(positions, i)
+		}
+	}
+
+	results, err := database.ImportSubscribers(valid, opts)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		report.Rows[positions[i]].Result = result
+		switch result {
+		case db.ImportCreated:
+			report.Created++
+		case db.ImportUpdated:
+			report.Updated++
+		case db.ImportSkipped:
+			report.Skipped++
+		}
+	}
+	return nil
+}
+
+// importBody returns the CSV payload of an import request
+func importBody(w http.ResponseWriter, r *http.Request) (io.Reader, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, errors.New("multipart upload must contain a file field")
+	}
+	return file, nil
+}
+
+// parseImportCSV reads and validates all rows. The returned report has one entry
+// per data row; rows that failed validation are already marked invalid, the
+// others have an empty result and a matching entry in the returned rows.
+func parseImportCSV(body io.Reader) (*ImportReport, []db.ImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	report := &ImportReport{Rows: []ImportRowResult{}}
+	var rows []db.ImportRow
+	columns := importColumns{email: 0, name: 1, status: 2, attributes: 3}
+	seen := make(map[string]bool)
+
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.New("invalid CSV: " + err.Error())
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first {
+			if header, ok := parseImportHeader(record); ok {
+				columns = header
+				continue
+			}
+		}
+
+		row := db.ImportRow{
+			Email:  strings.ToLower(field(record, columns.email)),
+			Name:   field(record, columns.name),
+			Status: strings.ToLower(field(record, columns.status)),
+		}
+		result := ImportRowResult{Line: line, Email: row.Email}
+		attributesErr := parseImportAttributes(field(record, columns.attributes), &row)
+
+		switch {
+		case row.Email == "":
+			result.Error = "email is required"
+		case !validateEmail(row.Email):
+			result.Error = "invalid email format"
+		case len(row.Name) > 255:
+			result.Error = "name must be 255 characters or less"
+		case row.Status != "" && (!validSubscriberStatus(row.Status) || row.Status == models.StatusBounced):
+			result.Error = "invalid status: must be pending, verified, or unsubscribed"
+		case attributesErr != nil:
+			result.Error = attributesErr.Error()
+		case seen[row.Email]:
+			result.Error = "duplicate email in file"
+		}
+
+		if result.Error != "" {
+			result.Result = "invalid"
+			report.Invalid++
+		} else {
+			seen[row.Email] = true
+		}
+		report.Rows = append(report.Rows, result)
+		rows = append(rows, row)
+	}
+
+	return report, rows, nil
+}
+
+// parseImportHeader detects a header row and maps its column names
+func parseImportHeader(record []string) (importColumns, bool) {
+	columns := importColumns{email: -1, name: -1, status: -1, attributes: -1}
+	for i, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "email":
+			columns.email = i
+		case "name":
+			columns.name = i
+		case "status":
+			columns.status = i
+		case "attributes":
+			columns.attributes = i
+		}
+	}
+	return columns, columns.email != -1
+}
+
+// parseImportAttributes decodes the JSON object of the attributes column into row
+func parseImportAttributes(value string, row *db.ImportRow) error {
+	if value == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(value), &row.Attributes); err != nil {
+		return errors.New("attributes must be a JSON object")
+	}
+	return models.ValidateAttributes(row.Attributes)
+}
+
+// field returns the trimmed value at index i, or "" if the record is too short
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
@@ -0,0 +1,296 @@
+package private
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// ListHandler handles mailing list requests
+type ListHandler struct {
+	db *db.DB
+}
+
+// NewListHandler creates a new list handler
+func NewListHandler(database *db.DB) *ListHandler {
+	return &ListHandler{db: database}
+}
+
+// ListRequest represents the request body for creating or updating a list
+type ListRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Slug        *string `json:"slug,omitempty"` // Used by the public subscribe endpoint, derived from name if empty
+	Description *string `json:"description,omitempty"`
+}
+
+// MembershipRequest represents the request body for adding a subscriber to a list
+type MembershipRequest struct {
+	SubscriberID string `json:"subscriber_id"`
+}
+
+// slugRegex validates list slugs
+var slugRegex = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// nonSlugChars matches runs of characters that are not allowed in a slug
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify derives a slug from a list name
+func slugify(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// Create handles POST /api/private/lists
+func (h *ListHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req ListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+
+	list := &models.List{UUID: uuid.New().String()}
+	if req.Name == nil {
+		response.BadRequest(w, "name is required")
+		return
+	}
+	if !h.apply(w, list, req) {
+		return
+	}
+
+	if err := h.db.CreateList(list); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			response.Conflict(w, "list with this slug already exists")
+			return
+		}
+		response.InternalError(w, "failed to create list")
+		return
+	}
+
+	response.Created(w, list)
+}
+
+// List handles GET /api/private/lists
+func (h *ListHandler) List(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.db.ListLists()
+	if err != nil {
+		response.InternalError(w, "failed to list lists")
+		return
+	}
+
+	// Ensure we return an empty array instead of null
+	if lists == nil {
+		lists = []*models.List{}
+	}
+
+	response.OK(w, lists)
+}
+
+// Get handles GET /api/private/lists/{id}
+func (h *ListHandler) Get(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.getList(w, r)
+	if !ok {
+		return
+	}
+
+	response.OK(w, list)
+}
+
+// Update handles PUT /api/private/lists/{id}
+func (h *ListHandler) Update(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.getList(w, r)
+	if !ok {
+		return
+	}
+
+	var req ListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+
+	if !h.apply(w, list, req) {
+		return
+	}
+
+	if err := h.db.UpdateList(list); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			response.Conflict(w, "list with this slug already exists")
+			return
+		}
+		response.InternalError(w, "failed to update list")
+		return
+	}
+
+	response.OK(w, list)
+}
+
+// Delete handles DELETE /api/private/lists/{id}
+func (h *ListHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.getList(w, r)
+	if !ok {
+		return
+	}
+
+	// Deleting the list would silently turn its campaigns into sends to everyone
+	pending, err := h.db.CountPendingCampaignsForList(list.ID)
+	if err != nil {
+		response.InternalError(w, "failed to check campaigns for list")
+		return
+	}
+	if pending > 0 {
+		response.Conflict(w, "list is targeted by unsent campaigns")
+		return
+	}
+
+	if err := h.db.DeleteList(list.ID); err != nil {
+		response.InternalError(w, "failed to delete list")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// AddSubscriber handles POST /api/private/lists/{id}/subscribers
+func (h *ListHandler) AddSubscriber(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.getList(w, r)
+	if !ok {
+		return
+	}
+
+	var req MembershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+	if req.SubscriberID == "" {
+		response.BadRequest(w, "subscriber_id is required")
+		return
+	}
+
+	sub, err := h.db.GetSubscriberByUUID(req.SubscriberID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get subscriber") {
+			response.NotFound(w, "subscriber not found")
+			return
+		}
+		response.InternalError(w, "failed to get subscriber")
+		return
+	}
+
+	// Membership follows the subscriber's own opt-in state
+	if sub.Status == models.StatusUnsubscribed || sub.Status == models.StatusBounced {
+		response.BadRequest(w, "cannot add an unsubscribed or bounced subscriber to a list")
+		return
+	}
+
+	if err := h.db.SetListMembership(sub.ID, list.ID, sub.Status); err != nil {
+		response.InternalError(w, "failed to add subscriber to list")
+		return
+	}
+
+	response.OK(w, map[string]string{"message": "subscriber added to list"})
+}
+
+// RemoveSubscriber handles DELETE /api/private/lists/{id}/subscribers/{subscriberID}
+func (h *ListHandler) RemoveSubscriber(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.getList(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.db.GetSubscriberByUUID(chi.URLParam(r, "subscriberID"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get subscriber") {
+			response.NotFound(w, "subscriber not found")
+			return
+		}
+		response.InternalError(w, "failed to get subscriber")
+		return
+	}
+
+	if err := h.db.DeleteListMembership(sub.ID, list.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.NotFound(w, "subscriber is not on this list")
+			return
+		}
+		response.InternalError(w, "failed to remove subscriber from list")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// getList loads the list named by the {id} URL parameter.
+// It writes an error response and returns false if the list can't be loaded.
+func (h *ListHandler) getList(w http.ResponseWriter, r *http.Request) (*models.List, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.BadRequest(w, "list id is required")
+		return nil, false
+	}
+
+	list, err := h.db.GetListByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get list") {
+			response.NotFound(w, "list not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get list")
+		return nil, false
+	}
+	return list, true
+}
+
+// apply validates the request fields and copies them onto list.
+// It writes a bad request response and returns false on invalid input.
+func (h *ListHandler) apply(w http.ResponseWriter, list *models.List, req ListRequest) bool {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			response.BadRequest(w, "name cannot be empty")
+			return false
+		}
+		if len(name) > 255 {
+			response.BadRequest(w, "name must be 255 characters or less")
+			return false
+		}
+		list.Name = name
+	}
+
+	if req.Slug != nil {
+		list.Slug = strings.TrimSpace(strings.ToLower(*req.Slug))
+	}
+	if list.Slug == "" {
+		list.Slug = slugify(list.Name)
+	}
+	if !slugRegex.MatchString(list.Slug) || len(list.Slug) > 100 {
+		response.BadRequest(w, "slug may only contain lowercase letters, digits, and dashes")
+		return false
+	}
+
+	if req.Description != nil {
+		list.Description = strings.TrimSpace(*req.Description)
+	}
+	return true
+}
+
+// Routes returns a router with all list routes
+func (h *ListHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	r.Put("/{id}", h.Update)
+	r.Delete("/{id}", h.Delete)
+	r.Post("/{id}/subscribers", h.AddSubscriber)
+	r.Delete("/{id}/subscribers/{subscriberID}", h.RemoveSubscriber)
+	return r
+}
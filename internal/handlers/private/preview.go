@@ -0,0 +1,140 @@
+package private
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/worker"
+)
+
+// maxTestRecipients caps the number of addresses a test email goes to
+const maxTestRecipients = 20
+
+// TestCampaignRequest represents the request body for sending a test email
+type TestCampaignRequest struct {
+	Emails     []string `json:"emails"`
+	Subscriber string   `json:"subscriber,omitempty"` // Render for this subscriber, sample data if empty
+}
+
+// Preview handles GET /api/private/campaigns/{id}/preview
+//
+// The optional subscriber query parameter renders the campaign for that
+// subscriber, otherwise sample data is used.
+func (h *CampaignHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := h.campaignFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	sub, ok := h.previewSubscriber(w, r.URL.Query().Get("subscriber"), "subscriber@example.com")
+	if !ok {
+		return
+	}
+
+	preview, err := h.worker.Preview(campaign, sub)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	response.OK(w, preview)
+}
+
+// Test handles POST /api/private/campaigns/{id}/test
+//
+// Sends the rendered campaign to the given addresses. The campaign's status,
+// counts and logs are left untouched.
+func (h *CampaignHandler) Test(w http.ResponseWriter, r *http.Request) {
+	campaign, ok := h.campaignFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	var req TestCampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+
+	if len(req.Emails) == 0 {
+		response.BadRequest(w, "at least one email is required")
+		return
+	}
+	if len(req.Emails) > maxTestRecipients {
+		response.BadRequest(w, fmt.Sprintf("at most %d emails are allowed", maxTestRecipients))
+		return
+	}
+	for i, email := range req.Emails {
+		email = strings.TrimSpace(strings.ToLower(email))
+		if !validateEmail(email) {
+			response.BadRequest(w, "invalid email format: "+email)
+			return
+		}
+		req.Emails[i] = email
+	}
+
+	if !h.mailer.IsConfigured() {
+		response.BadRequest(w, "SMTP is not configured")
+		return
+	}
+
+	sub, ok := h.previewSubscriber(w, req.Subscriber, req.Emails[0])
+	if !ok {
+		return
+	}
+
+	results, err := h.worker.SendTest(r.Context(), campaign, sub, req.Emails)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	h.logJournal(campaign.ID, models.JournalEventInfo, "Test email sent to "+strings.Join(req.Emails, ", "))
+	response.OK(w, results)
+}
+
+// campaignFromURL loads the campaign named by the id URL parameter
+func (h *CampaignHandler) campaignFromURL(w http.ResponseWriter, r *http.Request) (*models.Campaign, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.BadRequest(w, "campaign id is required")
+		return nil, false
+	}
+
+	campaign, err := h.db.GetCampaignByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get campaign") {
+			response.NotFound(w, "campaign not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get campaign")
+		return nil, false
+	}
+	return campaign, true
+}
+
+// previewSubscriber returns the subscriber with the given UUID, or a sample
+// subscriber with sampleEmail if the UUID is empty
+func (h *CampaignHandler) previewSubscriber(w http.ResponseWriter, id, sampleEmail string) (*models.Subscriber, bool) {
+	if id == "" {
+		return worker.SampleSubscriber(sampleEmail), true
+	}
+
+	sub, err := h.db.GetSubscriberByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get subscriber") {
+			response.BadRequest(w, "unknown subscriber")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get subscriber")
+		return nil, false
+	}
+	return sub, true
+}
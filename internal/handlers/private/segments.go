@@ -0,0 +1,262 @@
+package private
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// SegmentHandler handles subscriber segment requests
+type SegmentHandler struct {
+	db *db.DB
+}
+
+// NewSegmentHandler creates a new segment handler
+func NewSegmentHandler(database *db.DB) *SegmentHandler {
+	return &SegmentHandler{db: database}
+}
+
+// SegmentRequest represents the request body for creating or updating a segment.
+// Rules are replaced as a whole.
+type SegmentRequest struct {
+	Name  *string              `json:"name,omitempty"`
+	Rules *models.SegmentRules `json:"rules,omitempty"`
+}
+
+// SegmentCount is the audience size of a segment
+type SegmentCount struct {
+	Count int `json:"count"` // Verified subscribers a campaign targeting the segment would reach
+	Total int `json:"total"` // All matching subscribers, whatever their status
+}
+
+// Create handles POST /api/private/segments
+func (h *SegmentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req SegmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+
+	seg := &models.Segment{UUID: uuid.New().String()}
+	if req.Name == nil {
+		response.BadRequest(w, "name is required")
+		return
+	}
+	if !h.apply(w, seg, req) {
+		return
+	}
+
+	if err := h.db.CreateSegment(seg); err != nil {
+		response.InternalError(w, "failed to create segment")
+		return
+	}
+
+	response.Created(w, seg)
+}
+
+// List handles GET /api/private/segments
+func (h *SegmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	segments, err := h.db.ListSegments()
+	if err != nil {
+		response.InternalError(w, "failed to list segments")
+		return
+	}
+
+	// Ensure we return an empty array instead of null
+	if segments == nil {
+		segments = []*models.Segment{}
+	}
+
+	response.OK(w, segments)
+}
+
+// Get handles GET /api/private/segments/{id}
+func (h *SegmentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	seg, ok := h.getSegment(w, r)
+	if !ok {
+		return
+	}
+
+	response.OK(w, seg)
+}
+
+// Update handles PUT /api/private/segments/{id}
+func (h *SegmentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	seg, ok := h.getSegment(w, r)
+	if !ok {
+		return
+	}
+
+	var req SegmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+
+	if !h.apply(w, seg, req) {
+		return
+	}
+
+	if err := h.db.UpdateSegment(seg); err != nil {
+		response.InternalError(w, "failed to update segment")
+		return
+	}
+
+	response.OK(w, seg)
+}
+
+// Delete handles DELETE /api/private/segments/{id}
+func (h *SegmentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	seg, ok := h.getSegment(w, r)
+	if !ok {
+		return
+	}
+
+	// Deleting the segment would silently widen its campaigns to all their lists
+	pending, err := h.db.CountPendingCampaignsForSegment(seg.ID)
+	if err != nil {
+		response.InternalError(w, "failed to check campaigns for segment")
+		return
+	}
+	if pending > 0 {
+		response.Conflict(w, "segment is targeted by unsent campaigns")
+		return
+	}
+
+	if err := h.db.DeleteSegment(seg.ID); err != nil {
+		response.InternalError(w, "failed to delete segment")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// Count handles GET /api/private/segments/{id}/count
+func (h *SegmentHandler) Count(w http.ResponseWriter, r *http.Request) {
+	seg, ok := h.getSegment(w, r)
+	if !ok {
+		return
+	}
+
+	total, verified, err := h.db.CountSegment(seg.Rules)
+	if err != nil {
+		response.InternalError(w, "failed to count segment")
+		return
+	}
+
+	response.OK(w, SegmentCount{Count: verified, Total: total})
+}
+
+// getSegment loads the segment named by the {id} URL parameter.
+// It writes an error response and returns false if the segment can't be loaded.
+func (h *SegmentHandler) getSegment(w http.ResponseWriter, r *http.Request) (*models.Segment, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.BadRequest(w, "segment id is required")
+		return nil, false
+	}
+
+	seg, err := h.db.GetSegmentByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get segment") {
+			response.NotFound(w, "segment not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get segment")
+		return nil, false
+	}
+	return seg, true
+}
+
+// apply validates the request fields and copies them onto seg.
+// It writes a bad request response and returns false on invalid input.
+func (h *SegmentHandler) apply(w http.ResponseWriter, seg *models.Segment, req SegmentRequest) bool {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			response.BadRequest(w, "name cannot be empty")
+			return false
+		}
+		if len(name) > 255 {
+			response.BadRequest(w, "name must be 255 characters or less")
+			return false
+		}
+		seg.Name = name
+	}
+
+	if req.Rules != nil {
+		if !h.validateRules(w, *req.Rules) {
+			return false
+		}
+		seg.Rules = *req.Rules
+	}
+	return true
+}
+
+// validateRules checks that the statuses, lists, attributes and campaigns
+// named by segment rules exist, and normalizes tag names.
+// It writes a bad request response and returns false on invalid rules.
+func (h *SegmentHandler) validateRules(w http.ResponseWriter, rules models.SegmentRules) bool {
+	for _, status := range rules.Status {
+		if !validSubscriberStatus(status) {
+			response.BadRequest(w, "invalid status: must be pending, verified, unsubscribed, or bounced")
+			return false
+		}
+	}
+
+	for _, listUUID := range rules.Lists {
+		if _, err := h.db.GetListByUUID(listUUID); err != nil {
+			response.BadRequest(w, "unknown list: "+listUUID)
+			return false
+		}
+	}
+
+	if rules.SubscribedAfter != nil && rules.SubscribedBefore != nil && !rules.SubscribedBefore.After(*rules.SubscribedAfter) {
+		response.BadRequest(w, "subscribed_before must be after subscribed_after")
+		return false
+	}
+
+	if err := models.ValidateAttributes(rules.Attributes); err != nil {
+		response.BadRequest(w, err.Error())
+		return false
+	}
+
+	for i, tag := range rules.Tags {
+		name, ok := tagName(w, tag)
+		if !ok {
+			return false
+		}
+		rules.Tags[i] = name
+	}
+
+	for _, campaignUUID := range []string{rules.OpenedCampaign, rules.ClickedCampaign} {
+		if campaignUUID == "" {
+			continue
+		}
+		if _, err := h.db.GetCampaignByUUID(campaignUUID); err != nil {
+			response.BadRequest(w, "unknown campaign: "+campaignUUID)
+			return false
+		}
+	}
+	return true
+}
+
+// Routes returns a router with all segment routes
+func (h *SegmentHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	r.Put("/{id}", h.Update)
+	r.Delete("/{id}", h.Delete)
+	r.Get("/{id}/count", h.Count)
+	return r
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/zhisme/tinylist/internal/db"
@@ -22,6 +23,22 @@ type SMTPSettings struct {
 	TLS       bool   `json:"tls"`
 }
 
+// DeliverySettings selects the backend emails are delivered through
+type DeliverySettings struct {
+	Backend string `json:"backend"`         // smtp, file, log or http
+	Dir     string `json:"dir,omitempty"`   // file: directory for .eml files
+	URL     string `json:"url,omitempty"`   // http: endpoint messages are posted to
+	Token   string `json:"token,omitempty"` // http: bearer token, only returned as "***" if set
+}
+
+// SystemEmailSettings is a system email as edited in the settings
+type SystemEmailSettings struct {
+	Kind    string `json:"kind"`
+	Enabled bool   `json:"enabled"` // Verification emails are always sent
+	Custom  bool   `json:"custom"`  // False if the built-in is used
+	mailer.SystemEmail
+}
+
 // SettingsHandler handles settings API requests
 type SettingsHandler struct {
 	db     *db.DB
@@ -43,6 +60,11 @@ func (h *SettingsHandler) Routes() chi.Router {
 	r.Get("/smtp", h.GetSMTPSettings)
 	r.Put("/smtp", h.UpdateSMTPSettings)
 	r.Post("/smtp/test", h.TestSMTPSettings)
+	r.Get("/delivery", h.GetDeliverySettings)
+	r.Put("/delivery", h.UpdateDeliverySettings)
+	r.Get("/emails", h.GetSystemEmails)
+	r.Put("/emails/{kind}", h.UpdateSystemEmail)
+	r.Post("/emails/{kind}/preview", h.PreviewSystemEmail)
 
 	return r
 }
@@ -183,3 +205,202 @@ func (h *SettingsHandler) TestSMTPSettings(w http.ResponseWriter, r *http.Reques
 
 	response.JSON(w, http.StatusOK, map[string]string{"message": "Test email sent successfully"})
 }
+
+// GetDeliverySettings returns the current delivery backend settings
+func (h *SettingsHandler) GetDeliverySettings(w http.ResponseWriter, r *http.Request) {
+	dbSettings, err := h.db.GetAllSettings()
+	if err != nil {
+		dbSettings = make(map[string]string)
+	}
+
+	delivery := DeliverySettings{
+		Backend: dbSettings["delivery_backend"],
+		Dir:     dbSettings["delivery_dir"],
+		URL:     dbSettings["delivery_url"],
+	}
+	if delivery.Backend == "" {
+		delivery.Backend = mailer.BackendSMTP
+	}
+	if dbSettings["delivery_token"] != "" {
+		delivery.Token = "***"
+	}
+
+	response.JSON(w, http.StatusOK, delivery)
+}
+
+// UpdateDeliverySettings switches the delivery backend
+func (h *SettingsHandler) UpdateDeliverySettings(w http.ResponseWriter, r *http.Request) {
+	var req DeliverySettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	switch req.Backend {
+	case mailer.BackendSMTP, mailer.BackendLog:
+	case mailer.BackendFile:
+		if req.Dir == "" {
+			response.BadRequest(w, "Directory is required for the file backend")
+			return
+		}
+	case mailer.BackendHTTP:
+		if req.URL == "" {
+			response.BadRequest(w, "URL is required for the http backend")
+			return
+		}
+	default:
+		response.BadRequest(w, "Backend must be smtp, file, log or http")
+		return
+	}
+
+	values := map[string]string{
+		"delivery_backend": req.Backend,
+		"delivery_dir":     req.Dir,
+		"delivery_url":     req.URL,
+	}
+	// Only update token if not masked
+	if req.Token != "***" {
+		values["delivery_token"] = req.Token
+	}
+	for key, value := range values {
+		if err := h.db.SetSetting(key, value); err != nil {
+			response.InternalError(w, "Failed to save settings")
+			return
+		}
+	}
+
+	dbSettings, err := h.db.GetAllSettings()
+	if err != nil {
+		response.InternalError(w, "Failed to load settings")
+		return
+	}
+	backend, err := mailer.BackendFromSettings(dbSettings)
+	if err != nil {
+		response.InternalError(w, err.Error())
+		return
+	}
+	h.mailer.SetBackend(backend)
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Settings saved successfully"})
+}
+
+// GetSystemEmails returns the verification, welcome and goodbye emails.
+// Kinds that aren't customized return the built-in.
+func (h *SettingsHandler) GetSystemEmails(w http.ResponseWriter, r *http.Request) {
+	dbSettings, err := h.db.GetAllSettings()
+	if err != nil {
+		dbSettings = make(map[string]string)
+	}
+
+	stored := mailer.SystemEmailsFromSettings(dbSettings)
+	emails := make([]SystemEmailSettings, 0, len(mailer.SystemEmailKinds))
+	for _, kind := range mailer.SystemEmailKinds {
+		email, custom := stored[kind]
+		if !custom {
+			email = mailer.BuiltinSystemEmail(kind)
+		}
+		emails = append(emails, SystemEmailSettings{
+			Kind:        kind,
+			Enabled:     kind == mailer.SystemVerification || dbSettings["email_"+kind+"_enabled"] == "true",
+			Custom:      custom,
+			SystemEmail: email,
+		})
+	}
+
+	response.JSON(w, http.StatusOK, emails)
+}
+
+// UpdateSystemEmail stores a customized system email. An empty subject and
+// text body restore the built-in.
+func (h *SettingsHandler) UpdateSystemEmail(w http.ResponseWriter, r *http.Request) {
+	kind, ok := systemEmailKind(w, r)
+	if !ok {
+		return
+	}
+
+	var req SystemEmailSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	reset := strings.TrimSpace(req.Subject) == "" && strings.TrimSpace(req.BodyText) == ""
+	if reset {
+		req.SystemEmail = mailer.SystemEmail{}
+	} else if err := mailer.ValidateSystemEmail(req.SystemEmail); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	values := map[string]string{
+		"email_" + kind + "_subject": req.Subject,
+		"email_" + kind + "_text":    req.BodyText,
+		"email_" + kind + "_html":    req.BodyHTML,
+	}
+	if kind != mailer.SystemVerification {
+		values["email_"+kind+"_enabled"] = strconv.FormatBool(req.Enabled)
+	}
+	for key, value := range values {
+		if err := h.db.SetSetting(key, value); err != nil {
+			response.InternalError(w, "Failed to save settings")
+			return
+		}
+	}
+
+	dbSettings, err := h.db.GetAllSettings()
+	if err != nil {
+		response.InternalError(w, "Failed to load settings")
+		return
+	}
+	h.mailer.SetSystemEmails(mailer.SystemEmailsFromSettings(dbSettings))
+
+	response.JSON(w, http.StatusOK, map[string]string{"message": "Settings saved successfully"})
+}
+
+// PreviewSystemEmail renders a system email for a sample subscriber. The
+// request body holds the template to preview, an empty subject previews the
+// email as currently sent.
+func (h *SettingsHandler) PreviewSystemEmail(w http.ResponseWriter, r *http.Request) {
+	kind, ok := systemEmailKind(w, r)
+	if !ok {
+		return
+	}
+
+	var req mailer.SystemEmail
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	var email *mailer.SystemEmail
+	if req.Subject != "" {
+		if err := mailer.ValidateSystemEmail(req); err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		email = &req
+	}
+
+	urls := map[string]string{
+		"verify_url":      "https://example.com/api/verify/preview",
+		"unsubscribe_url": "https://example.com/api/unsubscribe/preview",
+	}
+	subject, text, html, err := h.mailer.RenderSystemEmail(kind, email, "jane@example.com", "Jane", urls)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	response.OK(w, mailer.SystemEmail{Subject: subject, BodyText: text, BodyHTML: html})
+}
+
+// systemEmailKind returns the system email named in the URL. It writes an
+// error response and returns false if there is no such email.
+func systemEmailKind(w http.ResponseWriter, r *http.Request) (string, bool) {
+	kind := chi.URLParam(r, "kind")
+	if !mailer.IsSystemEmail(kind) {
+		response.NotFound(w, "system email not found")
+		return "", false
+	}
+	return kind, true
+}
@@ -4,10 +4,13 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -35,8 +38,16 @@ func NewSubscriberHandler(database *db.DB, m *mailer.Mailer, publicURL string) *
 
 // CreateRequest represents the request body for creating a subscriber
 type CreateRequest struct {
-	Email string `json:"email"`
-	Name  string `json:"name"`
+	Email      string                 `json:"email"`
+	Name       string                 `json:"name"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// UpdateSubscriberRequest represents the request body for updating a subscriber.
+// Omitted fields are left unchanged, attributes are replaced as a whole.
+type UpdateSubscriberRequest struct {
+	Name       *string                `json:"name,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
 
 // emailRegex validates email format
@@ -50,6 +61,15 @@ func validateEmail(email string) bool {
 	return emailRegex.MatchString(email)
 }
 
+// validSubscriberStatus checks if status is a known subscriber status
+func validSubscriberStatus(status string) bool {
+	switch status {
+	case models.StatusPending, models.StatusVerified, models.StatusUnsubscribed, models.StatusBounced:
+		return true
+	}
+	return false
+}
+
 // Create handles POST /api/private/subscribers
 func (h *SubscriberHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateRequest
@@ -76,6 +96,10 @@ func (h *SubscriberHandler) Create(w http.ResponseWriter, r *http.Request) {
 		response.BadRequest(w, "name must be 255 characters or less")
 		return
 	}
+	if err := models.ValidateAttributes(req.Attributes); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
 
 	// Check for existing subscriber
 	existing, err := h.db.GetSubscriberByEmail(req.Email)
@@ -98,6 +122,7 @@ func (h *SubscriberHandler) Create(w http.ResponseWriter, r *http.Request) {
 		Email:            req.Email,
 		Name:             req.Name,
 		Status:           models.StatusPending,
+		Attributes:       req.Attributes,
 		VerifyToken:      &verifyToken,
 		UnsubscribeToken: unsubscribeToken,
 	}
@@ -116,12 +141,145 @@ func (h *SubscriberHandler) Create(w http.ResponseWriter, r *http.Request) {
 	response.Created(w, sub)
 }
 
+// attributeFilter reads attr.<name>=<value> query parameters. Values are
+// compared as JSON, anything that isn't valid JSON is taken as a string, so
+// attr.city=Berlin and attr.age=30 both work.
+func attributeFilter(query url.Values) (map[string]string, error) {
+	filter := make(map[string]string)
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, "attr.")
+		if !ok {
+			continue
+		}
+		if !models.ValidAttributeKey(key) {
+			return nil, fmt.Errorf("invalid attribute name %q", key)
+		}
+		value := values[0]
+		if !json.Valid([]byte(value)) {
+			quoted, _ := json.Marshal(value)
+			value = string(quoted)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
+
+// subscriberFilter reads the status, list, tag, q, created_after, created_before
+// and attr.<name> query parameters shared by the subscriber list, export and
+// bulk tag endpoints.
+// It writes a bad request response and returns false on invalid parameters.
+func subscriberFilter(w http.ResponseWriter, database *db.DB, query url.Values) (db.SubscriberFilter, bool) {
+	filter, err := ParseSubscriberFilter(database, query)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return filter, false
+	}
+	return filter, true
+}
+
+// ParseSubscriberFilter builds a subscriber filter from query parameters, see
+// subscriberFilter. The error describes the first invalid parameter.
+func ParseSubscriberFilter(database *db.DB, query url.Values) (db.SubscriberFilter, error) {
+	filter := db.SubscriberFilter{Status: query.Get("status")}
+	if filter.Status != "" && !validSubscriberStatus(filter.Status) {
+		return filter, errors.New("invalid status: must be pending, verified, unsubscribed, or bounced")
+	}
+	if listID := query.Get("list"); listID != "" {
+		list, err := database.GetListByUUID(listID)
+		if err != nil {
+			return filter, errors.New("unknown list")
+		}
+		filter.ListID = list.ID
+	}
+	for _, tag := range query["tag"] {
+		name := models.NormalizeTag(tag)
+		if !models.ValidTagName(name) {
+			return filter, fmt.Errorf("invalid tag %q: use up to 50 letters, digits, dots, dashes and underscores", tag)
+		}
+		filter.Tags = append(filter.Tags, name)
+	}
+	filter.Query = strings.TrimSpace(query.Get("q"))
+	if len(filter.Query) > 255 {
+		return filter, errors.New("q must be 255 characters or less")
+	}
+	if value := query.Get("created_after"); value != "" {
+		t, err := parseDateParam(value)
+		if err != nil {
+			return filter, errors.New("invalid created_after: use YYYY-MM-DD or RFC 3339")
+		}
+		filter.CreatedAfter = &t
+	}
+	if value := query.Get("created_before"); value != "" {
+		t, err := parseDateParam(value)
+		if err != nil {
+			return filter, errors.New("invalid created_before: use YYYY-MM-DD or RFC 3339")
+		}
+		filter.CreatedBefore = &t
+	}
+	attributes, err := attributeFilter(query)
+	if err != nil {
+		return filter, err
+	}
+	filter.Attributes = attributes
+	return filter, nil
+}
+
+// parseDateParam parses a date or timestamp query parameter
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// perPageParam reads the per_page query parameter, 20 unless it is between 1 and 100
+func perPageParam(r *http.Request) int {
+	perPage := 20
+	if pp := r.URL.Query().Get("per_page"); pp != "" {
+		if parsed, err := strconv.Atoi(pp); err == nil && parsed > 0 && parsed <= 100 {
+			perPage = parsed
+		}
+	}
+	return perPage
+}
+
+// subscriberSort reads the sort and order query parameters of the subscriber list.
+// It defaults to the newest subscribers first.
+// It writes a bad request response and returns false on invalid parameters.
+func subscriberSort(w http.ResponseWriter, query url.Values) (db.SubscriberSort, bool) {
+	sort := db.SubscriberSort{Field: query.Get("sort"), Desc: true}
+	if sort.Field == "" {
+		sort.Field = "created_at"
+	}
+	if !db.ValidSubscriberSort(sort.Field) {
+		response.BadRequest(w, "invalid sort: must be email, name, created_at, or verified_at")
+		return sort, false
+	}
+	switch query.Get("order") {
+	case "asc":
+		sort.Desc = false
+	case "", "desc":
+	default:
+		response.BadRequest(w, "invalid order: must be asc or desc")
+		return sort, false
+	}
+	return sort, true
+}
+
 // List handles GET /api/private/subscribers
+// Besides status and list, tag=<name> filters by tag (repeat to require several),
+// attr.<name>=<value> by attribute, q searches email and name, and
+// created_after/created_before limit the signup date. Results are ordered by
+// sort=email|name|created_at|verified_at and order=asc|desc.
+// Pages are numbered by page, or follow cursor if it is given (empty for the
+// first page), which skips counting and stays stable while subscribers are added.
 func (h *SubscriberHandler) List(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	status := r.URL.Query().Get("status")
-	if status != "" && status != models.StatusPending && status != models.StatusVerified && status != models.StatusUnsubscribed {
-		response.BadRequest(w, "invalid status: must be pending, verified, or unsubscribed")
+	filter, ok := subscriberFilter(w, h.db, r.URL.Query())
+	if !ok {
+		return
+	}
+	sort, ok := subscriberSort(w, r.URL.Query())
+	if !ok {
 		return
 	}
 
@@ -132,14 +290,29 @@ func (h *SubscriberHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	perPage := 20
-	if pp := r.URL.Query().Get("per_page"); pp != "" {
-		if parsed, err := strconv.Atoi(pp); err == nil && parsed > 0 && parsed <= 100 {
-			perPage = parsed
+	perPage := perPageParam(r)
+
+	if r.URL.Query().Has("cursor") {
+		subscribers, next, err := h.db.ListSubscribersAfter(filter, sort, r.URL.Query().Get("cursor"), perPage)
+		if err != nil {
+			if errors.Is(err, db.ErrInvalidCursor) {
+				response.BadRequest(w, "invalid cursor")
+				return
+			}
+			response.InternalError(w, "failed to list subscribers")
+			return
+		}
+
+		// Ensure we return an empty array instead of null
+		if subscribers == nil {
+			subscribers = []*models.Subscriber{}
 		}
+
+		response.CursorPaginatedResponse(w, subscribers, perPage, next)
+		return
 	}
 
-	subscribers, total, err := h.db.ListSubscribers(status, page, perPage)
+	subscribers, total, err := h.db.ListSubscribers(filter, sort, page, perPage)
 	if err != nil {
 		response.InternalError(w, "failed to list subscribers")
 		return
@@ -174,6 +347,59 @@ func (h *SubscriberHandler) Get(w http.ResponseWriter, r *http.Request) {
 	response.OK(w, sub)
 }
 
+// Update handles PUT /api/private/subscribers/{id}
+func (h *SubscriberHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.BadRequest(w, "subscriber id is required")
+		return
+	}
+
+	var req UpdateSubscriberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+
+	sub, err := h.db.GetSubscriberByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get subscriber") {
+			response.NotFound(w, "subscriber not found")
+			return
+		}
+		response.InternalError(w, "failed to get subscriber")
+		return
+	}
+
+	if req.Name != nil {
+		sub.Name = strings.TrimSpace(*req.Name)
+		if len(sub.Name) > 255 {
+			response.BadRequest(w, "name must be 255 characters or less")
+			return
+		}
+	}
+	if req.Attributes != nil {
+		if err := models.ValidateAttributes(req.Attributes); err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		sub.Attributes = req.Attributes
+	}
+
+	if err := h.db.UpdateSubscriber(sub); err != nil {
+		response.InternalError(w, "failed to update subscriber")
+		return
+	}
+
+	updated, err := h.db.GetSubscriberByID(sub.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get subscriber")
+		return
+	}
+
+	response.OK(w, updated)
+}
+
 // Delete handles DELETE /api/private/subscribers/{id}
 func (h *SubscriberHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -238,12 +464,7 @@ func (h *SubscriberHandler) SendVerification(w http.ResponseWriter, r *http.Requ
 	}
 
 	verifyURL := h.publicURL + "/api/verify/" + *sub.VerifyToken
-	name := sub.Name
-	if name == "" {
-		name = "there"
-	}
-
-	if err := h.mailer.SendVerification(sub.Email, name, verifyURL); err != nil {
+	if err := h.mailer.SendVerification(sub.Email, sub.Name, verifyURL); err != nil {
 		response.InternalError(w, "failed to send verification email")
 		return
 	}
@@ -256,8 +477,16 @@ func (h *SubscriberHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 	r.Post("/", h.Create)
 	r.Get("/", h.List)
+	r.Post("/import", h.Import)
+	r.Get("/export", h.Export)
+	r.Get("/tags", h.ListTags)
+	r.Post("/tags/{tag}", h.BulkTag)
+	r.Delete("/tags/{tag}", h.BulkUntag)
 	r.Get("/{id}", h.Get)
+	r.Put("/{id}", h.Update)
 	r.Delete("/{id}", h.Delete)
 	r.Post("/{id}/send-verification", h.SendVerification)
+	r.Post("/{id}/tags", h.AddTags)
+	r.Delete("/{id}/tags/{tag}", h.RemoveTag)
 	return r
 }
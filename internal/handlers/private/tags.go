@@ -0,0 +1,173 @@
+package private
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// TagsRequest represents the request body for tagging a subscriber
+type TagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// BulkTagResponse reports how many subscribers a bulk tag operation changed
+type BulkTagResponse struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// ListTags handles GET /api/private/subscribers/tags
+func (h *SubscriberHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.db.ListTags()
+	if err != nil {
+		response.InternalError(w, "failed to list tags")
+		return
+	}
+
+	// Ensure we return an empty array instead of null
+	if tags == nil {
+		tags = []*models.Tag{}
+	}
+
+	response.OK(w, tags)
+}
+
+// AddTags handles POST /api/private/subscribers/{id}/tags
+func (h *SubscriberHandler) AddTags(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.getSubscriber(w, r)
+	if !ok {
+		return
+	}
+
+	var req TagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+	if len(req.Tags) == 0 {
+		response.BadRequest(w, "tags are required")
+		return
+	}
+	tags := make([]string, 0, len(req.Tags))
+	for _, tag := range req.Tags {
+		name, ok := tagName(w, tag)
+		if !ok {
+			return
+		}
+		tags = append(tags, name)
+	}
+
+	if err := h.db.AddSubscriberTags(sub.ID, tags); err != nil {
+		response.InternalError(w, "failed to tag subscriber")
+		return
+	}
+
+	updated, err := h.db.GetSubscriberByID(sub.ID)
+	if err != nil {
+		response.InternalError(w, "failed to get subscriber")
+		return
+	}
+
+	response.OK(w, updated)
+}
+
+// RemoveTag handles DELETE /api/private/subscribers/{id}/tags/{tag}
+func (h *SubscriberHandler) RemoveTag(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.getSubscriber(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.RemoveSubscriberTag(sub.ID, models.NormalizeTag(chi.URLParam(r, "tag"))); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			response.NotFound(w, "subscriber does not have this tag")
+			return
+		}
+		response.InternalError(w, "failed to untag subscriber")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// BulkTag handles POST /api/private/subscribers/tags/{tag}
+// The tag is added to every subscriber matching the filter query parameters of List.
+func (h *SubscriberHandler) BulkTag(w http.ResponseWriter, r *http.Request) {
+	name, ok := tagName(w, chi.URLParam(r, "tag"))
+	if !ok {
+		return
+	}
+	filter, ok := subscriberFilter(w, h.db, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	count, err := h.db.TagSubscribers(filter, name)
+	if err != nil {
+		response.InternalError(w, "failed to tag subscribers")
+		return
+	}
+
+	response.OK(w, BulkTagResponse{Tag: name, Count: count})
+}
+
+// BulkUntag handles DELETE /api/private/subscribers/tags/{tag}
+// The tag is removed from every subscriber matching the filter query parameters of List.
+func (h *SubscriberHandler) BulkUntag(w http.ResponseWriter, r *http.Request) {
+	name, ok := tagName(w, chi.URLParam(r, "tag"))
+	if !ok {
+		return
+	}
+	filter, ok := subscriberFilter(w, h.db, r.URL.Query())
+	if !ok {
+		return
+	}
+
+	count, err := h.db.UntagSubscribers(filter, name)
+	if err != nil {
+		response.InternalError(w, "failed to untag subscribers")
+		return
+	}
+
+	response.OK(w, BulkTagResponse{Tag: name, Count: count})
+}
+
+// getSubscriber loads the subscriber named by the {id} URL parameter.
+// It writes an error response and returns false if the subscriber can't be loaded.
+func (h *SubscriberHandler) getSubscriber(w http.ResponseWriter, r *http.Request) (*models.Subscriber, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.BadRequest(w, "subscriber id is required")
+		return nil, false
+	}
+
+	sub, err := h.db.GetSubscriberByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get subscriber") {
+			response.NotFound(w, "subscriber not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get subscriber")
+		return nil, false
+	}
+	return sub, true
+}
+
+// tagName normalizes and validates a tag name.
+// It writes a bad request response and returns false if the name is invalid.
+func tagName(w http.ResponseWriter, tag string) (string, bool) {
+	name := models.NormalizeTag(tag)
+	if !models.ValidTagName(name) {
+		response.BadRequest(w, fmt.Sprintf("invalid tag %q: use up to 50 letters, digits, dots, dashes and underscores", tag))
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,244 @@
+package private
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/render"
+)
+
+// TemplateHandler handles stored template requests
+type TemplateHandler struct {
+	db *db.DB
+}
+
+// NewTemplateHandler creates a new template handler
+func NewTemplateHandler(database *db.DB) *TemplateHandler {
+	return &TemplateHandler{db: database}
+}
+
+// TemplateRequest represents the request body for creating or updating a template
+type TemplateRequest struct {
+	Name     *string `json:"name,omitempty"`
+	Type     *string `json:"type,omitempty"`    // Only on create, defaults to tx
+	Subject  *string `json:"subject,omitempty"` // Not used by layouts
+	BodyText *string `json:"body_text,omitempty"`
+	BodyHTML *string `json:"body_html,omitempty"`
+}
+
+// validTemplateType checks if templateType is a known template type
+func validTemplateType(templateType string) bool {
+	return templateType == models.TemplateTypeTx || templateType == models.TemplateTypeLayout
+}
+
+// Create handles POST /api/private/templates
+func (h *TemplateHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+
+	t := &models.Template{UUID: uuid.New().String(), Type: models.TemplateTypeTx}
+	if req.Type != nil {
+		t.Type = strings.TrimSpace(*req.Type)
+		if !validTemplateType(t.Type) {
+			response.BadRequest(w, "invalid type: must be tx or layout")
+			return
+		}
+	}
+	if req.Name == nil {
+		response.BadRequest(w, "name is required")
+		return
+	}
+	if !h.apply(w, t, req) {
+		return
+	}
+
+	if err := h.db.CreateTemplate(t); err != nil {
+		response.InternalError(w, "failed to create template")
+		return
+	}
+
+	response.Created(w, t)
+}
+
+// List handles GET /api/private/templates
+func (h *TemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	templateType := r.URL.Query().Get("type")
+	if templateType != "" && !validTemplateType(templateType) {
+		response.BadRequest(w, "invalid type: must be tx or layout")
+		return
+	}
+
+	templates, err := h.db.ListTemplates(templateType)
+	if err != nil {
+		response.InternalError(w, "failed to list templates")
+		return
+	}
+
+	// Ensure we return an empty array instead of null
+	if templates == nil {
+		templates = []*models.Template{}
+	}
+
+	response.OK(w, templates)
+}
+
+// Get handles GET /api/private/templates/{id}
+func (h *TemplateHandler) Get(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.getTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	response.OK(w, t)
+}
+
+// Update handles PUT /api/private/templates/{id}
+func (h *TemplateHandler) Update(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.getTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var req TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+	if req.Type != nil && *req.Type != t.Type {
+		response.BadRequest(w, "type cannot be changed")
+		return
+	}
+
+	if !h.apply(w, t, req) {
+		return
+	}
+
+	if err := h.db.UpdateTemplate(t); err != nil {
+		response.InternalError(w, "failed to update template")
+		return
+	}
+
+	response.OK(w, t)
+}
+
+// Delete handles DELETE /api/private/templates/{id}
+func (h *TemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.getTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	// Unsent campaigns would silently lose their layout
+	pending, err := h.db.CountPendingCampaignsForTemplate(t.ID)
+	if err != nil {
+		response.InternalError(w, "failed to check campaigns for template")
+		return
+	}
+	if pending > 0 {
+		response.Conflict(w, "template is used by unsent campaigns")
+		return
+	}
+
+	if err := h.db.DeleteTemplate(t.ID); err != nil {
+		response.InternalError(w, "failed to delete template")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// getTemplate loads the template named by the {id} URL parameter.
+// It writes an error response and returns false if the template can't be loaded.
+func (h *TemplateHandler) getTemplate(w http.ResponseWriter, r *http.Request) (*models.Template, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		response.BadRequest(w, "template id is required")
+		return nil, false
+	}
+
+	t, err := h.db.GetTemplateByUUID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get template") {
+			response.NotFound(w, "template not found")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get template")
+		return nil, false
+	}
+	return t, true
+}
+
+// apply validates the request fields and copies them onto t.
+// It writes a bad request response and returns false on invalid input.
+func (h *TemplateHandler) apply(w http.ResponseWriter, t *models.Template, req TemplateRequest) bool {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			response.BadRequest(w, "name cannot be empty")
+			return false
+		}
+		if len(name) > 255 {
+			response.BadRequest(w, "name must be 255 characters or less")
+			return false
+		}
+		t.Name = name
+	}
+
+	if req.Subject != nil {
+		t.Subject = strings.TrimSpace(*req.Subject)
+	}
+	if t.Subject == "" && t.Type != models.TemplateTypeLayout {
+		response.BadRequest(w, "subject is required")
+		return false
+	}
+	if len(t.Subject) > 500 {
+		response.BadRequest(w, "subject must be 500 characters or less")
+		return false
+	}
+
+	if req.BodyText != nil {
+		t.BodyText = *req.BodyText
+	}
+	if strings.TrimSpace(t.BodyText) == "" {
+		response.BadRequest(w, "body_text is required")
+		return false
+	}
+
+	if req.BodyHTML != nil {
+		t.BodyHTML = req.BodyHTML
+		if *req.BodyHTML == "" {
+			t.BodyHTML = nil
+		}
+	}
+
+	if t.Type == models.TemplateTypeLayout {
+		if err := render.ValidateLayout(*layoutOf(t)); err != nil {
+			response.BadRequest(w, err.Error())
+			return false
+		}
+		return true
+	}
+	return validateTemplates(w, t.Subject, t.BodyText, t.BodyHTML, nil)
+}
+
+// Routes returns a router with all template routes
+func (h *TemplateHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	r.Put("/{id}", h.Update)
+	r.Delete("/{id}", h.Delete)
+	return r
+}
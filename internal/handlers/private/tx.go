@@ -0,0 +1,279 @@
+package private
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/mailer"
+	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/render"
+)
+
+// maxTxSize caps the size of a transactional email request
+const maxTxSize = 1 << 20 // 1 MB
+
+// txFinishAttempts is how many times the outcome of a send is saved before
+// giving up, txFinishRetryDelay apart
+const (
+	txFinishAttempts   = 3
+	txFinishRetryDelay = 500 * time.Millisecond
+)
+
+// txPendingTimeout is how long an entry can stay in sending status before its
+// outcome is reported as unknown. It is well beyond the send timeout, so only
+// entries whose request died before saving the outcome get there.
+const txPendingTimeout = 5 * time.Minute
+
+// TxHandler handles transactional email requests
+type TxHandler struct {
+	db     *db.DB
+	mailer *mailer.Mailer
+}
+
+// NewTxHandler creates a new transactional email handler
+func NewTxHandler(database *db.DB, m *mailer.Mailer) *TxHandler {
+	return &TxHandler{db: database, mailer: m}
+}
+
+// TxRequest represents the request body for sending a transactional email.
+// Exactly one of SubscriberID and Email must be set.
+type TxRequest struct {
+	TemplateID   string                 `json:"template_id"`
+	SubscriberID string                 `json:"subscriber_id,omitempty"`
+	Email        string                 `json:"email,omitempty"`
+	Name         string                 `json:"name,omitempty"` // Recipient name for Email, ignored for subscribers
+	Data         map[string]interface{} `json:"data,omitempty"` // Available to the template as .Data
+}
+
+// Send handles POST /api/private/tx
+//
+// Renders a stored tx template and sends it to one recipient. Requests with an
+// Idempotency-Key header are sent at most once; repeating the request returns
+// the original result with the Idempotent-Replayed header set. If the original
+// request never recorded its outcome, the result is reported as unknown once
+// it has been pending for txPendingTimeout.
+func (h *TxHandler) Send(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTxSize))
+	if err != nil {
+		response.BadRequest(w, "request body too large")
+		return
+	}
+	var req TxRequest
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&req); err != nil {
+		response.BadRequest(w, "invalid JSON body")
+		return
+	}
+
+	idempotencyKey := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	if len(idempotencyKey) > 255 {
+		response.BadRequest(w, "Idempotency-Key must be 255 characters or less")
+		return
+	}
+
+	if req.TemplateID == "" {
+		response.BadRequest(w, "template_id is required")
+		return
+	}
+	if (req.SubscriberID == "") == (req.Email == "") {
+		response.BadRequest(w, "exactly one of subscriber_id and email is required")
+		return
+	}
+
+	tmpl, err := h.db.GetTemplateByUUID(req.TemplateID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get template") {
+			response.BadRequest(w, "unknown template")
+			return
+		}
+		response.InternalError(w, "failed to get template")
+		return
+	}
+	if tmpl.Type != models.TemplateTypeTx {
+		response.BadRequest(w, "template is not a tx template")
+		return
+	}
+
+	sub, ok := h.recipient(w, req)
+	if !ok {
+		return
+	}
+
+	if !h.mailer.IsConfigured() {
+		response.BadRequest(w, "SMTP is not configured")
+		return
+	}
+
+	// Render before recording anything, a broken template or payload is the caller's error
+	var bodyHTML string
+	if tmpl.BodyHTML != nil {
+		bodyHTML = *tmpl.BodyHTML
+	}
+	compiled, err := render.Compile(tmpl.Subject, tmpl.BodyText, bodyHTML)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	subject, text, html, err := compiled.Execute(render.NewTxData(sub, req.Data))
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	hash := sha256.Sum256(body)
+	entry := &models.TxLog{
+		UUID:         uuid.New().String(),
+		TemplateID:   tmpl.ID,
+		SubscriberID: sub.ID,
+		Email:        sub.Email,
+	}
+	entry, created, err := h.db.StartTxLog(entry, idempotencyKey, hex.EncodeToString(hash[:]))
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyMismatch) {
+			response.Conflict(w, "Idempotency-Key was already used for a different request")
+			return
+		}
+		response.InternalError(w, "failed to record email")
+		return
+	}
+	if !created {
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeTxResult(w, entry)
+		return
+	}
+
+	// Finish the send even if the client goes away, so a retry finds the real outcome
+	ctx := context.WithoutCancel(r.Context())
+	status := models.TxStatusSent
+	var errStr *string
+	if err := h.mailer.SendTx(ctx, sub.Email, sub.Name, subject, text, html); err != nil {
+		status = models.TxStatusFailed
+		msg := err.Error()
+		errStr = &msg
+	}
+	if err := h.finishTxLog(entry, status, errStr); err != nil {
+		// The caller still learns the outcome, retries see it as unknown later
+		log.Printf("Warning: failed to record tx email %s as %s: %v", entry.UUID, status, err)
+		entry.Status = status
+		entry.Error = errStr
+	}
+
+	writeTxResult(w, entry)
+}
+
+// finishTxLog saves the outcome of a send, retrying a failed save
+func (h *TxHandler) finishTxLog(entry *models.TxLog, status string, errStr *string) error {
+	var err error
+	for attempt := 0; attempt < txFinishAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(txFinishRetryDelay)
+		}
+		if err = h.db.FinishTxLog(entry, status, errStr); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// recipient returns the subscriber named in the request. For a plain email
+// address, the matching subscriber is used if there is one.
+func (h *TxHandler) recipient(w http.ResponseWriter, req TxRequest) (*models.Subscriber, bool) {
+	var sub *models.Subscriber
+	var err error
+	if req.SubscriberID != "" {
+		sub, err = h.db.GetSubscriberByUUID(req.SubscriberID)
+	} else {
+		email := strings.TrimSpace(strings.ToLower(req.Email))
+		if !validateEmail(email) {
+			response.BadRequest(w, "invalid email format")
+			return nil, false
+		}
+		sub, err = h.db.GetSubscriberByEmail(email)
+		if err != nil && (errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get subscriber")) {
+			return &models.Subscriber{Email: email, Name: strings.TrimSpace(req.Name)}, true
+		}
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get subscriber") {
+			response.BadRequest(w, "unknown subscriber")
+			return nil, false
+		}
+		response.InternalError(w, "failed to get subscriber")
+		return nil, false
+	}
+
+	if sub.Status == models.StatusBounced {
+		response.BadRequest(w, "subscriber address has bounced")
+		return nil, false
+	}
+	return sub, true
+}
+
+// writeTxResult responds with a tx log entry. The status code follows the
+// outcome, so replayed requests get the same response as the original.
+func writeTxResult(w http.ResponseWriter, entry *models.TxLog) {
+	switch {
+	case entry.Status == models.TxStatusSent:
+		response.Created(w, entry)
+	case entry.Status == models.TxStatusFailed:
+		response.JSON(w, http.StatusBadGateway, entry)
+	case time.Since(entry.UpdatedAt) > txPendingTimeout:
+		// The email may or may not have gone out
+		entry.Status = models.TxStatusUnknown
+		response.JSON(w, http.StatusInternalServerError, entry)
+	default:
+		response.Conflict(w, "a request with this Idempotency-Key is still being processed")
+	}
+}
+
+// List handles GET /api/private/tx
+func (h *TxHandler) List(w http.ResponseWriter, r *http.Request) {
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	perPage := 20
+	if pp := r.URL.Query().Get("per_page"); pp != "" {
+		if parsed, err := strconv.Atoi(pp); err == nil && parsed > 0 && parsed <= 100 {
+			perPage = parsed
+		}
+	}
+
+	logs, total, err := h.db.ListTxLogs(page, perPage)
+	if err != nil {
+		response.InternalError(w, "failed to list transactional emails")
+		return
+	}
+
+	// Ensure we return an empty array instead of null
+	if logs == nil {
+		logs = []*models.TxLog{}
+	}
+
+	response.PaginatedResponse(w, logs, page, perPage, total)
+}
+
+// Routes returns a router with all transactional email routes
+func (h *TxHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+	r.Post("/", h.Send)
+	r.Get("/", h.List)
+	return r
+}
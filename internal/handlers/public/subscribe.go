@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"regexp"
@@ -18,27 +19,39 @@ import (
 
 // SubscribeHandler handles public subscription requests
 type SubscribeHandler struct {
-	db        *db.DB
-	mailer    *mailer.Mailer
-	publicURL string
+	db         *db.DB
+	mailer     *mailer.Mailer
+	publicURL  string
+	attributes map[string]bool // Attributes the form may set
 }
 
-// NewSubscribeHandler creates a new subscribe handler
-func NewSubscribeHandler(database *db.DB, m *mailer.Mailer, publicURL string) *SubscribeHandler {
+// NewSubscribeHandler creates a new subscribe handler. Only the named
+// attributes are taken from the request, others are ignored.
+func NewSubscribeHandler(database *db.DB, m *mailer.Mailer, publicURL string, attributes []string) *SubscribeHandler {
+	allowed := make(map[string]bool, len(attributes))
+	for _, name := range attributes {
+		allowed[name] = true
+	}
 	return &SubscribeHandler{
-		db:        database,
-		mailer:    m,
-		publicURL: strings.TrimSuffix(publicURL, "/"),
+		db:         database,
+		mailer:     m,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
+		attributes: allowed,
 	}
 }
 
 // SubscribeRequest represents the request body for subscribing
 // TODO: verify name field, maybe not needed at all, or later can be enriched by user configuration via UI
 type SubscribeRequest struct {
-	Email string `json:"email"`
-	Name  string `json:"name"`
+	Email      string                 `json:"email"`
+	Name       string                 `json:"name"`
+	List       string                 `json:"list,omitempty"`       // List slug or UUID, empty subscribes without joining a list
+	Attributes map[string]interface{} `json:"attributes,omitempty"` // Only whitelisted names are kept
 }
 
+// maxAttributeLength caps string attribute values set through the form
+const maxAttributeLength = 255
+
 // SubscribeResponse represents the response for subscribing
 type SubscribeResponse struct {
 	Message string `json:"message"`
@@ -75,23 +88,59 @@ func (h *SubscribeHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
 		req.Name = req.Name[:255]
 	}
 
+	attributes, err := h.allowedAttributes(req.Attributes)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	// Resolve the list to join, if any
+	var list *models.List
+	if req.List != "" {
+		var err error
+		list, err = h.db.GetListByIdentifier(req.List)
+		if err != nil {
+			response.BadRequest(w, "unknown list")
+			return
+		}
+	}
+
 	// Check for existing subscriber
 	existing, err := h.db.GetSubscriberByEmail(req.Email)
 	if err == nil && existing != nil {
 		// Handle based on current status
-		if existing.Status == models.StatusUnsubscribed {
-			// Allow resubscription - generate new verify token and reset status
+		if existing.Status == models.StatusUnsubscribed || existing.Status == models.StatusBounced {
+			// Allow resubscription - generate new verify token and reset status.
+			// A bounced address has to prove it works again by verifying.
 			verifyToken := uuid.New().String()
 			if err := h.db.UpdateSubscriberForResubscribe(existing.ID, verifyToken); err != nil {
 				response.InternalError(w, "subscription failed")
 				return
 			}
+			if list != nil {
+				if err := h.db.SetListMembership(existing.ID, list.ID, models.StatusPending); err != nil {
+					response.InternalError(w, "subscription failed")
+					return
+				}
+			}
 
 			// Send verification email
-			if h.mailer.IsConfigured() {
-				verifyURL := h.publicURL + "/api/verify/" + verifyToken
-				if err := h.mailer.SendVerification(existing.Email, existing.Name, verifyURL); err != nil {
-					// Log error but don't fail the request
+			h.sendVerification(existing.Email, existing.Name, verifyToken)
+		} else if list != nil {
+			// Joining another list needs its own confirmation, even for verified subscribers
+			status, err := h.db.GetListMembershipStatus(existing.ID, list.ID)
+			if err != nil {
+				response.InternalError(w, "subscription failed")
+				return
+			}
+			if status != models.StatusVerified {
+				if err := h.db.SetListMembership(existing.ID, list.ID, models.StatusPending); err != nil {
+					response.InternalError(w, "subscription failed")
+					return
+				}
+				// Pending subscribers confirm all their lists with the email they already got
+				if existing.Status == models.StatusVerified && existing.VerifyToken != nil {
+					h.sendVerification(existing.Email, existing.Name, *existing.VerifyToken)
 				}
 			}
 		}
@@ -116,6 +165,7 @@ func (h *SubscribeHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
 		Email:            req.Email,
 		Name:             req.Name,
 		Status:           models.StatusPending,
+		Attributes:       attributes,
 		VerifyToken:      &verifyToken,
 		UnsubscribeToken: unsubscribeToken,
 	}
@@ -132,18 +182,55 @@ func (h *SubscribeHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if list != nil {
+		if err := h.db.SetListMembership(sub.ID, list.ID, models.StatusPending); err != nil {
+			response.InternalError(w, "subscription failed")
+			return
+		}
+	}
+
 	log.Printf(`{"event":"new_subscription","email":"%s","name":"%s","status":"pending"}`, req.Email, req.Name)
 
 	// Send verification email
-	if h.mailer.IsConfigured() {
-		verifyURL := h.publicURL + "/api/verify/" + verifyToken
-		if err := h.mailer.SendVerification(req.Email, req.Name, verifyURL); err != nil {
-			// Log error but don't fail the request
-			// In production, we'd want proper logging here
-		}
-	}
+	h.sendVerification(req.Email, req.Name, verifyToken)
 
 	response.OK(w, SubscribeResponse{
 		Message: "Please check your email to verify your subscription.",
 	})
 }
+
+// allowedAttributes returns the whitelisted attributes of a request. Values
+// must be strings, numbers or booleans, as forms have no use for more.
+func (h *SubscribeHandler) allowedAttributes(attributes map[string]interface{}) (map[string]interface{}, error) {
+	allowed := make(map[string]interface{})
+	for name, value := range attributes {
+		if !h.attributes[name] {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			v = strings.TrimSpace(v)
+			if len(v) > maxAttributeLength {
+				return nil, fmt.Errorf("attribute %s must be %d characters or less", name, maxAttributeLength)
+			}
+			allowed[name] = v
+		case float64, bool:
+			allowed[name] = v
+		default:
+			return nil, fmt.Errorf("attribute %s must be a string, number or boolean", name)
+		}
+	}
+	return allowed, nil
+}
+
+// sendVerification sends a verification email if SMTP is configured.
+// Errors are logged but don't fail the request.
+func (h *SubscribeHandler) sendVerification(email, name, verifyToken string) {
+	if !h.mailer.IsConfigured() {
+		return
+	}
+	verifyURL := h.publicURL + "/api/verify/" + verifyToken
+	if err := h.mailer.SendVerification(email, name, verifyURL); err != nil {
+		log.Printf("Warning: failed to send verification email: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package public
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// trackingPixel is a transparent 1x1 GIF
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// TrackHandler handles open and click tracking
+type TrackHandler struct {
+	db *db.DB
+}
+
+// NewTrackHandler creates a new track handler
+func NewTrackHandler(database *db.DB) *TrackHandler {
+	return &TrackHandler{db: database}
+}
+
+// Open handles GET /api/track/open/:campaign/:subscriber
+// The pixel is always served, tracking failures are only logged.
+func (h *TrackHandler) Open(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		w.Header().Set("Content-Type", "image/gif")
+		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+		w.Write(trackingPixel)
+	}()
+
+	campaign, err := h.db.GetCampaignByUUID(chi.URLParam(r, "campaign"))
+	if err != nil || !campaign.TrackOpens {
+		return
+	}
+	sub, err := h.db.GetSubscriberByUUID(chi.URLParam(r, "subscriber"))
+	if err != nil {
+		return
+	}
+
+	if err := h.db.CreateCampaignEvent(campaign.ID, sub.ID, models.CampaignEventOpen, 0); err != nil {
+		log.Printf("Warning: failed to record open: %v", err)
+	}
+}
+
+// Click handles GET /api/track/click/:link/:subscriber
+// The subscriber is redirected to the original URL even if recording the click fails.
+func (h *TrackHandler) Click(w http.ResponseWriter, r *http.Request) {
+	link, err := h.db.GetCampaignLinkByUUID(chi.URLParam(r, "link"))
+	if err != nil {
+		renderHTML(w, http.StatusNotFound, "Invalid Link", "This link is invalid or has expired.", false)
+		return
+	}
+
+	if sub, err := h.db.GetSubscriberByUUID(chi.URLParam(r, "subscriber")); err == nil {
+		if err := h.db.CreateCampaignEvent(link.CampaignID, sub.ID, models.CampaignEventClick, link.ID); err != nil {
+			log.Printf("Warning: failed to record click: %v", err)
+		}
+	}
+
+	http.Redirect(w, r, link.URL, http.StatusFound)
+}
@@ -9,17 +9,19 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/zhisme/tinylist/internal/db"
 	"github.com/zhisme/tinylist/internal/handlers/response"
+	"github.com/zhisme/tinylist/internal/mailer"
 	"github.com/zhisme/tinylist/internal/models"
 )
 
 // UnsubscribeHandler handles unsubscribe requests
 type UnsubscribeHandler struct {
-	db *db.DB
+	db     *db.DB
+	mailer *mailer.Mailer
 }
 
 // NewUnsubscribeHandler creates a new unsubscribe handler
-func NewUnsubscribeHandler(database *db.DB) *UnsubscribeHandler {
-	return &UnsubscribeHandler{db: database}
+func NewUnsubscribeHandler(database *db.DB, m *mailer.Mailer) *UnsubscribeHandler {
+	return &UnsubscribeHandler{db: database, mailer: m}
 }
 
 // UnsubscribeResponse represents the unsubscribe response
@@ -28,11 +30,35 @@ type UnsubscribeResponse struct {
 }
 
 // Unsubscribe handles GET /api/unsubscribe/:token
+// With a ?list= query parameter only that list membership is cancelled.
 func (h *UnsubscribeHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	message, ok := h.unsubscribe(w, r)
+	if !ok {
+		return
+	}
+
+	response.OK(w, UnsubscribeResponse{Message: message})
+}
+
+// OneClick handles POST /api/unsubscribe/:token
+// This is the RFC 8058 one-click unsubscribe sent by mail clients on behalf of the
+// subscriber, so there is nothing to show and the response has no body.
+func (h *UnsubscribeHandler) OneClick(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.unsubscribe(w, r); !ok {
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// unsubscribe cancels the subscription identified by the token URL parameter and
+// returns a message for the subscriber. It writes an error response and returns
+// false if the unsubscribe fails.
+func (h *UnsubscribeHandler) unsubscribe(w http.ResponseWriter, r *http.Request) (string, bool) {
 	token := chi.URLParam(r, "token")
 	if token == "" {
 		response.BadRequest(w, "unsubscribe token is required")
-		return
+		return "", false
 	}
 
 	// Find subscriber by token
@@ -40,27 +66,54 @@ func (h *UnsubscribeHandler) Unsubscribe(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "failed to get subscriber") {
 			response.NotFound(w, "invalid unsubscribe link")
-			return
+			return "", false
 		}
 		response.InternalError(w, "unsubscribe failed")
-		return
+		return "", false
+	}
+
+	// Unsubscribe from a single list only
+	if listID := r.URL.Query().Get("list"); listID != "" {
+		list, err := h.db.GetListByIdentifier(listID)
+		if err != nil {
+			response.NotFound(w, "invalid unsubscribe link")
+			return "", false
+		}
+		status, err := h.db.GetListMembershipStatus(sub.ID, list.ID)
+		if err != nil {
+			response.InternalError(w, "unsubscribe failed")
+			return "", false
+		}
+		if status == "" || status == models.StatusUnsubscribed {
+			return "You have already been unsubscribed from " + list.Name + ".", true
+		}
+		if err := h.db.SetListMembership(sub.ID, list.ID, models.StatusUnsubscribed); err != nil {
+			response.InternalError(w, "unsubscribe failed")
+			return "", false
+		}
+		return "You have been unsubscribed from " + list.Name + " successfully.", true
 	}
 
 	// Check if already unsubscribed
 	if sub.Status == models.StatusUnsubscribed {
-		response.OK(w, UnsubscribeResponse{
-			Message: "You have already been unsubscribed.",
-		})
-		return
+		return "You have already been unsubscribed.", true
 	}
 
 	// Update status to unsubscribed
 	if err := h.db.UpdateSubscriberStatus(sub.ID, models.StatusUnsubscribed); err != nil {
 		response.InternalError(w, "unsubscribe failed")
-		return
+		return "", false
+	}
+	for _, from := range []string{models.StatusPending, models.StatusVerified} {
+		if _, err := h.db.UpdateSubscriberListsStatus(sub.ID, from, models.StatusUnsubscribed); err != nil {
+			response.InternalError(w, "unsubscribe failed")
+			return "", false
+		}
 	}
 
-	response.OK(w, UnsubscribeResponse{
-		Message: "You have been unsubscribed successfully.",
+	sendSystemEmail(h.db, h.mailer, mailer.SystemGoodbye, func() error {
+		return h.mailer.SendGoodbye(sub.Email, sub.Name)
 	})
+
+	return "You have been unsubscribed successfully.", true
 }
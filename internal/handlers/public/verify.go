@@ -10,17 +10,37 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/mailer"
 	"github.com/zhisme/tinylist/internal/models"
 )
 
 // VerifyHandler handles email verification
 type VerifyHandler struct {
-	db *db.DB
+	db        *db.DB
+	mailer    *mailer.Mailer
+	publicURL string
 }
 
 // NewVerifyHandler creates a new verify handler
-func NewVerifyHandler(database *db.DB) *VerifyHandler {
-	return &VerifyHandler{db: database}
+func NewVerifyHandler(database *db.DB, m *mailer.Mailer, publicURL string) *VerifyHandler {
+	return &VerifyHandler{
+		db:        database,
+		mailer:    m,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// sendSystemEmail sends an optional system email in the background if it is
+// enabled in the settings and email delivery is configured. Errors are logged.
+func sendSystemEmail(database *db.DB, m *mailer.Mailer, kind string, send func() error) {
+	if enabled, _ := database.GetSetting("email_" + kind + "_enabled"); enabled != "true" || !m.IsConfigured() {
+		return
+	}
+	go func() {
+		if err := send(); err != nil {
+			log.Printf("Warning: failed to send %s email: %v", kind, err)
+		}
+	}()
 }
 
 // renderHTML renders a simple HTML page with the given title, message, and status
@@ -116,6 +136,16 @@ func (h *VerifyHandler) Verify(w http.ResponseWriter, r *http.Request) {
 
 	// Check if already verified
 	if sub.Status == models.StatusVerified {
+		// Verified subscribers confirm lists they joined later with the same link
+		confirmed, err := h.db.UpdateSubscriberListsStatus(sub.ID, models.StatusPending, models.StatusVerified)
+		if err != nil {
+			renderHTML(w, http.StatusInternalServerError, "Error", "Something went wrong. Please try again later.", false)
+			return
+		}
+		if confirmed > 0 {
+			renderHTML(w, http.StatusOK, "Subscription Confirmed", "Thank you! Your subscription has been confirmed.", true)
+			return
+		}
 		renderHTML(w, http.StatusOK, "Already Verified", "Your email address has already been verified.", true)
 		return
 	}
@@ -131,8 +161,17 @@ func (h *VerifyHandler) Verify(w http.ResponseWriter, r *http.Request) {
 		renderHTML(w, http.StatusInternalServerError, "Error", "Something went wrong. Please try again later.", false)
 		return
 	}
+	if _, err := h.db.UpdateSubscriberListsStatus(sub.ID, models.StatusPending, models.StatusVerified); err != nil {
+		renderHTML(w, http.StatusInternalServerError, "Error", "Something went wrong. Please try again later.", false)
+		return
+	}
 
 	log.Printf(`{"event":"email_verified","email":"%s","status":"verified"}`, sub.Email)
 
+	unsubscribeURL := h.publicURL + "/api/unsubscribe/" + sub.UnsubscribeToken
+	sendSystemEmail(h.db, h.mailer, mailer.SystemWelcome, func() error {
+		return h.mailer.SendWelcome(sub.Email, sub.Name, unsubscribeURL)
+	})
+
 	renderHTML(w, http.StatusOK, "Email Verified", "Thank you! Your email address has been verified successfully.", true)
 }
@@ -11,13 +11,16 @@ type Error struct {
 	Message string `json:"message,omitempty"`
 }
 
-// Paginated represents a paginated response
+// Paginated represents a paginated response. Pages are either numbered, with
+// page, total and total_pages set, or follow a cursor, with next_cursor set
+// unless it is the last page.
 type Paginated struct {
 	Data       interface{} `json:"data"`
-	Page       int         `json:"page"`
+	Page       int         `json:"page,omitempty"`
 	PerPage    int         `json:"per_page"`
-	Total      int         `json:"total"`
-	TotalPages int         `json:"total_pages"`
+	Total      *int        `json:"total,omitempty"`
+	TotalPages int         `json:"total_pages,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
 
 // JSON sends a JSON response with the given status code
@@ -91,7 +94,17 @@ func PaginatedResponse(w http.ResponseWriter, data interface{}, page, perPage, t
 		Data:       data,
 		Page:       page,
 		PerPage:    perPage,
-		Total:      total,
+		Total:      &total,
 		TotalPages: totalPages,
 	})
 }
+
+// CursorPaginatedResponse creates a cursor paginated response.
+// nextCursor is empty on the last page.
+func CursorPaginatedResponse(w http.ResponseWriter, data interface{}, perPage int, nextCursor string) {
+	OK(w, Paginated{
+		Data:       data,
+		PerPage:    perPage,
+		NextCursor: nextCursor,
+	})
+}
@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Delivery backends, selected with the delivery_backend setting
+const (
+	BackendSMTP = "smtp" // Default, uses the smtp_* settings
+	BackendFile = "file" // Writes .eml files to delivery_dir
+	BackendLog  = "log"  // Only logs recipient and subject
+	BackendHTTP = "http" // Posts messages as JSON to delivery_url
+)
+
+// Message is a fully rendered email, ready for delivery
+type Message struct {
+	FromEmail string            `json:"from_email"`
+	FromName  string            `json:"from_name,omitempty"`
+	ToEmail   string            `json:"to_email"`
+	ToName    string            `json:"to_name,omitempty"`
+	Subject   string            `json:"subject"`
+	Text      string            `json:"text"`
+	HTML      string            `json:"html,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// gomail converts the message for the gomail package
+func (msg *Message) gomail() *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetAddressHeader("From", msg.FromEmail, msg.FromName)
+	m.SetAddressHeader("To", msg.ToEmail, msg.ToName)
+	m.SetHeader("Subject", msg.Subject)
+	for name, value := range msg.Headers {
+		m.SetHeader(name, value)
+	}
+	m.SetBody("text/plain", msg.Text)
+	if msg.HTML != "" {
+		m.AddAlternative("text/html", msg.HTML)
+	}
+	return m
+}
+
+// WriteTo writes the message in RFC 5322 format, as it would go over SMTP
+func (msg *Message) WriteTo(w io.Writer) (int64, error) {
+	return msg.gomail().WriteTo(w)
+}
+
+// Backend delivers messages. Implementations must respect ctx for cancellation.
+type Backend interface {
+	// Send delivers a single message
+	Send(ctx context.Context, msg *Message) error
+	// Open starts a session for delivering several messages in a row, e.g. over
+	// one connection. Sessions are not safe for concurrent use.
+	Open() Session
+	// Configured reports whether the backend has the settings it needs
+	Configured() bool
+}
+
+// Session delivers a sequence of messages for a Backend
+type Session interface {
+	Send(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+// singleSession is a Session for backends without per-session state
+type singleSession struct {
+	backend Backend
+}
+
+func (s singleSession) Send(ctx context.Context, msg *Message) error {
+	return s.backend.Send(ctx, msg)
+}
+
+func (s singleSession) Close() error {
+	return nil
+}
+
+// BackendFromSettings returns the backend selected by the delivery_* settings,
+// or nil for SMTP, which is configured separately through Reconfigure
+func BackendFromSettings(settings map[string]string) (Backend, error) {
+	switch name := settings["delivery_backend"]; name {
+	case "", BackendSMTP:
+		return nil, nil
+	case BackendFile:
+		return NewFileBackend(settings["delivery_dir"]), nil
+	case BackendLog:
+		return NewLogBackend(), nil
+	case BackendHTTP:
+		return NewHTTPBackend(settings["delivery_url"], settings["delivery_token"]), nil
+	default:
+		return nil, fmt.Errorf("unknown delivery backend %q", name)
+	}
+}
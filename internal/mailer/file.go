@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileBackend writes each message as an .eml file to a directory instead of
+// sending it. Files are written under a temporary name and renamed once
+// complete, so a reader watching the directory never sees partial messages.
+type FileBackend struct {
+	dir string
+}
+
+// NewFileBackend creates a file backend writing to dir
+func NewFileBackend(dir string) *FileBackend {
+	return &FileBackend{dir: dir}
+}
+
+// Send writes the message to a new file
+func (b *FileBackend) Send(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("send cancelled or timed out: %w", err)
+	}
+	if err := os.MkdirAll(b.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create delivery directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), uuid.New().String())
+	tmp, err := os.CreateTemp(b.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create message file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := msg.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write message file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write message file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(b.dir, name)); err != nil {
+		return fmt.Errorf("failed to write message file: %w", err)
+	}
+	return nil
+}
+
+// Open returns a session writing files like Send
+func (b *FileBackend) Open() Session {
+	return singleSession{b}
+}
+
+// Configured reports whether a directory is set
+func (b *FileBackend) Configured() bool {
+	return b.dir != ""
+}
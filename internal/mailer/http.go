@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPBackend posts each message as JSON to an email API endpoint. The body is
+// the Message itself; any 2xx response counts as delivered. With a token set,
+// it is sent as a bearer token.
+type HTTPBackend struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPBackend creates an HTTP backend posting to url
+func NewHTTPBackend(url, token string) *HTTPBackend {
+	return &HTTPBackend{url: url, token: token, client: &http.Client{}}
+}
+
+// Send posts the message
+func (b *HTTPBackend) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to send email: %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// Open returns a session posting messages like Send. The HTTP client keeps
+// connections alive on its own.
+func (b *HTTPBackend) Open() Session {
+	return singleSession{b}
+}
+
+// Configured reports whether an endpoint is set
+func (b *HTTPBackend) Configured() bool {
+	return b.url != ""
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// LogBackend only logs recipient and subject of each message, for development
+type LogBackend struct{}
+
+// NewLogBackend creates a log backend
+func NewLogBackend() *LogBackend {
+	return &LogBackend{}
+}
+
+// Send logs the message
+func (b *LogBackend) Send(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("send cancelled or timed out: %w", err)
+	}
+	log.Printf(`{"event":"email","to":%q,"subject":%q}`, msg.ToEmail, msg.Subject)
+	return nil
+}
+
+// Open returns a session logging messages like Send
+func (b *LogBackend) Open() Session {
+	return singleSession{b}
+}
+
+// Configured always reports true, there is nothing to set up
+func (b *LogBackend) Configured() bool {
+	return true
+}
@@ -4,50 +4,64 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
-	"gopkg.in/gomail.v2"
+	"github.com/zhisme/tinylist/internal/render"
 )
 
-// Default timeout for SMTP operations
+// Default timeout for send operations
 const defaultSendTimeout = 30 * time.Second
 
-// Mailer handles email sending
-// SMTP settings are loaded from database via Reconfigure()
+// defaultFromEmail is the sender used by non-SMTP backends when no from address is set
+const defaultFromEmail = "tinylist@localhost"
+
+// Mailer builds emails and hands them to the delivery backend.
+// SMTP settings are loaded from database via Reconfigure(), another backend
+// can be selected with SetBackend().
 type Mailer struct {
-	dialer      *gomail.Dialer
+	mu          sync.RWMutex
+	smtp        *SMTPBackend
+	backend     Backend                     // Overrides SMTP when set
+	system      map[string]*render.Template // Customized system emails by kind
 	fromEmail   string
 	fromName    string
 	sendTimeout time.Duration
-	host        string
-	port        int
-	username    string
-	password    string
-	tls         bool
 }
 
 // New creates a new unconfigured Mailer instance
 // SMTP settings must be loaded from database using Reconfigure()
 func New() *Mailer {
 	return &Mailer{
-		dialer:      gomail.NewDialer("", 587, "", ""),
+		smtp:        NewSMTPBackend("", 587, "", "", false),
 		sendTimeout: defaultSendTimeout,
 	}
 }
 
 // Reconfigure updates the mailer with new SMTP settings
 func (m *Mailer) Reconfigure(host string, port int, username, password, fromEmail, fromName string, tls bool) {
-	dialer := gomail.NewDialer(host, port, username, password)
-	dialer.SSL = tls && port == 465
-
-	m.dialer = dialer
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.smtp = NewSMTPBackend(host, port, username, password, tls)
 	m.fromEmail = fromEmail
 	m.fromName = fromName
-	m.host = host
-	m.port = port
-	m.username = username
-	m.password = password
-	m.tls = tls
+}
+
+// SetBackend delivers through b instead of SMTP, nil switches back to SMTP
+func (m *Mailer) SetBackend(b Backend) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backend = b
+}
+
+// current returns the active backend
+func (m *Mailer) current() Backend {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.backend != nil {
+		return m.backend
+	}
+	return m.smtp
 }
 
 // SendTest sends a test email to verify SMTP configuration
@@ -74,50 +88,53 @@ Best regards,
 	return m.send(toEmail, "", subject, textBody, htmlBody)
 }
 
-// TODO: move to separate email template files if they get more complex
-// SendVerification sends a verification email
+// SendVerification sends the double opt-in email with the verification link
 func (m *Mailer) SendVerification(toEmail, toName, verifyURL string) error {
-	// Use fallback greeting for email body, but keep actual name for To header
-	greeting := toName
-	if greeting == "" {
-		greeting = "there"
-	}
-
-	subject := "Please verify your email address"
-	textBody := fmt.Sprintf(`Hi %s,
+	return m.sendSystem(SystemVerification, toEmail, toName, map[string]string{"verify_url": verifyURL})
+}
 
-Thanks for subscribing! Please verify your email address by clicking the link below:
+// SendWelcome sends the welcome email once a subscriber has verified their address
+func (m *Mailer) SendWelcome(toEmail, toName, unsubscribeURL string) error {
+	return m.sendSystem(SystemWelcome, toEmail, toName, map[string]string{"unsubscribe_url": unsubscribeURL})
+}
 
-%s
+// SendGoodbye confirms to a subscriber that they have been unsubscribed
+func (m *Mailer) SendGoodbye(toEmail, toName string) error {
+	return m.sendSystem(SystemGoodbye, toEmail, toName, nil)
+}
 
-If you didn't subscribe to this list, you can safely ignore this email.
+// SendCampaign sends a campaign email with context support for cancellation/timeout.
+// headers are added to the message as is, along with the RFC 8058 one-click
+// unsubscribe headers required by large mailbox providers for bulk mail.
+func (m *Mailer) SendCampaign(ctx context.Context, toEmail, toName, subject, textBody, htmlBody, unsubscribeURL string, headers map[string]string) error {
+	msg := m.campaignMessage(toEmail, toName, subject, textBody, htmlBody, unsubscribeURL, headers)
+	return m.sendWithContext(ctx, m.current(), msg)
+}
 
-Best regards,
-%s`, greeting, verifyURL, m.fromName)
+// SendTx sends a transactional email. Unlike campaigns these carry no
+// unsubscribe footer or headers, as they are not bulk mail.
+func (m *Mailer) SendTx(ctx context.Context, toEmail, toName, subject, textBody, htmlBody string) error {
+	msg := m.newMessage(toEmail, toName, subject, textBody, htmlBody, nil)
+	return m.sendWithContext(ctx, m.current(), msg)
+}
 
-	htmlBody := fmt.Sprintf(`<!DOCTYPE html>
-<html>
-<head><meta charset="UTF-8"></head>
-<body style="font-family: sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
-<h2>Verify your email address</h2>
-<p>Hi %s,</p>
-<p>Thanks for subscribing! Please verify your email address by clicking the button below:</p>
-<p style="margin: 30px 0;">
-  <a href="%s" style="background-color: #4CAF50; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px;">Verify Email</a>
-</p>
-<p>Or copy and paste this link into your browser:</p>
-<p style="word-break: break-all; color: #666;">%s</p>
-<p style="color: #999; font-size: 12px; margin-top: 40px;">
-If you didn't subscribe to this list, you can safely ignore this email.
-</p>
-</body>
-</html>`, greeting, verifyURL, verifyURL)
+// campaignMessage builds a campaign email with the unsubscribe headers and footer
+func (m *Mailer) campaignMessage(toEmail, toName, subject, textBody, htmlBody, unsubscribeURL string, headers map[string]string) *Message {
+	listHeaders := map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	for name, value := range headers {
+		listHeaders[name] = value
+	}
 
-	return m.send(toEmail, toName, subject, textBody, htmlBody)
+	textBody, htmlBody = AddUnsubscribeFooter(textBody, htmlBody, unsubscribeURL)
+	return m.newMessage(toEmail, toName, subject, textBody, htmlBody, listHeaders)
 }
 
-// SendCampaign sends a campaign email with context support for cancellation/timeout
-func (m *Mailer) SendCampaign(ctx context.Context, toEmail, toName, subject, textBody, htmlBody, unsubscribeURL string) error {
+// AddUnsubscribeFooter appends the unsubscribe notice to the text body and, if
+// present, the HTML body of a campaign email
+func AddUnsubscribeFooter(textBody, htmlBody, unsubscribeURL string) (string, string) {
 	// Append unsubscribe link to text body
 	textBody = textBody + fmt.Sprintf("\n\n---\nYou received this email because you are in my list of subscribers. I send these emails occasionally. Visit %s to unsubscribe instantly (no questions asked — you can always resubscribe).", unsubscribeURL)
 
@@ -133,64 +150,57 @@ You received this email because you are in my list of subscribers. I send these
 		}
 	}
 
-	return m.sendWithContext(ctx, toEmail, toName, subject, textBody, htmlBody)
+	return textBody, htmlBody
 }
 
-// send sends an email (blocking, no timeout)
-func (m *Mailer) send(toEmail, toName, subject, textBody, htmlBody string) error {
-	msg := gomail.NewMessage()
-	msg.SetAddressHeader("From", m.fromEmail, m.fromName)
-	msg.SetAddressHeader("To", toEmail, toName)
-	msg.SetHeader("Subject", subject)
-	msg.SetBody("text/plain", textBody)
-	if htmlBody != "" {
-		msg.AddAlternative("text/html", htmlBody)
+// newMessage builds an email from the configured sender
+func (m *Mailer) newMessage(toEmail, toName, subject, textBody, htmlBody string, headers map[string]string) *Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	fromEmail := m.fromEmail
+	if fromEmail == "" && m.backend != nil {
+		fromEmail = defaultFromEmail
 	}
-
-	if err := m.dialer.DialAndSend(msg); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+	return &Message{
+		FromEmail: fromEmail,
+		FromName:  m.fromName,
+		ToEmail:   toEmail,
+		ToName:    toName,
+		Subject:   subject,
+		Text:      textBody,
+		HTML:      htmlBody,
+		Headers:   headers,
 	}
+}
 
-	return nil
+// send sends an email with the default timeout
+func (m *Mailer) send(toEmail, toName, subject, textBody, htmlBody string) error {
+	msg := m.newMessage(toEmail, toName, subject, textBody, htmlBody, nil)
+	return m.sendWithContext(context.Background(), m.current(), msg)
 }
 
-// sendWithContext sends an email with context support for cancellation/timeout
-func (m *Mailer) sendWithContext(ctx context.Context, toEmail, toName, subject, textBody, htmlBody string) error {
-	msg := gomail.NewMessage()
-	msg.SetAddressHeader("From", m.fromEmail, m.fromName)
-	msg.SetAddressHeader("To", toEmail, toName)
-	msg.SetHeader("Subject", subject)
-	msg.SetBody("text/plain", textBody)
-	if htmlBody != "" {
-		msg.AddAlternative("text/html", htmlBody)
-	}
+// deliverer is implemented by both Backend and Session
+type deliverer interface {
+	Send(ctx context.Context, msg *Message) error
+}
 
-	// Create a timeout context if parent doesn't have deadline
-	sendCtx := ctx
+// sendWithContext sends an email through d, applying the send timeout unless
+// the parent context has a deadline already
+func (m *Mailer) sendWithContext(ctx context.Context, d deliverer, msg *Message) error {
 	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
 		var cancel context.CancelFunc
-		sendCtx, cancel = context.WithTimeout(ctx, m.sendTimeout)
+		ctx, cancel = context.WithTimeout(ctx, m.sendTimeout)
 		defer cancel()
 	}
-
-	// Run send in goroutine so we can respect context cancellation
-	errCh := make(chan error, 1)
-	go func() {
-		errCh <- m.dialer.DialAndSend(msg)
-	}()
-
-	select {
-	case <-sendCtx.Done():
-		return fmt.Errorf("send cancelled or timed out: %w", sendCtx.Err())
-	case err := <-errCh:
-		if err != nil {
-			return fmt.Errorf("failed to send email: %w", err)
-		}
-		return nil
-	}
+	return d.Send(ctx, msg)
 }
 
-// IsConfigured returns true if SMTP is configured
+// IsConfigured returns true if the active backend can deliver. SMTP also needs a from address.
 func (m *Mailer) IsConfigured() bool {
-	return m.host != "" && m.fromEmail != ""
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.backend != nil {
+		return m.backend.Configured()
+	}
+	return m.smtp.Configured() && m.fromEmail != ""
 }
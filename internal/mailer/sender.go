@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"context"
+)
+
+// Sender sends campaign emails over one session of the active backend. With
+// SMTP that is a single connection kept open between messages.
+//
+// A Sender is not safe for concurrent use, run one per parallel connection.
+type Sender struct {
+	m       *Mailer
+	session Session
+}
+
+// NewSender returns a Sender using the current backend. SMTP connections are
+// dialed on the first send.
+func (m *Mailer) NewSender() *Sender {
+	return &Sender{m: m, session: m.current().Open()}
+}
+
+// SendCampaign sends a campaign email like Mailer.SendCampaign, reusing the session
+func (s *Sender) SendCampaign(ctx context.Context, toEmail, toName, subject, textBody, htmlBody, unsubscribeURL string, headers map[string]string) error {
+	msg := s.m.campaignMessage(toEmail, toName, subject, textBody, htmlBody, unsubscribeURL, headers)
+	return s.m.sendWithContext(ctx, s.session, msg)
+}
+
+// Close ends the session, closing any open connection
+func (s *Sender) Close() error {
+	return s.session.Close()
+}
@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/gomail.v2"
+)
+
+// SMTPBackend delivers messages over SMTP
+type SMTPBackend struct {
+	dialer *gomail.Dialer
+	host   string
+}
+
+// NewSMTPBackend creates an SMTP backend. With tls set, port 465 uses implicit
+// TLS, other ports use STARTTLS when the server offers it.
+func NewSMTPBackend(host string, port int, username, password string, tls bool) *SMTPBackend {
+	dialer := gomail.NewDialer(host, port, username, password)
+	dialer.SSL = tls && port == 465
+	return &SMTPBackend{dialer: dialer, host: host}
+}
+
+// Send delivers a message over a new connection
+func (b *SMTPBackend) Send(ctx context.Context, msg *Message) error {
+	// Run send in goroutine so we can respect context cancellation
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- b.dialer.DialAndSend(msg.gomail())
+	}()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("send cancelled or timed out: %w", ctx.Err())
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	}
+}
+
+// Open returns a session that keeps one connection open between messages, so
+// only the first message pays for the TCP, TLS and AUTH handshake. After a
+// failed send the connection is closed and the next message dials a fresh one,
+// which also resets any half-finished SMTP transaction.
+func (b *SMTPBackend) Open() Session {
+	return &smtpSession{dialer: b.dialer}
+}
+
+// Configured reports whether a host is set
+func (b *SMTPBackend) Configured() bool {
+	return b.host != ""
+}
+
+// smtpSession sends messages over a single, reused SMTP connection
+type smtpSession struct {
+	dialer *gomail.Dialer
+	conn   gomail.SendCloser
+}
+
+// sendResult is the outcome of a send running in the background
+type sendResult struct {
+	conn gomail.SendCloser
+	err  error
+}
+
+// Send delivers a message over the open connection, dialing one if needed
+func (s *smtpSession) Send(ctx context.Context, msg *Message) error {
+	// The connection is handed to the goroutine and only taken back once it is done with it
+	conn := s.conn
+	s.conn = nil
+	resultCh := make(chan sendResult, 1)
+	go func() {
+		resultCh <- s.deliver(conn, msg.gomail())
+	}()
+
+	select {
+	case <-ctx.Done():
+		// Abandon the connection, it is closed once the send returns
+		go func() {
+			if result := <-resultCh; result.conn != nil {
+				result.conn.Close()
+			}
+		}()
+		return fmt.Errorf("send cancelled or timed out: %w", ctx.Err())
+	case result := <-resultCh:
+		s.conn = result.conn
+		if result.err != nil {
+			return fmt.Errorf("failed to send email: %w", result.err)
+		}
+		return nil
+	}
+}
+
+// deliver sends msg over conn, dialing first if conn is nil. The returned
+// connection is nil if the send failed, the failed one is closed.
+func (s *smtpSession) deliver(conn gomail.SendCloser, msg *gomail.Message) sendResult {
+	if conn == nil {
+		var err error
+		if conn, err = s.dialer.Dial(); err != nil {
+			return sendResult{err: err}
+		}
+	}
+
+	if err := gomail.Send(conn, msg); err != nil {
+		conn.Close()
+		return sendResult{err: err}
+	}
+	return sendResult{conn: conn}
+}
+
+// Close closes the open connection, if any
+func (s *smtpSession) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,231 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/render"
+)
+
+// System emails sent by TinyList itself
+const (
+	SystemVerification = "verification" // Double opt-in link after subscribing
+	SystemWelcome      = "welcome"      // After the address has been verified
+	SystemGoodbye      = "goodbye"      // After unsubscribing
+)
+
+// SystemEmailKinds lists all system emails
+var SystemEmailKinds = []string{SystemVerification, SystemWelcome, SystemGoodbye}
+
+// SystemEmail is the subject and bodies of a system email. They are templates
+// like transactional emails, with the links in .Data.verify_url and
+// .Data.unsubscribe_url and the sender name in .Data.from_name.
+type SystemEmail struct {
+	Subject  string `json:"subject"`
+	BodyText string `json:"body_text"`
+	BodyHTML string `json:"body_html"`
+}
+
+// IsSystemEmail reports whether kind names a system email
+func IsSystemEmail(kind string) bool {
+	for _, k := range SystemEmailKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinEmails are used when no valid template is stored in the settings
+var builtinEmails = map[string]SystemEmail{
+	SystemVerification: {
+		Subject: "Please verify your email address",
+		BodyText: `Hi {{ .Name | default "there" }},
+
+Thanks for subscribing! Please verify your email address by clicking the link below:
+
+{{ .Data.verify_url }}
+
+If you didn't subscribe to this list, you can safely ignore this email.
+
+Best regards,
+{{ .Data.from_name }}`,
+		BodyHTML: `<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="font-family: sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+<h2>Verify your email address</h2>
+<p>Hi {{ .Name | default "there" }},</p>
+<p>Thanks for subscribing! Please verify your email address by clicking the button below:</p>
+<p style="margin: 30px 0;">
+  <a href="{{ .Data.verify_url }}" style="background-color: #4CAF50; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px;">Verify Email</a>
+</p>
+<p>Or copy and paste this link into your browser:</p>
+<p style="word-break: break-all; color: #666;">{{ .Data.verify_url }}</p>
+<p style="color: #999; font-size: 12px; margin-top: 40px;">
+If you didn't subscribe to this list, you can safely ignore this email.
+</p>
+</body>
+</html>`,
+	},
+	SystemWelcome: {
+		Subject: "Welcome aboard!",
+		BodyText: `Hi {{ .Name | default "there" }},
+
+Your email address has been verified, you are now subscribed.
+
+You can unsubscribe at any time: {{ .Data.unsubscribe_url }}
+
+Best regards,
+{{ .Data.from_name }}`,
+		BodyHTML: `<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="font-family: sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+<h2>Welcome aboard!</h2>
+<p>Hi {{ .Name | default "there" }},</p>
+<p>Your email address has been verified, you are now subscribed.</p>
+<p style="margin-top: 40px;">Best regards,<br>{{ .Data.from_name }}</p>
+<p style="color: #999; font-size: 12px; margin-top: 40px;">
+<a href="{{ .Data.unsubscribe_url }}" style="color: #666;">Unsubscribe</a> at any time.
+</p>
+</body>
+</html>`,
+	},
+	SystemGoodbye: {
+		Subject: "You have been unsubscribed",
+		BodyText: `Hi {{ .Name | default "there" }},
+
+You have been unsubscribed and won't receive any more emails from this list.
+
+If this was a mistake, you are always welcome to subscribe again.
+
+Best regards,
+{{ .Data.from_name }}`,
+		BodyHTML: `<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="font-family: sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+<h2>You have been unsubscribed</h2>
+<p>Hi {{ .Name | default "there" }},</p>
+<p>You have been unsubscribed and won't receive any more emails from this list.</p>
+<p>If this was a mistake, you are always welcome to subscribe again.</p>
+<p style="margin-top: 40px;">Best regards,<br>{{ .Data.from_name }}</p>
+</body>
+</html>`,
+	},
+}
+
+// builtins holds the compiled built-in emails
+var builtins = func() map[string]*render.Template {
+	compiled := make(map[string]*render.Template, len(builtinEmails))
+	for kind, email := range builtinEmails {
+		t, err := render.Compile(email.Subject, email.BodyText, email.BodyHTML)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in %s email: %v", kind, err))
+		}
+		compiled[kind] = t
+	}
+	return compiled
+}()
+
+// BuiltinSystemEmail returns the built-in subject and bodies of a system email
+func BuiltinSystemEmail(kind string) SystemEmail {
+	return builtinEmails[kind]
+}
+
+// SystemEmailsFromSettings returns the system emails stored in the settings,
+// keyed email_<kind>_subject, email_<kind>_text and email_<kind>_html. Kinds
+// without a subject and text body are left out, so the built-in is used.
+func SystemEmailsFromSettings(settings map[string]string) map[string]SystemEmail {
+	emails := make(map[string]SystemEmail)
+	for _, kind := range SystemEmailKinds {
+		email := SystemEmail{
+			Subject:  settings["email_"+kind+"_subject"],
+			BodyText: settings["email_"+kind+"_text"],
+			BodyHTML: settings["email_"+kind+"_html"],
+		}
+		if email.Subject != "" && email.BodyText != "" {
+			emails[kind] = email
+		}
+	}
+	return emails
+}
+
+// ValidateSystemEmail checks that the subject and bodies of a system email are valid templates
+func ValidateSystemEmail(email SystemEmail) error {
+	if email.Subject == "" {
+		return fmt.Errorf("subject is required")
+	}
+	if email.BodyText == "" {
+		return fmt.Errorf("body_text is required")
+	}
+	return render.Validate(email.Subject, email.BodyText, email.BodyHTML)
+}
+
+// SetSystemEmails replaces the customized system emails. Kinds that are
+// missing or fail to compile fall back to the built-in.
+func (m *Mailer) SetSystemEmails(emails map[string]SystemEmail) {
+	compiled := make(map[string]*render.Template, len(emails))
+	for kind, email := range emails {
+		t, err := render.Compile(email.Subject, email.BodyText, email.BodyHTML)
+		if err != nil {
+			log.Printf("Warning: invalid %s email template, using the built-in: %v", kind, err)
+			continue
+		}
+		compiled[kind] = t
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.system = compiled
+}
+
+// RenderSystemEmail renders a system email for the recipient. email is used if
+// it isn't nil, otherwise the customized template, otherwise the built-in.
+// Stored templates that fail to render fall back to the built-in as well.
+func (m *Mailer) RenderSystemEmail(kind string, email *SystemEmail, toEmail, toName string, urls map[string]string) (subject, text, html string, err error) {
+	builtin, ok := builtins[kind]
+	if !ok {
+		return "", "", "", fmt.Errorf("unknown system email %q", kind)
+	}
+
+	m.mu.RLock()
+	tmpl := m.system[kind]
+	fromName := m.fromName
+	m.mu.RUnlock()
+
+	payload := map[string]interface{}{"from_name": fromName}
+	for key, value := range urls {
+		payload[key] = value
+	}
+	data := render.NewTxData(&models.Subscriber{Email: toEmail, Name: toName}, payload)
+
+	// An explicitly given template is being previewed, so errors are reported
+	if email != nil {
+		t, err := render.Compile(email.Subject, email.BodyText, email.BodyHTML)
+		if err != nil {
+			return "", "", "", err
+		}
+		return t.Execute(data)
+	}
+
+	if tmpl != nil {
+		subject, text, html, err = tmpl.Execute(data)
+		if err == nil {
+			return subject, text, html, nil
+		}
+		log.Printf("Warning: failed to render %s email, using the built-in: %v", kind, err)
+	}
+	return builtin.Execute(data)
+}
+
+// sendSystem renders and sends a system email
+func (m *Mailer) sendSystem(kind, toEmail, toName string, urls map[string]string) error {
+	subject, text, html, err := m.RenderSystemEmail(kind, nil, toEmail, toName, urls)
+	if err != nil {
+		return err
+	}
+	return m.send(toEmail, toName, subject, text, html)
+}
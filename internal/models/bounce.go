@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Bounce represents a delivery failure reported for a subscriber
+type Bounce struct {
+	ID             int       `json:"id"`
+	SubscriberID   int       `json:"-"`
+	SubscriberUUID string    `json:"subscriber_id"`
+	Email          string    `json:"email"`
+	CampaignUUID   *string   `json:"campaign_id,omitempty"`
+	Type           string    `json:"type"`   // hard, soft
+	Source         string    `json:"source"` // webhook, mailbox
+	Reason         string    `json:"reason"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Bounce types
+const (
+	BounceHard = "hard"
+	BounceSoft = "soft"
+)
+
+// Bounce sources
+const (
+	BounceSourceWebhook = "webhook"
+	BounceSourceMailbox = "mailbox"
+)
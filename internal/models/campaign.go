@@ -4,23 +4,63 @@ import "time"
 
 // Campaign represents an email campaign
 type Campaign struct {
-	ID          int        `json:"-"`
-	UUID        string     `json:"id"`
-	Subject     string     `json:"subject"`
-	BodyText    string     `json:"body_text"`
-	BodyHTML    *string    `json:"body_html,omitempty"`
-	Status      string     `json:"status"` // draft, sending, sent, failed
-	TotalCount  int        `json:"total_count"`
-	SentCount   int        `json:"sent_count"`
-	FailedCount int        `json:"failed_count"`
-	CreatedAt   time.Time  `json:"created_at"`
-	StartedAt   *time.Time `json:"started_at,omitempty"`
-	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ID           int            `json:"-"`
+	UUID         string         `json:"id"`
+	Subject      string         `json:"subject"`
+	BodyText     string         `json:"body_text"`
+	BodyHTML     *string        `json:"body_html,omitempty"`
+	Status       string         `json:"status"` // draft, scheduled, sending, sent, failed, cancelled
+	TotalCount   int            `json:"total_count"`
+	SentCount    int            `json:"sent_count"`
+	FailedCount  int            `json:"failed_count"`
+	CreatedAt    time.Time      `json:"created_at"`
+	ScheduledAt  *time.Time     `json:"scheduled_at,omitempty"`
+	StartedAt    *time.Time     `json:"started_at,omitempty"`
+	CompletedAt  *time.Time     `json:"completed_at,omitempty"`
+	ListIDs      []string       `json:"list_ids"` // Targeted list UUIDs, empty means all verified subscribers
+	TrackOpens   bool           `json:"track_opens"`
+	TrackClicks  bool           `json:"track_clicks"`
+	TemplateID   int            `json:"-"`
+	TemplateUUID *string        `json:"template_id,omitempty"` // Layout template wrapping the bodies
+	SegmentID    int            `json:"-"`
+	SegmentUUID  *string        `json:"segment_id,omitempty"` // Segment narrowing the recipients
+	Stats        *CampaignStats `json:"stats,omitempty"`      // Only set on the campaign detail endpoint
 }
 
+// CampaignStats holds open and click tracking results of a campaign.
+// Rates are relative to the number of successfully sent emails.
+type CampaignStats struct {
+	Opens     int          `json:"opens"`  // Subscribers who opened at least once
+	Clicks    int          `json:"clicks"` // Subscribers who clicked at least one link
+	OpenRate  float64      `json:"open_rate"`
+	ClickRate float64      `json:"click_rate"`
+	Links     []*LinkStats `json:"links"`
+}
+
+// LinkStats holds click results for a single tracked link
+type LinkStats struct {
+	URL    string `json:"url"`
+	Clicks int    `json:"clicks"` // Subscribers who clicked this link
+}
+
+// CampaignLink is a link in a campaign rewritten for click tracking
+type CampaignLink struct {
+	ID         int
+	UUID       string
+	CampaignID int
+	URL        string
+}
+
+// Campaign event types
+const (
+	CampaignEventOpen  = "open"
+	CampaignEventClick = "click"
+)
+
 // CampaignStatus constants
 const (
 	CampaignStatusDraft     = "draft"
+	CampaignStatusScheduled = "scheduled"
 	CampaignStatusSending   = "sending"
 	CampaignStatusSent      = "sent"
 	CampaignStatusFailed    = "failed"
@@ -29,12 +69,14 @@ const (
 
 // CampaignLog represents a log entry for campaign sends
 type CampaignLog struct {
-	ID           int       `json:"id"`
-	CampaignID   int       `json:"campaign_id"`
-	SubscriberID int       `json:"subscriber_id"`
-	Status       string    `json:"status"` // sent, failed
-	Error        *string   `json:"error,omitempty"`
-	SentAt       time.Time `json:"sent_at"`
+	ID             int       `json:"-"`
+	CampaignID     int       `json:"-"`
+	SubscriberID   int       `json:"-"`
+	SubscriberUUID string    `json:"subscriber_id"` // Only set when listing logs
+	Email          string    `json:"email"`         // Only set when listing logs
+	Status         string    `json:"status"`        // sent, failed
+	Error          *string   `json:"error,omitempty"`
+	SentAt         time.Time `json:"sent_at"`
 }
 
 // CampaignJournal represents a lifecycle event for a campaign
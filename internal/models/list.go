@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// List represents a mailing list subscribers can join
+type List struct {
+	ID              int       `json:"-"`
+	UUID            string    `json:"id"`
+	Slug            string    `json:"slug"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	SubscriberCount int       `json:"subscriber_count"` // Verified members only
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Segment is a named set of rules selecting subscribers
+type Segment struct {
+	ID        int          `json:"-"`
+	UUID      string       `json:"id"`
+	Name      string       `json:"name"`
+	Rules     SegmentRules `json:"rules"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// SegmentRules selects the subscribers of a segment. Empty rules are ignored,
+// a subscriber must match all others.
+type SegmentRules struct {
+	Status           []string               `json:"status,omitempty"`            // Any of these statuses
+	Lists            []string               `json:"lists,omitempty"`             // Verified member of any of these list UUIDs
+	SubscribedAfter  *time.Time             `json:"subscribed_after,omitempty"`  // Created at or after
+	SubscribedBefore *time.Time             `json:"subscribed_before,omitempty"` // Created before
+	Attributes       map[string]interface{} `json:"attributes,omitempty"`        // Attribute equals value
+	Tags             []string               `json:"tags,omitempty"`              // Has all of these tags
+	OpenedCampaign   string                 `json:"opened_campaign,omitempty"`   // Opened the campaign with this UUID
+	ClickedCampaign  string                 `json:"clicked_campaign,omitempty"`  // Clicked a link in the campaign with this UUID
+}
@@ -1,19 +1,26 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
 
 // Subscriber represents an email subscriber
 type Subscriber struct {
-	ID               int        `json:"-"`
-	UUID             string     `json:"id"`
-	Email            string     `json:"email"`
-	Name             string     `json:"name"`
-	Status           string     `json:"status"` // pending, verified, unsubscribed
-	VerifyToken      *string    `json:"-"`
-	UnsubscribeToken string     `json:"-"`
-	CreatedAt        time.Time  `json:"created_at"`
-	VerifiedAt       *time.Time `json:"verified_at,omitempty"`
-	UpdatedAt        time.Time  `json:"updated_at"`
+	ID               int                    `json:"-"`
+	UUID             string                 `json:"id"`
+	Email            string                 `json:"email"`
+	Name             string                 `json:"name"`
+	Status           string                 `json:"status"` // pending, verified, unsubscribed, bounced
+	Attributes       map[string]interface{} `json:"attributes"`
+	Tags             []string               `json:"tags"`
+	VerifyToken      *string                `json:"-"`
+	UnsubscribeToken string                 `json:"-"`
+	CreatedAt        time.Time              `json:"created_at"`
+	VerifiedAt       *time.Time             `json:"verified_at,omitempty"`
+	UpdatedAt        time.Time              `json:"updated_at"`
 }
 
 // SubscriberStatus constants
@@ -21,4 +28,33 @@ const (
 	StatusPending      = "pending"
 	StatusVerified     = "verified"
 	StatusUnsubscribed = "unsubscribed"
+	StatusBounced      = "bounced"
 )
+
+// MaxAttributesSize caps the encoded size of a subscriber's attributes
+const MaxAttributesSize = 16 << 10 // 16 KB
+
+// attributeKey matches keys that can be used in templates and filters
+var attributeKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// ValidAttributeKey reports whether key can be used as an attribute name
+func ValidAttributeKey(key string) bool {
+	return attributeKey.MatchString(key)
+}
+
+// ValidateAttributes checks the keys and size of subscriber attributes
+func ValidateAttributes(attributes map[string]interface{}) error {
+	for key := range attributes {
+		if !ValidAttributeKey(key) {
+			return fmt.Errorf("invalid attribute name %q: use letters, digits and underscores", key)
+		}
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return fmt.Errorf("invalid attributes: %w", err)
+	}
+	if len(data) > MaxAttributesSize {
+		return fmt.Errorf("attributes must be %d bytes or less", MaxAttributesSize)
+	}
+	return nil
+}
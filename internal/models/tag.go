@@ -0,0 +1,28 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Tag is a label attached to subscribers
+type Tag struct {
+	ID              int       `json:"-"`
+	Name            string    `json:"name"`
+	SubscriberCount int       `json:"subscriber_count"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// tagName matches normalized tag names, e.g. "beta" or "conference-2026"
+var tagName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,49}$`)
+
+// NormalizeTag returns the stored form of a tag name
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidTagName reports whether a normalized name can be used as a tag
+func ValidTagName(name string) bool {
+	return tagName.MatchString(name)
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Template is a stored email template
+type Template struct {
+	ID        int       `json:"-"`
+	UUID      string    `json:"id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"` // tx, layout
+	Subject   string    `json:"subject"`
+	BodyText  string    `json:"body_text"`
+	BodyHTML  *string   `json:"body_html,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Template types
+const (
+	TemplateTypeTx     = "tx"     // Transactional email sent through the tx API
+	TemplateTypeLayout = "layout" // Wraps campaign content at its {{ content }} slot
+)
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// TxLog records a single transactional email
+type TxLog struct {
+	ID             int       `json:"-"`
+	UUID           string    `json:"id"`
+	TemplateID     int       `json:"-"`
+	TemplateUUID   *string   `json:"template_id"` // Nil once the template is deleted
+	SubscriberID   int       `json:"-"`
+	SubscriberUUID *string   `json:"subscriber_id,omitempty"`
+	Email          string    `json:"email"`
+	Status         string    `json:"status"` // sending, sent, failed
+	Error          *string   `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Transactional email statuses
+const (
+	TxStatusSending = "sending"
+	TxStatusSent    = "sent"
+	TxStatusFailed  = "failed"
+	TxStatusUnknown = "unknown" // Reported for entries whose outcome was never recorded, never stored
+)
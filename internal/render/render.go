@@ -0,0 +1,248 @@
+// Package render executes campaign and transactional templates for a subscriber.
+//
+// Subjects and plain text bodies use text/template, HTML bodies use html/template,
+// so values inserted into HTML are escaped for their context. The legacy
+// {{name}} and {{email}} placeholders keep working.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// Data is the value templates are executed with
+type Data struct {
+	Name       string // Shorthand for Subscriber.Name
+	Email      string // Shorthand for Subscriber.Email
+	Subscriber Subscriber
+	Campaign   Campaign
+	Data       map[string]interface{} // Payload of a transactional email
+}
+
+// Subscriber holds the subscriber fields available to templates
+type Subscriber struct {
+	UUID       string
+	Email      string
+	Name       string
+	Attributes map[string]interface{}
+}
+
+// Campaign holds the campaign fields available to templates
+type Campaign struct {
+	UUID    string
+	Subject string
+}
+
+// newSubscriber returns the template fields of a subscriber
+func newSubscriber(sub *models.Subscriber) Subscriber {
+	attributes := sub.Attributes
+	if attributes == nil {
+		attributes = map[string]interface{}{}
+	}
+	return Subscriber{
+		UUID:       sub.UUID,
+		Email:      sub.Email,
+		Name:       sub.Name,
+		Attributes: attributes,
+	}
+}
+
+// NewData builds template data for sending a campaign to a subscriber
+func NewData(sub *models.Subscriber, campaign *models.Campaign) Data {
+	return Data{
+		Name:       sub.Name,
+		Email:      sub.Email,
+		Subscriber: newSubscriber(sub),
+		Campaign: Campaign{
+			UUID:    campaign.UUID,
+			Subject: campaign.Subject,
+		},
+	}
+}
+
+// NewTxData builds template data for a transactional email with the given payload
+func NewTxData(sub *models.Subscriber, payload map[string]interface{}) Data {
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	return Data{
+		Name:       sub.Name,
+		Email:      sub.Email,
+		Subscriber: newSubscriber(sub),
+		Data:       payload,
+	}
+}
+
+// funcs are the helper functions available to templates
+var funcs = map[string]interface{}{
+	// default returns def if value is empty, e.g. {{ .Name | default "there" }}
+	"default": func(def string, value interface{}) interface{} {
+		if value == nil {
+			return def
+		}
+		if s, ok := value.(string); ok && strings.TrimSpace(s) == "" {
+			return def
+		}
+		return value
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+}
+
+// legacyVars matches the {{name}} and {{email}} placeholders of earlier versions
+var legacyVars = regexp.MustCompile(`\{\{\s*(name|email)\s*\}\}`)
+
+// translateLegacy rewrites legacy placeholders to template actions
+func translateLegacy(text string) string {
+	return legacyVars.ReplaceAllStringFunc(text, func(match string) string {
+		if strings.Contains(match, "name") {
+			return "{{.Name}}"
+		}
+		return "{{.Email}}"
+	})
+}
+
+// contentSlot matches the {{ content }} placeholder of layouts
+var contentSlot = regexp.MustCompile(`\{\{\s*content\s*\}\}`)
+
+// Layout wraps the bodies of a campaign. Each body must contain a {{ content }}
+// placeholder, which is replaced by the matching campaign body. HTML is empty if
+// the layout only wraps plain text.
+type Layout struct {
+	Text string
+	HTML string
+}
+
+// ValidateLayout checks that the bodies of a layout are valid templates with a
+// content slot. Errors name the part that is invalid.
+func ValidateLayout(layout Layout) error {
+	if !contentSlot.MatchString(layout.Text) {
+		return fmt.Errorf("invalid body_text layout: missing {{ content }}")
+	}
+	if layout.HTML != "" && !contentSlot.MatchString(layout.HTML) {
+		return fmt.Errorf("invalid body_html layout: missing {{ content }}")
+	}
+	t, err := CompileWithLayout("Sample", "Sample content", "<p>Sample content</p>", &layout)
+	if err != nil {
+		return err
+	}
+	_, _, _, err = t.Execute(sampleData("Sample"))
+	return err
+}
+
+// Template is a compiled campaign, ready to be executed for each subscriber
+type Template struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template // nil if the campaign has no HTML body
+}
+
+// Compile parses the subject and bodies of a campaign. bodyHTML may be empty.
+// Errors name the part that failed to parse.
+func Compile(subject, bodyText, bodyHTML string) (*Template, error) {
+	return CompileWithLayout(subject, bodyText, bodyHTML, nil)
+}
+
+// CompileWithLayout is like Compile, but wraps the bodies in layout if it isn't
+// nil. The HTML layout is only used if the campaign has an HTML body.
+func CompileWithLayout(subject, bodyText, bodyHTML string, layout *Layout) (*Template, error) {
+	t := &Template{}
+	var err error
+
+	if t.subject, err = texttemplate.New("subject").Funcs(funcs).Parse(translateLegacy(subject)); err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+
+	t.text = texttemplate.New("body_text").Funcs(funcs)
+	if layout != nil {
+		if _, err = t.text.Parse(withSlot(layout.Text)); err != nil {
+			return nil, fmt.Errorf("invalid body_text layout: %w", err)
+		}
+		if _, err = t.text.New("content").Parse(translateLegacy(bodyText)); err != nil {
+			return nil, fmt.Errorf("invalid body_text template: %w", err)
+		}
+	} else if _, err = t.text.Parse(translateLegacy(bodyText)); err != nil {
+		return nil, fmt.Errorf("invalid body_text template: %w", err)
+	}
+
+	if bodyHTML != "" {
+		t.html = htmltemplate.New("body_html").Funcs(funcs)
+		if layout != nil && layout.HTML != "" {
+			if _, err = t.html.Parse(withSlot(layout.HTML)); err != nil {
+				return nil, fmt.Errorf("invalid body_html layout: %w", err)
+			}
+			if _, err = t.html.New("content").Parse(translateLegacy(bodyHTML)); err != nil {
+				return nil, fmt.Errorf("invalid body_html template: %w", err)
+			}
+		} else if _, err = t.html.Parse(translateLegacy(bodyHTML)); err != nil {
+			return nil, fmt.Errorf("invalid body_html template: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// withSlot rewrites the content placeholder of a layout to invoke the
+// campaign body, which is defined as the "content" template
+func withSlot(layout string) string {
+	return contentSlot.ReplaceAllString(translateLegacy(layout), `{{template "content" .}}`)
+}
+
+// Validate checks that the subject and bodies of a campaign are valid templates.
+// Besides parsing, the templates are executed once with sample data, which
+// catches errors html/template only reports on first use.
+func Validate(subject, bodyText, bodyHTML string) error {
+	return ValidateWithLayout(subject, bodyText, bodyHTML, nil)
+}
+
+// ValidateWithLayout is like Validate, with the bodies wrapped in layout if it isn't nil
+func ValidateWithLayout(subject, bodyText, bodyHTML string, layout *Layout) error {
+	t, err := CompileWithLayout(subject, bodyText, bodyHTML, layout)
+	if err != nil {
+		return err
+	}
+	_, _, _, err = t.Execute(sampleData(subject))
+	return err
+}
+
+// sampleData returns data for test runs of a template
+func sampleData(subject string) Data {
+	return Data{
+		Name:       "Jane",
+		Email:      "jane@example.com",
+		Subscriber: Subscriber{UUID: "sample", Email: "jane@example.com", Name: "Jane", Attributes: map[string]interface{}{}},
+		Campaign:   Campaign{UUID: "sample", Subject: subject},
+		Data:       map[string]interface{}{},
+	}
+}
+
+// Execute renders the subject and bodies for one subscriber.
+// html is empty if the campaign has no HTML body.
+func (t *Template) Execute(data Data) (subject, text, html string, err error) {
+	var buf bytes.Buffer
+	if err := t.subject.Execute(&buf, data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	subject = buf.String()
+
+	buf.Reset()
+	if err := t.text.Execute(&buf, data); err != nil {
+		return "", "", "", fmt.Errorf("failed to render body_text: %w", err)
+	}
+	text = buf.String()
+
+	if t.html != nil {
+		buf.Reset()
+		if err := t.html.Execute(&buf, data); err != nil {
+			return "", "", "", fmt.Errorf("failed to render body_html: %w", err)
+		}
+		html = buf.String()
+	}
+	return subject, text, html, nil
+}
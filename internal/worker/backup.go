@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/config"
+	"github.com/zhisme/tinylist/internal/db"
+)
+
+// snapshotPattern matches the files written by BackupJob, the timestamp makes
+// them sort by age
+const snapshotPattern = "tinylist-*.db"
+
+// BackupJob periodically writes database snapshots to the configured directory
+// and removes the oldest ones
+type BackupJob struct {
+	db     *db.DB
+	config config.BackupConfig
+}
+
+// NewBackupJob creates a new backup job
+func NewBackupJob(database *db.DB, cfg config.BackupConfig) *BackupJob {
+	return &BackupJob{
+		db:     database,
+		config: cfg,
+	}
+}
+
+// Run writes a snapshot every interval until ctx is cancelled. The first one
+// is written after an interval, so restarts don't rotate out older snapshots.
+// It returns immediately if no backup directory is configured.
+func (j *BackupJob) Run(ctx context.Context) {
+	if j.config.Dir == "" {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(j.config.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		path, err := j.Snapshot()
+		if err != nil {
+			log.Printf("Warning: database backup failed: %v", err)
+			continue
+		}
+		log.Printf("Database backed up to %s", path)
+	}
+}
+
+// Snapshot writes a snapshot to the backup directory, removes those beyond the
+// number to keep and returns the path of the new one
+func (j *BackupJob) Snapshot() (string, error) {
+	if err := os.MkdirAll(j.config.Dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := "tinylist-" + time.Now().UTC().Format("20060102-150405") + ".db"
+	path := filepath.Join(j.config.Dir, name)
+	if err := j.db.Backup(path); err != nil {
+		return "", err
+	}
+
+	snapshots, err := filepath.Glob(filepath.Join(j.config.Dir, snapshotPattern))
+	if err != nil {
+		return path, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	slices.Sort(snapshots)
+	for len(snapshots) > j.config.Keep {
+		if err := os.Remove(snapshots[0]); err != nil {
+			return path, fmt.Errorf("failed to remove old snapshot: %w", err)
+		}
+		snapshots = snapshots[1:]
+	}
+	return path, nil
+}
@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/bounce"
+	"github.com/zhisme/tinylist/internal/config"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// mboxOffsetSetting stores how far the bounce mbox has been read
+const mboxOffsetSetting = "bounce_mbox_offset"
+
+// BounceProcessor records bounces reported by webhook and collects them from the
+// configured mailbox
+type BounceProcessor struct {
+	db     *db.DB
+	config config.BounceConfig
+}
+
+// NewBounceProcessor creates a new bounce processor
+func NewBounceProcessor(database *db.DB, cfg config.BounceConfig) *BounceProcessor {
+	return &BounceProcessor{
+		db:     database,
+		config: cfg,
+	}
+}
+
+// Record stores a bounce and updates the subscriber's status.
+// It returns sql.ErrNoRows if the address doesn't belong to a subscriber.
+func (p *BounceProcessor) Record(b *models.Bounce) error {
+	marked, err := p.db.RecordBounce(b, p.config.SoftThreshold)
+	if err != nil {
+		return err
+	}
+	if marked {
+		log.Printf(`{"event":"subscriber_bounced","email":"%s","type":"%s"}`, b.Email, b.Type)
+	}
+	return nil
+}
+
+// RecordAll records bounces parsed from a DSN message and returns the recorded
+// ones. Bounces for addresses that don't belong to a subscriber are skipped.
+func (p *BounceProcessor) RecordAll(bounces []*models.Bounce, source string) ([]*models.Bounce, error) {
+	recorded := make([]*models.Bounce, 0, len(bounces))
+	for _, b := range bounces {
+		b.Source = source
+		if err := p.Record(b); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return recorded, err
+		}
+		recorded = append(recorded, b)
+	}
+	return recorded, nil
+}
+
+// Run checks the bounce mailbox until ctx is cancelled.
+// It returns immediately if no mailbox is configured.
+func (p *BounceProcessor) Run(ctx context.Context) {
+	if p.config.Mailbox == "" {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(p.config.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if err := p.checkMailbox(); err != nil {
+			log.Printf("Warning: failed to process bounce mailbox: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkMailbox processes new messages in the Maildir or mbox at the configured path
+func (p *BounceProcessor) checkMailbox() error {
+	info, err := os.Stat(p.config.Mailbox)
+	if err != nil {
+		return fmt.Errorf("failed to open bounce mailbox: %w", err)
+	}
+
+	// Messages that aren't bounces or can't be parsed are consumed all the same,
+	// only database errors leave a message to be retried
+	handle := func(r io.Reader) error {
+		bounces, err := bounce.Parse(r)
+		if err != nil {
+			if !errors.Is(err, bounce.ErrNotDSN) {
+				log.Printf("Warning: skipping unreadable bounce message: %v", err)
+			}
+			return nil
+		}
+		_, err = p.RecordAll(bounces, models.BounceSourceMailbox)
+		return err
+	}
+
+	if info.IsDir() {
+		return bounce.ReadMaildir(p.config.Mailbox, handle)
+	}
+
+	var offset int64
+	if value, err := p.db.GetSetting(mboxOffsetSetting); err == nil {
+		offset, _ = strconv.ParseInt(value, 10, 64)
+	}
+	newOffset, readErr := bounce.ReadMbox(p.config.Mailbox, offset, handle)
+	if newOffset != offset {
+		if err := p.db.SetSetting(mboxOffsetSetting, strconv.FormatInt(newOffset, 10)); err != nil {
+			return err
+		}
+	}
+	return readErr
+}
@@ -2,18 +2,47 @@ package worker
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/bounce"
 	"github.com/zhisme/tinylist/internal/config"
 	"github.com/zhisme/tinylist/internal/db"
 	"github.com/zhisme/tinylist/internal/mailer"
 	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/render"
 )
 
+// ErrAlreadySending is returned when a campaign is already being sent by this worker
+var ErrAlreadySending = errors.New("campaign is already being sent")
+
+// ErrNotScheduled is returned when a campaign due for sending was unscheduled,
+// rescheduled or started before the scheduler could start it
+var ErrNotScheduled = errors.New("campaign is no longer scheduled")
+
+// ErrSentElsewhere is returned when a campaign is being sent by another
+// process, or was taken over by one because this worker's heartbeat went stale
+var ErrSentElsewhere = errors.New("campaign is being sent by another process")
+
+// cancelCheckInterval is how often a sending campaign's status is checked for
+// a cancellation requested by another process
+const cancelCheckInterval = 2 * time.Second
+
+// heartbeatInterval is how often the process sending a campaign records that
+// it is still at it
+const heartbeatInterval = 10 * time.Second
+
+// heartbeatTimeout is how long a sending campaign's heartbeat can go without
+// an update before another process takes the campaign over
+const heartbeatTimeout = time.Minute
+
 // campaignContext holds the context and cancel func for a sending campaign
 type campaignContext struct {
 	cancel context.CancelFunc
@@ -21,9 +50,11 @@ type campaignContext struct {
 
 // CampaignWorker handles sending campaigns
 type CampaignWorker struct {
+	id        string // Identifies this worker as the sender of its campaigns
 	db        *db.DB
 	mailer    *mailer.Mailer
 	config    config.SendingConfig
+	limiter   *RateLimiter // Shared by all connections and campaigns
 	publicURL string
 	mu        sync.Mutex
 	sending   map[int]*campaignContext // Track campaigns currently being sent
@@ -32,15 +63,20 @@ type CampaignWorker struct {
 // NewCampaignWorker creates a new campaign worker
 func NewCampaignWorker(database *db.DB, mail *mailer.Mailer, cfg config.SendingConfig, publicURL string) *CampaignWorker {
 	return &CampaignWorker{
+		id:        uuid.New().String(),
 		db:        database,
 		mailer:    mail,
 		config:    cfg,
+		limiter:   NewRateLimiter(cfg.RateLimit),
 		publicURL: publicURL,
 		sending:   make(map[int]*campaignContext),
 	}
 }
 
-// ReplaceTemplateVars replaces {{name}} and {{email}} in text
+// ReplaceTemplateVars replaces {{name}} and {{email}} in text.
+//
+// Deprecated: campaigns are rendered with the render package, which also
+// understands these placeholders.
 func ReplaceTemplateVars(text, name, email string) string {
 	result := strings.ReplaceAll(text, "{{name}}", name)
 	result = strings.ReplaceAll(result, "{{email}}", email)
@@ -59,23 +95,45 @@ func (w *CampaignWorker) logJournal(campaignID int, eventType, message string) {
 	}
 }
 
-// SendCampaign starts sending a campaign to all verified subscribers
-func (w *CampaignWorker) SendCampaign(campaignID int) error {
-	// Check if already sending
+// track registers a campaign as currently sending and returns its context
+// along with a release func that must be called once sending is done
+func (w *CampaignWorker) track(campaignID int) (context.Context, func(), error) {
 	w.mu.Lock()
+	defer w.mu.Unlock()
 	if w.sending[campaignID] != nil {
-		w.mu.Unlock()
-		return fmt.Errorf("campaign %d is already being sent", campaignID)
+		return nil, nil, fmt.Errorf("campaign %d: %w", campaignID, ErrAlreadySending)
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	w.sending[campaignID] = &campaignContext{cancel: cancel}
-	w.mu.Unlock()
 
-	defer func() {
+	release := func() {
 		w.mu.Lock()
 		delete(w.sending, campaignID)
 		w.mu.Unlock()
-	}()
+		cancel()
+	}
+	return ctx, release, nil
+}
+
+// SendCampaign starts sending a draft or scheduled campaign to all verified subscribers
+func (w *CampaignWorker) SendCampaign(campaignID int) error {
+	return w.send(campaignID, false)
+}
+
+// SendScheduledCampaign starts sending a scheduled campaign that is due. It
+// returns ErrNotScheduled if the campaign has been unscheduled, rescheduled or
+// started since it was found due.
+func (w *CampaignWorker) SendScheduledCampaign(campaignID int) error {
+	return w.send(campaignID, true)
+}
+
+// send sends a campaign, which must be scheduled and due if scheduled is set
+func (w *CampaignWorker) send(campaignID int, scheduled bool) error {
+	ctx, release, err := w.track(campaignID)
+	if err != nil {
+		return err
+	}
+	defer release()
 
 	// Get campaign
 	campaign, err := w.db.GetCampaignByID(campaignID)
@@ -85,13 +143,16 @@ func (w *CampaignWorker) SendCampaign(campaignID int) error {
 	}
 
 	// Check campaign status
-	if campaign.Status != models.CampaignStatusDraft {
-		w.logJournal(campaignID, models.JournalEventError, "Campaign is not in draft status")
-		return fmt.Errorf("campaign is not in draft status")
+	if scheduled && campaign.Status != models.CampaignStatusScheduled {
+		return fmt.Errorf("campaign %d: %w", campaignID, ErrNotScheduled)
+	}
+	if campaign.Status != models.CampaignStatusDraft && campaign.Status != models.CampaignStatusScheduled {
+		w.logJournal(campaignID, models.JournalEventError, "Campaign is not in draft or scheduled status")
+		return fmt.Errorf("campaign is not in draft or scheduled status")
 	}
 
 	// Get all verified subscribers
-	subscribers, err := w.db.GetVerifiedSubscribers()
+	subscribers, err := w.db.GetCampaignRecipients(campaignID)
 	if err != nil {
 		w.logJournal(campaignID, models.JournalEventError, fmt.Sprintf("Failed to get subscribers: %v", err))
 		return fmt.Errorf("failed to get subscribers: %w", err)
@@ -102,85 +163,212 @@ func (w *CampaignWorker) SendCampaign(campaignID int) error {
 		return fmt.Errorf("no verified subscribers to send to")
 	}
 
-	// Log start
-	w.logJournal(campaignID, models.JournalEventInfo, fmt.Sprintf("Started sending to %d subscribers", len(subscribers)))
-
-	// Update campaign status to sending
-	if err := w.db.UpdateCampaignStatus(campaignID, models.CampaignStatusSending); err != nil {
+	// Update campaign status to sending, unless it was unscheduled or another
+	// process got there first
+	start := w.db.StartCampaign
+	if scheduled {
+		start = w.db.StartScheduledCampaign
+	}
+	if err := start(campaignID, w.id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) && scheduled {
+			return fmt.Errorf("campaign %d: %w", campaignID, ErrNotScheduled)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("campaign is not in draft or scheduled status")
+		}
 		w.logJournal(campaignID, models.JournalEventError, fmt.Sprintf("Failed to update status: %v", err))
 		return fmt.Errorf("failed to update campaign status: %w", err)
 	}
 
+	// Log start
+	w.logJournal(campaignID, models.JournalEventInfo, fmt.Sprintf("Started sending to %d subscribers", len(subscribers)))
+
 	// Set total count
 	if err := w.db.UpdateCampaignCounts(campaignID, len(subscribers), 0, 0); err != nil {
 		log.Printf("Warning: failed to update campaign counts: %v", err)
 	}
 
-	// Send emails with rate limiting
-	sentCount := 0
-	failedCount := 0
-	cancelled := false
-	ticker := time.NewTicker(time.Second / time.Duration(w.config.RateLimit))
-	defer ticker.Stop()
+	return w.deliver(ctx, campaign, subscribers, len(subscribers), 0, 0)
+}
 
-	for _, sub := range subscribers {
-		// Check for cancellation before each send
-		select {
-		case <-ctx.Done():
-			cancelled = true
-			w.logJournal(campaignID, models.JournalEventWarning, fmt.Sprintf("Cancelled: %d sent, %d failed, %d remaining", sentCount, failedCount, len(subscribers)-sentCount-failedCount))
-			break
-		case <-ticker.C:
-			// Continue with rate limiting
-		}
+// ResumeCampaign continues sending a campaign that was left in sending status,
+// e.g. because the process was restarted mid-send. It returns ErrSentElsewhere
+// unless the heartbeat of the campaign's sender is stale, and takes the
+// campaign over otherwise. Subscribers that already have a campaign log entry
+// are skipped. Logs are saved a batch at a time, so the recipients of a batch
+// that wasn't saved yet get the campaign again.
+func (w *CampaignWorker) ResumeCampaign(campaignID int) error {
+	ctx, release, err := w.track(campaignID)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-		if cancelled {
-			break
+	campaign, err := w.db.GetCampaignByID(campaignID)
+	if err != nil {
+		w.logJournal(campaignID, models.JournalEventError, fmt.Sprintf("Failed to get campaign: %v", err))
+		return fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	if campaign.Status != models.CampaignStatusSending {
+		return fmt.Errorf("campaign is not in sending status")
+	}
+
+	// Only one process gets to take over a campaign whose sender stopped
+	if err := w.db.ClaimCampaign(campaignID, w.id, time.Now().Add(-heartbeatTimeout)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("campaign %d: %w", campaignID, ErrSentElsewhere)
 		}
+		w.logJournal(campaignID, models.JournalEventError, fmt.Sprintf("Failed to take over campaign: %v", err))
+		return fmt.Errorf("failed to claim campaign: %w", err)
+	}
+
+	// Counts are rebuilt from the logs rather than trusting the stored ones
+	sentCount, failedCount, err := w.db.GetCampaignLogCounts(campaignID)
+	if err != nil {
+		w.logJournal(campaignID, models.JournalEventError, fmt.Sprintf("Failed to count sent emails: %v", err))
+		return fmt.Errorf("failed to count sent emails: %w", err)
+	}
+
+	subscribers, err := w.db.GetCampaignRecipients(campaignID)
+	if err != nil {
+		w.logJournal(campaignID, models.JournalEventError, fmt.Sprintf("Failed to get subscribers: %v", err))
+		return fmt.Errorf("failed to get subscribers: %w", err)
+	}
+
+	total := sentCount + failedCount + len(subscribers)
+	w.logJournal(campaignID, models.JournalEventInfo, fmt.Sprintf("Resumed after restart: %d of %d recipients remaining", len(subscribers), total))
+
+	if err := w.db.UpdateCampaignCounts(campaignID, total, sentCount, failedCount); err != nil {
+		log.Printf("Warning: failed to update campaign counts: %v", err)
+	}
 
-		// Replace template variables
-		subject := ReplaceTemplateVars(campaign.Subject, sub.Name, sub.Email)
-		bodyText := ReplaceTemplateVars(campaign.BodyText, sub.Name, sub.Email)
-		var bodyHTML string
-		if campaign.BodyHTML != nil {
-			bodyHTML = ReplaceTemplateVars(*campaign.BodyHTML, sub.Name, sub.Email)
+	return w.deliver(ctx, campaign, subscribers, total, sentCount, failedCount)
+}
+
+// ResumeInterrupted resumes the sending campaigns whose sender has stopped,
+// e.g. because the process was restarted mid-send. A sender counts as stopped
+// once its heartbeat is older than heartbeatTimeout, so campaigns sent by a
+// process that is still running are left to it. Each campaign is resumed in
+// its own goroutine.
+func (w *CampaignWorker) ResumeInterrupted() {
+	campaigns, err := w.db.GetAbandonedCampaigns(time.Now().Add(-heartbeatTimeout))
+	if err != nil {
+		log.Printf("Warning: failed to look up interrupted campaigns: %v", err)
+		return
+	}
+
+	for _, campaign := range campaigns {
+		if w.IsSending(campaign.ID) {
+			continue
 		}
 
-		// Build unsubscribe URL
-		unsubscribeURL := fmt.Sprintf("%s/api/unsubscribe/%s", w.publicURL, sub.UnsubscribeToken)
+		log.Printf("Resuming interrupted campaign %s", campaign.UUID)
+		go func(id int, uuid string) {
+			err := w.ResumeCampaign(id)
+			if err == nil || errors.Is(err, ErrAlreadySending) || errors.Is(err, ErrSentElsewhere) {
+				return
+			}
+			log.Printf("Campaign %s resume failed: %v", uuid, err)
+		}(campaign.ID, campaign.UUID)
+	}
+}
+
+// deliver sends the campaign to the given subscribers and sets the final status.
+// total, sentCount and failedCount include recipients handled by an earlier run.
+func (w *CampaignWorker) deliver(ctx context.Context, campaign *models.Campaign, subscribers []*models.Subscriber, total, sentCount, failedCount int) error {
+	campaignID := campaign.ID
+	go w.watchCancelled(ctx, campaignID)
+
+	var takenOver atomic.Bool
+	stop := func() {
+		takenOver.Store(true)
+		w.CancelCampaign(campaignID)
+	}
+	go w.heartbeat(ctx, campaignID, stop)
+
+	renderer, err := w.newRenderer(campaign, func(err error) {
+		w.logJournal(campaignID, models.JournalEventWarning, fmt.Sprintf("Click tracking disabled, failed to register links: %v", err))
+	})
+	if err != nil {
+		w.logJournal(campaignID, models.JournalEventError, fmt.Sprintf("Campaign could not be rendered: %v", err))
+		if err := w.db.UpdateCampaignStatus(campaignID, models.CampaignStatusFailed); err != nil {
+			log.Printf("Warning: failed to update campaign status: %v", err)
+		}
+		return err
+	}
 
-		// Attempt to send with retries
-		var sendErr error
-		for attempt := 0; attempt <= w.config.MaxRetries; attempt++ {
-			sendErr = w.mailer.SendCampaign(ctx, sub.Email, sub.Name, subject, bodyText, bodyHTML, unsubscribeURL)
-			if sendErr == nil {
-				break
+	// Lets bounces returned with the original message be traced back to the campaign
+	headers := map[string]string{bounce.CampaignHeader: campaign.UUID}
+
+	// Hand subscribers to one goroutine per connection, results are logged here
+	jobs := make(chan *models.Subscriber)
+	results := make(chan deliveryResult)
+	go func() {
+		defer close(jobs)
+		for _, sub := range subscribers {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- sub:
 			}
-			// Check if context was cancelled - don't retry in that case
-			if ctx.Err() != nil {
-				break
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(w.config.Connections, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sender := w.mailer.NewSender()
+			defer sender.Close()
+			for sub := range jobs {
+				results <- w.sendTo(ctx, sender, renderer, campaign, sub, headers)
 			}
-			if attempt < w.config.MaxRetries {
-				time.Sleep(w.config.RetryDelay)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Logs are written a batch at a time, together with the counts. A batch
+	// that fails to save is kept and saved with the next one. Once another
+	// process has taken over, the logs are still saved but the counts are left
+	// to it.
+	var batch []*models.CampaignLog
+	flush := func() {
+		err := w.db.CreateCampaignLogs(campaignID, w.id, batch, total, sentCount, failedCount)
+		if errors.Is(err, db.ErrTakenOver) {
+			if !takenOver.Load() {
+				log.Printf("Campaign %d was taken over by another process, stopping", campaignID)
+				stop()
 			}
+			err = nil
+		}
+		if err != nil {
+			log.Printf("Warning: failed to save %d campaign logs: %v", len(batch), err)
+			return
 		}
+		batch = batch[:0]
+	}
 
-		// Check if cancelled during send
-		if ctx.Err() != nil {
-			cancelled = true
-			w.logJournal(campaignID, models.JournalEventWarning, fmt.Sprintf("Cancelled: %d sent, %d failed, %d remaining", sentCount, failedCount, len(subscribers)-sentCount-failedCount))
-			break
+	for result := range results {
+		// Sends cut short by cancellation are left unlogged, like unsent ones
+		if result.cancelled {
+			continue
 		}
 
 		// Log the result
 		logEntry := &models.CampaignLog{
 			CampaignID:   campaignID,
-			SubscriberID: sub.ID,
+			SubscriberID: result.sub.ID,
+			SentAt:       time.Now(),
 		}
 
-		if sendErr != nil {
+		if result.err != nil {
 			logEntry.Status = "failed"
-			errStr := sendErr.Error()
+			errStr := result.err.Error()
 			logEntry.Error = &errStr
 			failedCount++
 		} else {
@@ -188,21 +376,38 @@ func (w *CampaignWorker) SendCampaign(campaignID int) error {
 			sentCount++
 		}
 
-		if err := w.db.CreateCampaignLog(logEntry); err != nil {
-			log.Printf("Warning: failed to create campaign log: %v", err)
+		// A batch kept after a failed save is retried with the next result
+		batch = append(batch, logEntry)
+		if len(batch) >= w.config.BatchSize {
+			flush()
 		}
+	}
 
-		// Update counts periodically (every batch)
-		if (sentCount+failedCount)%w.config.BatchSize == 0 {
-			if err := w.db.UpdateCampaignCounts(campaignID, len(subscribers), sentCount, failedCount); err != nil {
-				log.Printf("Warning: failed to update campaign counts: %v", err)
-			}
-		}
+	// The logs are still saved so their recipients aren't sent to again, the
+	// status is left to the process that took over
+	if takenOver.Load() {
+		flush()
+		w.logJournal(campaignID, models.JournalEventWarning, fmt.Sprintf("Taken over by another process after %d sent, %d failed", sentCount, failedCount))
+		return fmt.Errorf("campaign %d: %w", campaignID, ErrSentElsewhere)
 	}
 
-	// Final count update
-	if err := w.db.UpdateCampaignCounts(campaignID, len(subscribers), sentCount, failedCount); err != nil {
-		log.Printf("Warning: failed to update final campaign counts: %v", err)
+	cancelled := ctx.Err() != nil
+	if cancelled {
+		w.logJournal(campaignID, models.JournalEventWarning, fmt.Sprintf("Cancelled: %d sent, %d failed, %d remaining", sentCount, failedCount, total-sentCount-failedCount))
+	}
+
+	// The last batch is saved whether the send completed or was cancelled,
+	// along with the final counts
+	flush()
+	if len(batch) > 0 {
+		w.logJournal(campaignID, models.JournalEventError, fmt.Sprintf("Failed to save the logs of %d recipients", len(batch)))
+
+		// Without the logs the stored counts are behind. The campaign is left
+		// sending, so it is resumed with counts rebuilt from the logs that were
+		// saved. A cancelled campaign stays cancelled, with the counts it has.
+		if !cancelled {
+			return fmt.Errorf("failed to save the logs of %d recipients", len(batch))
+		}
 	}
 
 	// Update campaign status
@@ -236,6 +441,191 @@ func (w *CampaignWorker) SendCampaign(campaignID int) error {
 	return nil
 }
 
+// watchCancelled cancels a campaign sent by this worker once its status is set
+// to cancelled in the database, which is how other processes such as the CLI
+// cancel it. It returns when ctx is done.
+func (w *CampaignWorker) watchCancelled(ctx context.Context, campaignID int) {
+	ticker := time.NewTicker(cancelCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			status, err := w.db.GetCampaignStatus(campaignID)
+			if err != nil {
+				log.Printf("Warning: failed to check campaign %d for cancellation: %v", campaignID, err)
+				continue
+			}
+			if status == models.CampaignStatusCancelled {
+				w.CancelCampaign(campaignID)
+				return
+			}
+		}
+	}
+}
+
+// heartbeat keeps the heartbeat of a campaign sent by this worker fresh, so
+// no other process takes it over. If one did anyway, e.g. because this process
+// stalled for longer than heartbeatTimeout, takenOver is called. It returns
+// when ctx is done.
+func (w *CampaignWorker) heartbeat(ctx context.Context, campaignID int, takenOver func()) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := w.db.HeartbeatCampaign(campaignID, w.id)
+			if errors.Is(err, sql.ErrNoRows) {
+				log.Printf("Campaign %d was taken over by another process, stopping", campaignID)
+				takenOver()
+				return
+			}
+			if err != nil {
+				log.Printf("Warning: failed to update heartbeat of campaign %d: %v", campaignID, err)
+			}
+		}
+	}
+}
+
+// deliveryResult is the outcome of sending a campaign to one subscriber
+type deliveryResult struct {
+	sub       *models.Subscriber
+	err       error
+	cancelled bool
+}
+
+// sendTo renders the campaign for one subscriber and sends it over sender,
+// waiting for the shared rate limit first and retrying failed sends
+func (w *CampaignWorker) sendTo(ctx context.Context, sender *mailer.Sender, renderer *campaignRenderer, campaign *models.Campaign, sub *models.Subscriber, headers map[string]string) deliveryResult {
+	if err := w.limiter.Wait(ctx); err != nil {
+		return deliveryResult{sub: sub, cancelled: true}
+	}
+
+	// Render the templates for this subscriber
+	subject, bodyText, bodyHTML, renderErr := renderer.render(sub, false)
+
+	unsubscribeURL := w.unsubscribeURL(campaign, sub.UnsubscribeToken)
+
+	// Attempt to send with retries, a subscriber whose data breaks the template fails right away
+	sendErr := renderErr
+	for attempt := 0; renderErr == nil && attempt <= w.config.MaxRetries; attempt++ {
+		sendErr = sender.SendCampaign(ctx, sub.Email, sub.Name, subject, bodyText, bodyHTML, unsubscribeURL, headers)
+		if sendErr == nil {
+			break
+		}
+		// Check if context was cancelled - don't retry in that case
+		if ctx.Err() != nil {
+			break
+		}
+		if attempt < w.config.MaxRetries {
+			time.Sleep(w.config.RetryDelay)
+		}
+	}
+
+	return deliveryResult{sub: sub, err: sendErr, cancelled: ctx.Err() != nil}
+}
+
+// campaignRenderer renders a campaign for one recipient at a time, exactly as
+// it is sent: the content wrapped in its layout, links pointing at click
+// tracking and the open pixel added. Sending, previews and test emails all
+// render through it.
+type campaignRenderer struct {
+	campaign  *models.Campaign
+	tmpl      *render.Template
+	publicURL string
+}
+
+// newRenderer loads the layout of a campaign, registers its links for click
+// tracking and compiles its templates. If the links can't be registered they
+// are left untracked and warn, if set, is called with the error.
+func (w *CampaignWorker) newRenderer(campaign *models.Campaign, warn func(error)) (*campaignRenderer, error) {
+	var bodyHTML string
+	if campaign.BodyHTML != nil {
+		bodyHTML = *campaign.BodyHTML
+	}
+
+	// The layout is loaded now, so edits made after the campaign was written show up
+	layout, err := w.layoutFor(campaign)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load layout: %w", err)
+	}
+
+	// The subscriber part of the tracking URL is filled in by the template
+	if campaign.TrackClicks && bodyHTML != "" {
+		var layoutHTML string
+		if layout != nil {
+			layoutHTML = layout.HTML
+		}
+		links, err := w.db.RegisterCampaignLinks(campaign.ID, TrackableLinks(layoutHTML+bodyHTML))
+		if err != nil {
+			if warn != nil {
+				warn(err)
+			}
+		} else {
+			bodyHTML = RewriteLinks(bodyHTML, links, w.publicURL, "{{.Subscriber.UUID}}")
+			if layout != nil {
+				layout.HTML = RewriteLinks(layout.HTML, links, w.publicURL, "{{.Subscriber.UUID}}")
+			}
+		}
+	}
+
+	// Templates were validated when the campaign was saved, so this only fails
+	// for campaigns created before validation existed or a broken combination
+	// of layout and content
+	tmpl, err := render.CompileWithLayout(campaign.Subject, campaign.BodyText, bodyHTML, layout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile templates: %w", err)
+	}
+	return &campaignRenderer{campaign: campaign, tmpl: tmpl, publicURL: w.publicURL}, nil
+}
+
+// render renders the campaign for sub. With inert set, the tracking URLs name
+// a stand-in subscriber instead, so opening or clicking them records nothing;
+// tracked links still redirect to their targets.
+func (r *campaignRenderer) render(sub *models.Subscriber, inert bool) (subject, bodyText, bodyHTML string, err error) {
+	data := render.NewData(sub, r.campaign)
+	trackAs := sub.UUID
+	if inert {
+		trackAs = previewToken
+		data.Subscriber.UUID = previewToken
+	}
+
+	subject, bodyText, bodyHTML, err = r.tmpl.Execute(data)
+	if bodyHTML != "" && r.campaign.TrackOpens {
+		bodyHTML = AddOpenPixel(bodyHTML, r.publicURL, r.campaign.UUID, trackAs)
+	}
+	return subject, bodyText, bodyHTML, err
+}
+
+// layoutFor loads the layout of a campaign, or returns nil if it has none
+func (w *CampaignWorker) layoutFor(campaign *models.Campaign) (*render.Layout, error) {
+	if campaign.TemplateID == 0 {
+		return nil, nil
+	}
+	t, err := w.db.GetTemplateByID(campaign.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	layout := &render.Layout{Text: t.BodyText}
+	if t.BodyHTML != nil {
+		layout.HTML = *t.BodyHTML
+	}
+	return layout, nil
+}
+
+// unsubscribeURL builds the unsubscribe URL for a subscriber's token, scoped to the
+// list when the campaign targets exactly one
+func (w *CampaignWorker) unsubscribeURL(campaign *models.Campaign, token string) string {
+	url := fmt.Sprintf("%s/api/unsubscribe/%s", w.publicURL, token)
+	if len(campaign.ListIDs) == 1 {
+		url += "?list=" + campaign.ListIDs[0]
+	}
+	return url
+}
+
 // IsSending returns true if a campaign is currently being sent
 func (w *CampaignWorker) IsSending(campaignID int) bool {
 	w.mu.Lock()
@@ -0,0 +1,98 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zhisme/tinylist/internal/bounce"
+	"github.com/zhisme/tinylist/internal/mailer"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// previewToken stands in for the unsubscribe token in previews and test emails,
+// so unsubscribe links in them don't affect real subscribers
+const previewToken = "preview"
+
+// Preview is a campaign rendered for one recipient, as it would be sent
+type Preview struct {
+	Subject  string  `json:"subject"`
+	BodyText string  `json:"body_text"`
+	BodyHTML *string `json:"body_html,omitempty"`
+}
+
+// TestResult is the outcome of sending a test email to one address
+type TestResult struct {
+	Email string `json:"email"`
+	Sent  bool   `json:"sent"`
+	Error string `json:"error,omitempty"`
+}
+
+// SampleSubscriber returns a stand-in subscriber for previews and test emails.
+// The name is left empty, so fallbacks in templates show up.
+func SampleSubscriber(email string) *models.Subscriber {
+	return &models.Subscriber{
+		UUID:             previewToken,
+		Email:            email,
+		Status:           models.StatusVerified,
+		UnsubscribeToken: previewToken,
+	}
+}
+
+// Preview renders a campaign for sub as it is sent, with the unsubscribe footer,
+// tracked links and open pixel. The tracking URLs are inert, they name a
+// stand-in subscriber, so viewing the preview or following its links records
+// nothing. Links of campaigns with click tracking are registered as a sending
+// would.
+func (w *CampaignWorker) Preview(campaign *models.Campaign, sub *models.Subscriber) (*Preview, error) {
+	subject, bodyText, bodyHTML, err := w.renderFor(campaign, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyText, bodyHTML = mailer.AddUnsubscribeFooter(bodyText, bodyHTML, w.unsubscribeURL(campaign, sub.UnsubscribeToken))
+	preview := &Preview{Subject: subject, BodyText: bodyText}
+	if bodyHTML != "" {
+		preview.BodyHTML = &bodyHTML
+	}
+	return preview, nil
+}
+
+// SendTest sends a campaign rendered for sub to each of the given addresses,
+// as Preview renders it. Nothing is tracked or logged and the campaign itself
+// is left untouched; the subject is marked as a test and the unsubscribe link
+// is a dummy.
+func (w *CampaignWorker) SendTest(ctx context.Context, campaign *models.Campaign, sub *models.Subscriber, emails []string) ([]TestResult, error) {
+	subject, bodyText, bodyHTML, err := w.renderFor(campaign, sub)
+	if err != nil {
+		return nil, err
+	}
+	subject = "[Test] " + subject
+
+	unsubscribeURL := w.unsubscribeURL(campaign, previewToken)
+	headers := map[string]string{bounce.CampaignHeader: campaign.UUID}
+
+	results := make([]TestResult, 0, len(emails))
+	for _, email := range emails {
+		result := TestResult{Email: email, Sent: true}
+		if err := w.mailer.SendCampaign(ctx, email, "", subject, bodyText, bodyHTML, unsubscribeURL, headers); err != nil {
+			result.Sent = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// renderFor renders the campaign for sub through the same renderer as
+// sending, with inert tracking
+func (w *CampaignWorker) renderFor(campaign *models.Campaign, sub *models.Subscriber) (subject, bodyText, bodyHTML string, err error) {
+	renderer, err := w.newRenderer(campaign, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	subject, bodyText, bodyHTML, err = renderer.render(sub, true)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to render campaign: %w", err)
+	}
+	return subject, bodyText, bodyHTML, nil
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces out events evenly at a fixed rate. It is shared by all
+// connections and campaigns of a worker, so the configured rate is a global cap.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter returns a limiter allowing perSecond events per second.
+// A rate of zero or less means no limit.
+func NewRateLimiter(perSecond int) *RateLimiter {
+	l := &RateLimiter{}
+	if perSecond > 0 {
+		l.interval = time.Second / time.Duration(perSecond)
+	}
+	return l
+}
+
+// Wait blocks until the caller's turn comes up or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return ctx.Err()
+	}
+
+	// Reserve the next free slot, an idle limiter doesn't save up slots for bursts
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
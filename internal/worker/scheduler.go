@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// Scheduler periodically hands scheduled campaigns that are due to the campaign worker,
+// and has it resume campaigns whose sender stopped mid-send. The schedule itself
+// lives in the database, so nothing is lost across restarts.
+type Scheduler struct {
+	db       *db.DB
+	worker   *CampaignWorker
+	interval time.Duration
+}
+
+// NewScheduler creates a new scheduler checking for due campaigns every interval
+func NewScheduler(database *db.DB, w *CampaignWorker, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		db:       database,
+		worker:   w,
+		interval: interval,
+	}
+}
+
+// Run checks for due campaigns until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	// Catch up on campaigns that became due or were interrupted while the server was down
+	s.dispatchDue()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.dispatchDue()
+		}
+	}
+}
+
+// dispatchDue starts sending every scheduled campaign whose time has come and
+// resumes the interrupted ones
+func (s *Scheduler) dispatchDue() {
+	// Leave campaigns waiting until SMTP is configured rather than failing every send
+	if !s.worker.mailer.IsConfigured() {
+		return
+	}
+
+	s.worker.ResumeInterrupted()
+
+	campaigns, err := s.db.GetDueCampaigns()
+	if err != nil {
+		log.Printf("Warning: failed to get due campaigns: %v", err)
+		return
+	}
+
+	for _, campaign := range campaigns {
+		if s.worker.IsSending(campaign.ID) {
+			continue
+		}
+
+		log.Printf("Scheduled campaign %s is due, starting send", campaign.UUID)
+		go func(id int, uuid string) {
+			err := s.worker.SendScheduledCampaign(id)
+			if err == nil || errors.Is(err, ErrAlreadySending) || errors.Is(err, ErrNotScheduled) {
+				return
+			}
+			log.Printf("Campaign %s send failed: %v", uuid, err)
+
+			// A campaign that could not start (e.g. no verified subscribers) would be
+			// picked up again on every tick, so move it back to draft instead
+			if err := s.db.UnscheduleCampaign(id); err == nil {
+				s.worker.logJournal(id, models.JournalEventWarning, "Scheduled send could not start, campaign moved back to draft")
+			}
+		}(campaign.ID, campaign.UUID)
+	}
+}
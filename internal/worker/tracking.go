@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// hrefRegex matches absolute http(s) links in href attributes.
+// Group 1 is everything up to the URL, group 2 the URL, group 3 the closing quote.
+var hrefRegex = regexp.MustCompile(`(?i)(<a\s[^>]*?href\s*=\s*)(?:"(https?://[^"]+)"|'(https?://[^']+)')`)
+
+// hrefURL returns the unescaped URL of a hrefRegex match
+func hrefURL(match []string) string {
+	raw := match[2]
+	if raw == "" {
+		raw = match[3]
+	}
+	return html.UnescapeString(raw)
+}
+
+// TrackableLinks returns the distinct links in an HTML body that can be rewritten
+// for click tracking. Links containing template variables are left alone, since
+// they differ per subscriber.
+func TrackableLinks(body string) []string {
+	seen := make(map[string]bool)
+	var links []string
+	for _, match := range hrefRegex.FindAllStringSubmatch(body, -1) {
+		url := hrefURL(match)
+		if strings.Contains(url, "{{") || seen[url] {
+			continue
+		}
+		seen[url] = true
+		links = append(links, url)
+	}
+	return links
+}
+
+// RewriteLinks points every link found in links (URL to link UUID) at the click
+// tracking endpoint for the given subscriber. Other links are kept as they are.
+func RewriteLinks(body string, links map[string]string, publicURL, subscriberUUID string) string {
+	return hrefRegex.ReplaceAllStringFunc(body, func(attr string) string {
+		match := hrefRegex.FindStringSubmatch(attr)
+		linkUUID, ok := links[hrefURL(match)]
+		if !ok {
+			return attr
+		}
+		return fmt.Sprintf(`%s"%s/api/track/click/%s/%s"`, match[1], publicURL, linkUUID, subscriberUUID)
+	})
+}
+
+// AddOpenPixel inserts the open tracking image at the end of an HTML body
+func AddOpenPixel(body, publicURL, campaignUUID, subscriberUUID string) string {
+	pixel := fmt.Sprintf(`<img src="%s/api/track/open/%s/%s" width="1" height="1" alt="" style="display:none">`,
+		publicURL, campaignUUID, subscriberUUID)
+	// Try to insert before </body>, otherwise just append
+	if idx := strings.LastIndex(strings.ToLower(body), "</body>"); idx != -1 {
+		return body[:idx] + pixel + body[idx:]
+	}
+	return body + pixel
+}
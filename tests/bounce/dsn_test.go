@@ -0,0 +1,105 @@
+package bounce_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/bounce"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// sampleDSN is a bounce report for two recipients, one failed permanently and
+// one delayed, with the headers of the original message
+const sampleDSN = "From: MAILER-DAEMON@mx.example.com\r\n" +
+	"To: newsletter@example.com\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"BOUNDARY\"\r\n" +
+	"\r\n" +
+	"--BOUNDARY\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Your message could not be delivered.\r\n" +
+	"--BOUNDARY\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mx.example.com\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; Gone@Example.org\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"Diagnostic-Code: smtp; 550 5.1.1 user unknown\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; <slow@example.org>\r\n" +
+	"Action: delayed\r\n" +
+	"Status: 4.4.1\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; fine@example.org\r\n" +
+	"Action: delivered\r\n" +
+	"Status: 2.0.0\r\n" +
+	"--BOUNDARY\r\n" +
+	"Content-Type: text/rfc822-headers\r\n" +
+	"\r\n" +
+	"From: newsletter@example.com\r\n" +
+	"Subject: Hello\r\n" +
+	"X-TinyList-Campaign: 0f8c2c1e-campaign\r\n" +
+	"\r\n" +
+	"--BOUNDARY--\r\n"
+
+func TestParse(t *testing.T) {
+	bounces, err := bounce.Parse(strings.NewReader(sampleDSN))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(bounces) != 2 {
+		t.Fatalf("Parse() returned %d bounces, want 2", len(bounces))
+	}
+
+	expected := []struct {
+		email      string
+		bounceType string
+		reason     string
+	}{
+		{"gone@example.org", models.BounceHard, "5.1.1 smtp; 550 5.1.1 user unknown"},
+		{"slow@example.org", models.BounceSoft, "4.4.1"},
+	}
+	for i, want := range expected {
+		got := bounces[i]
+		if got.Email != want.email || got.Type != want.bounceType || got.Reason != want.reason {
+			t.Errorf("bounce %d = {%s %s %q}, want {%s %s %q}", i, got.Email, got.Type, got.Reason, want.email, want.bounceType, want.reason)
+		}
+		if got.CampaignUUID == nil || *got.CampaignUUID != "0f8c2c1e-campaign" {
+			t.Errorf("bounce %d campaign = %v, want 0f8c2c1e-campaign", i, got.CampaignUUID)
+		}
+	}
+}
+
+func TestParseRejectsOtherMessages(t *testing.T) {
+	msg := "From: someone@example.com\r\nSubject: Out of office\r\nContent-Type: text/plain\r\n\r\nI'm away.\r\n"
+	if _, err := bounce.Parse(strings.NewReader(msg)); !errors.Is(err, bounce.ErrNotDSN) {
+		t.Errorf("Parse() error = %v, want ErrNotDSN", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		status   string
+		expected string
+	}{
+		{"5.1.1", models.BounceHard},
+		{"5.7.1", models.BounceHard},
+		{"5.2.2", models.BounceSoft},
+		{"4.2.2", models.BounceSoft},
+		{"4.4.7", models.BounceSoft},
+		{"", models.BounceHard},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := bounce.Classify(tt.status); got != tt.expected {
+				t.Errorf("Classify(%q) = %s, want %s", tt.status, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package bounce_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/bounce"
+)
+
+// collect returns a callback that appends the first line of every message to lines
+func collect(lines *[]string) func(io.Reader) error {
+	return func(r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		*lines = append(*lines, strings.SplitN(string(data), "\n", 2)[0])
+		return nil
+	}
+}
+
+func TestReadMbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bounces.mbox")
+	first := "From MAILER-DAEMON Mon Jan  1 00:00:00 2024\nSubject: one\n\n>From the start\n\n" +
+		"From MAILER-DAEMON Mon Jan  1 00:01:00 2024\nSubject: two\n\nbody\n\n"
+	if err := os.WriteFile(path, []byte(first), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	offset, err := bounce.ReadMbox(path, 0, collect(&lines))
+	if err != nil {
+		t.Fatalf("ReadMbox() error = %v", err)
+	}
+	if want := []string{"Subject: one", "Subject: two"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("messages = %v, want %v", lines, want)
+	}
+	if offset != int64(len(first)) {
+		t.Errorf("offset = %d, want %d", offset, len(first))
+	}
+
+	// Only messages appended since the last read are returned
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("From MAILER-DAEMON Mon Jan  1 00:02:00 2024\nSubject: three\n\nbody\n\n")
+	file.Close()
+
+	lines = nil
+	if _, err := bounce.ReadMbox(path, offset, collect(&lines)); err != nil {
+		t.Fatalf("second ReadMbox() error = %v", err)
+	}
+	if want := []string{"Subject: three"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("messages = %v, want %v", lines, want)
+	}
+}
+
+func TestReadMaildir(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"new", "cur", "tmp"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "new", "1700000001.msg"), []byte("Subject: one\n\nbody\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "new", "1700000002.msg"), []byte("Subject: two\n\nbody\n"), 0o644)
+
+	var lines []string
+	if err := bounce.ReadMaildir(dir, collect(&lines)); err != nil {
+		t.Fatalf("ReadMaildir() error = %v", err)
+	}
+	if want := []string{"Subject: one", "Subject: two"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("messages = %v, want %v", lines, want)
+	}
+
+	remaining, _ := os.ReadDir(filepath.Join(dir, "new"))
+	seen, _ := os.ReadDir(filepath.Join(dir, "cur"))
+	if len(remaining) != 0 || len(seen) != 2 {
+		t.Errorf("new/ has %d messages, cur/ has %d, want 0 and 2", len(remaining), len(seen))
+	}
+}
+
+func TestReadMaildirRetriesFailedMessage(t *testing.T) {
+	// Without cur/, which some delivery agents leave to the mail client
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "new"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "new", "1700000001.msg"), []byte("Subject: one\n\nbody\n"), 0o644)
+
+	failing := func(io.Reader) error { return errors.New("database is down") }
+	if err := bounce.ReadMaildir(dir, failing); err == nil {
+		t.Fatal("ReadMaildir() with failing callback succeeded, want error")
+	}
+	remaining, _ := os.ReadDir(filepath.Join(dir, "new"))
+	if len(remaining) != 1 {
+		t.Fatalf("new/ has %d messages after failure, want 1", len(remaining))
+	}
+
+	var lines []string
+	if err := bounce.ReadMaildir(dir, collect(&lines)); err != nil {
+		t.Fatalf("ReadMaildir() error = %v", err)
+	}
+	// Handled messages are never read again
+	if err := bounce.ReadMaildir(dir, collect(&lines)); err != nil {
+		t.Fatalf("second ReadMaildir() error = %v", err)
+	}
+	if want := []string{"Subject: one"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("messages = %v, want %v", lines, want)
+	}
+}
@@ -0,0 +1,72 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestListSubscribersByAttributes(t *testing.T) {
+	database := newTestDB(t)
+	for email, attributes := range map[string]map[string]interface{}{
+		"berlin@example.com":  {"city": "Berlin", "age": 30, "vip": true},
+		"berlin2@example.com": {"city": "Berlin", "age": "30"},
+		"paris@example.com":   {"city": "Paris", "age": 41},
+		"none@example.com":    nil,
+	} {
+		sub := createSubscriber(t, database, email, models.StatusVerified)
+		sub.Attributes = attributes
+		if err := database.UpdateSubscriber(sub); err != nil {
+			t.Fatalf("UpdateSubscriber() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		want       int
+	}{
+		{"no filter", nil, 4},
+		{"string", map[string]string{"city": `"Berlin"`}, 2},
+		{"number", map[string]string{"age": `30`}, 1},
+		{"number as string", map[string]string{"age": `"30"`}, 1},
+		{"boolean", map[string]string{"vip": `true`}, 1},
+		{"all must match", map[string]string{"city": `"Berlin"`, "age": `41`}, 0},
+		{"missing attribute", map[string]string{"company": `"ACME"`}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subs, total, err := database.ListSubscribers(db.SubscriberFilter{Attributes: tt.attributes}, db.SubscriberSort{}, 1, 20)
+			if err != nil {
+				t.Fatalf("ListSubscribers() error = %v", err)
+			}
+			if total != tt.want || len(subs) != tt.want {
+				t.Errorf("ListSubscribers() returned %d of %d, want %d", len(subs), total, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportMergesAttributes(t *testing.T) {
+	database := newTestDB(t)
+	sub := createSubscriber(t, database, "jane@example.com", models.StatusVerified)
+	sub.Attributes = map[string]interface{}{"city": "Berlin", "plan": "free"}
+	if err := database.UpdateSubscriber(sub); err != nil {
+		t.Fatalf("UpdateSubscriber() error = %v", err)
+	}
+
+	rows := []db.ImportRow{{Email: "jane@example.com", Attributes: map[string]interface{}{"plan": "pro", "city": nil}}}
+	if _, err := database.ImportSubscribers(rows, db.ImportOptions{UpdateExisting: true}); err != nil {
+		t.Fatalf("ImportSubscribers() error = %v", err)
+	}
+
+	got, err := database.GetSubscriberByEmail("jane@example.com")
+	if err != nil {
+		t.Fatalf("GetSubscriberByEmail() error = %v", err)
+	}
+	if len(got.Attributes) != 1 || got.Attributes["plan"] != "pro" {
+		t.Errorf("attributes = %v, want only plan pro", got.Attributes)
+	}
+}
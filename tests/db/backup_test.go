@@ -0,0 +1,193 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestBackup(t *testing.T) {
+	database := newTestDB(t)
+	createSubscriber(t, database, "jane@example.com", models.StatusVerified)
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	if err := database.Backup(path); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	// Existing files are never overwritten
+	if err := database.Backup(path); err == nil {
+		t.Error("Backup() to an existing file succeeded, want error")
+	}
+
+	backup, err := db.New(path)
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer backup.Close()
+	if err := backup.CheckTables(); err != nil {
+		t.Fatalf("CheckTables() on backup error = %v", err)
+	}
+	if _, err := backup.GetSubscriberByEmail("jane@example.com"); err != nil {
+		t.Errorf("GetSubscriberByEmail() on backup error = %v", err)
+	}
+}
+
+func TestBackupDuringWrite(t *testing.T) {
+	database := newTestDB(t)
+	createSubscriber(t, database, "jane@example.com", models.StatusVerified)
+
+	// A long write holds the only writer connection
+	tx, err := database.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM subscribers"); err != nil {
+		t.Fatalf("failed to delete subscribers: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	done := make(chan error, 1)
+	go func() { done <- database.Backup(path) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Backup() error = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Backup() waited for the writer")
+	}
+
+	// The backup holds the committed data, not the open transaction's
+	backup, err := db.New(path)
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer backup.Close()
+	if _, err := backup.GetSubscriberByEmail("jane@example.com"); err != nil {
+		t.Errorf("GetSubscriberByEmail() on backup error = %v", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	database := newTestDB(t)
+	createSubscriber(t, database, "jane@example.com", models.StatusVerified)
+
+	snapshot, err := database.BackupTemp()
+	if err != nil {
+		t.Fatalf("BackupTemp() error = %v", err)
+	}
+	defer os.Remove(snapshot)
+	if err := db.ValidateSnapshot(snapshot); err != nil {
+		t.Fatalf("ValidateSnapshot() error = %v", err)
+	}
+
+	createSubscriber(t, database, "john@example.com", models.StatusVerified)
+	if err := database.Restore(snapshot); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	migrations, err := database.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	for _, m := range migrations {
+		if m.AppliedAt == nil {
+			t.Errorf("migration %04d_%s is pending after Restore()", m.Version, m.Name)
+		}
+	}
+
+	if _, err := database.GetSubscriberByEmail("jane@example.com"); err != nil {
+		t.Errorf("GetSubscriberByEmail() of backed up subscriber error = %v", err)
+	}
+	if _, err := database.GetSubscriberByEmail("john@example.com"); err == nil {
+		t.Error("subscriber created after the backup survived Restore()")
+	}
+}
+
+func TestRestoreWhileSending(t *testing.T) {
+	database := newTestDB(t)
+	snapshot, err := database.BackupTemp()
+	if err != nil {
+		t.Fatalf("BackupTemp() error = %v", err)
+	}
+	defer os.Remove(snapshot)
+
+	campaign := createCampaign(t, database, models.CampaignStatusDraft)
+	if err := database.StartCampaign(campaign.ID, "sender"); err != nil {
+		t.Fatalf("StartCampaign() error = %v", err)
+	}
+	if err := database.Restore(snapshot); !errors.Is(err, db.ErrCampaignsSending) {
+		t.Fatalf("Restore() while sending error = %v, want ErrCampaignsSending", err)
+	}
+	if _, err := database.GetCampaignByID(campaign.ID); err != nil {
+		t.Errorf("GetCampaignByID() after refused Restore() error = %v", err)
+	}
+
+	// The lock taken by a refused restore doesn't outlive it
+	other := createCampaign(t, database, models.CampaignStatusDraft)
+	if err := database.StartCampaign(other.ID, "sender"); err != nil {
+		t.Errorf("StartCampaign() after refused Restore() error = %v", err)
+	}
+}
+
+func TestRestoreLockBlocksCampaigns(t *testing.T) {
+	database := newTestDB(t)
+	campaign := createCampaign(t, database, models.CampaignStatusDraft)
+
+	// Held by a restore in progress
+	if err := database.SetSetting("restore_started_at", time.Now().UTC().Format("2006-01-02 15:04:05")); err != nil {
+		t.Fatalf("SetSetting() error = %v", err)
+	}
+	if err := database.StartCampaign(campaign.ID, "sender"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("StartCampaign() during restore error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Left behind by a restore that died
+	if err := database.SetSetting("restore_started_at", time.Now().Add(-time.Hour).UTC().Format("2006-01-02 15:04:05")); err != nil {
+		t.Fatalf("SetSetting() error = %v", err)
+	}
+	if err := database.StartCampaign(campaign.ID, "sender"); err != nil {
+		t.Errorf("StartCampaign() with a stale restore lock error = %v", err)
+	}
+}
+
+func TestValidateSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte("not a database"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	other := filepath.Join(dir, "other.db")
+	otherDB, err := db.New(other)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := otherDB.Exec("CREATE TABLE notes (body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	otherDB.Close()
+
+	for name, path := range map[string]string{
+		"missing":    filepath.Join(dir, "missing.db"),
+		"not sqlite": garbage,
+		"not ours":   other,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := db.ValidateSnapshot(path); err == nil {
+				t.Error("ValidateSnapshot() succeeded, want error")
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+		t.Error("ValidateSnapshot() created the missing snapshot")
+	}
+}
@@ -0,0 +1,61 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestRecordBounce(t *testing.T) {
+	database := newTestDB(t)
+	hard := createSubscriber(t, database, "hard@example.com", models.StatusVerified)
+	soft := createSubscriber(t, database, "soft@example.com", models.StatusVerified)
+	createSubscriber(t, database, "ok@example.com", models.StatusVerified)
+
+	record := func(email, bounceType string) bool {
+		t.Helper()
+		marked, err := database.RecordBounce(&models.Bounce{Email: email, Type: bounceType, Source: models.BounceSourceWebhook}, 2)
+		if err != nil {
+			t.Fatalf("RecordBounce(%s) error = %v", email, err)
+		}
+		return marked
+	}
+
+	if !record("HARD@example.com", models.BounceHard) {
+		t.Error("hard bounce did not mark subscriber as bounced")
+	}
+	if record("soft@example.com", models.BounceSoft) {
+		t.Error("first soft bounce marked subscriber as bounced, threshold is 2")
+	}
+	if !record("soft@example.com", models.BounceSoft) {
+		t.Error("second soft bounce did not mark subscriber as bounced")
+	}
+
+	if _, err := database.RecordBounce(&models.Bounce{Email: "nobody@example.com", Type: models.BounceHard, Source: models.BounceSourceWebhook}, 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RecordBounce() for unknown address error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Bounced subscribers no longer receive campaigns
+	verified, err := database.GetVerifiedSubscribers()
+	if err != nil {
+		t.Fatalf("GetVerifiedSubscribers() error = %v", err)
+	}
+	for _, sub := range verified {
+		if sub.ID == hard.ID || sub.ID == soft.ID {
+			t.Errorf("bounced subscriber %s is still verified", sub.Email)
+		}
+	}
+	if len(verified) != 1 {
+		t.Errorf("verified subscribers = %d, want 1", len(verified))
+	}
+
+	bounces, total, err := database.ListBounces(1, 20)
+	if err != nil {
+		t.Fatalf("ListBounces() error = %v", err)
+	}
+	if total != 3 || len(bounces) != 3 || bounces[0].Email != "soft@example.com" {
+		t.Errorf("ListBounces() = %d bounces (total %d), want 3 newest first", len(bounces), total)
+	}
+}
@@ -0,0 +1,336 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// newTestDB creates a migrated database in a temporary directory
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	database, err := db.New(filepath.Join(t.TempDir(), "tinylist.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return database
+}
+
+// createSubscriber inserts a subscriber with the given email and status
+func createSubscriber(t *testing.T, database *db.DB, email, status string) *models.Subscriber {
+	t.Helper()
+	sub := &models.Subscriber{
+		UUID:             uuid.New().String(),
+		Email:            email,
+		Status:           status,
+		UnsubscribeToken: uuid.New().String(),
+	}
+	if err := database.CreateSubscriber(sub); err != nil {
+		t.Fatalf("failed to create subscriber: %v", err)
+	}
+	return sub
+}
+
+// createCampaign inserts a campaign with the given status
+func createCampaign(t *testing.T, database *db.DB, status string) *models.Campaign {
+	t.Helper()
+	campaign := &models.Campaign{
+		UUID:     uuid.New().String(),
+		Subject:  "Hello",
+		BodyText: "Hello {{name}}",
+		Status:   status,
+	}
+	if err := database.CreateCampaign(campaign); err != nil {
+		t.Fatalf("failed to create campaign: %v", err)
+	}
+	return campaign
+}
+
+func TestGetCampaignRecipientsSkipsLoggedSubscribers(t *testing.T) {
+	database := newTestDB(t)
+
+	sent := createSubscriber(t, database, "sent@example.com", models.StatusVerified)
+	failed := createSubscriber(t, database, "failed@example.com", models.StatusVerified)
+	remaining := createSubscriber(t, database, "remaining@example.com", models.StatusVerified)
+	createSubscriber(t, database, "pending@example.com", models.StatusPending)
+
+	campaign := createCampaign(t, database, models.CampaignStatusSending)
+
+	errStr := "mailbox unavailable"
+	logs := []*models.CampaignLog{
+		{CampaignID: campaign.ID, SubscriberID: sent.ID, Status: "sent"},
+		{CampaignID: campaign.ID, SubscriberID: failed.ID, Status: "failed", Error: &errStr},
+	}
+	for _, entry := range logs {
+		if err := database.CreateCampaignLog(entry); err != nil {
+			t.Fatalf("failed to create campaign log: %v", err)
+		}
+	}
+
+	recipients, err := database.GetCampaignRecipients(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignRecipients() error = %v", err)
+	}
+	if len(recipients) != 1 || recipients[0].ID != remaining.ID {
+		t.Fatalf("GetCampaignRecipients() = %v, want only %s", recipients, remaining.Email)
+	}
+
+	sentCount, failedCount, err := database.GetCampaignLogCounts(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignLogCounts() error = %v", err)
+	}
+	if sentCount != 1 || failedCount != 1 {
+		t.Errorf("GetCampaignLogCounts() = (%d, %d), want (1, 1)", sentCount, failedCount)
+	}
+}
+
+func TestListCampaignsByStatus(t *testing.T) {
+	database := newTestDB(t)
+
+	sending := createCampaign(t, database, models.CampaignStatusSending)
+	createCampaign(t, database, models.CampaignStatusDraft)
+	createCampaign(t, database, models.CampaignStatusSent)
+
+	campaigns, err := database.ListCampaignsByStatus(models.CampaignStatusSending)
+	if err != nil {
+		t.Fatalf("ListCampaignsByStatus() error = %v", err)
+	}
+	if len(campaigns) != 1 || campaigns[0].ID != sending.ID {
+		t.Fatalf("ListCampaignsByStatus() returned %d campaigns, want only the sending one", len(campaigns))
+	}
+}
+
+func TestScheduleCampaign(t *testing.T) {
+	database := newTestDB(t)
+
+	due := createCampaign(t, database, models.CampaignStatusDraft)
+	later := createCampaign(t, database, models.CampaignStatusDraft)
+	sending := createCampaign(t, database, models.CampaignStatusSending)
+
+	if err := database.ScheduleCampaign(due.ID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("ScheduleCampaign() error = %v", err)
+	}
+	if err := database.ScheduleCampaign(later.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ScheduleCampaign() error = %v", err)
+	}
+	if err := database.ScheduleCampaign(sending.ID, time.Now()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ScheduleCampaign() on sending campaign error = %v, want sql.ErrNoRows", err)
+	}
+
+	campaigns, err := database.GetDueCampaigns()
+	if err != nil {
+		t.Fatalf("GetDueCampaigns() error = %v", err)
+	}
+	if len(campaigns) != 1 || campaigns[0].ID != due.ID {
+		t.Fatalf("GetDueCampaigns() returned %d campaigns, want only the due one", len(campaigns))
+	}
+
+	if err := database.UnscheduleCampaign(later.ID); err != nil {
+		t.Fatalf("UnscheduleCampaign() error = %v", err)
+	}
+	c, err := database.GetCampaignByID(later.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignByID() error = %v", err)
+	}
+	if c.Status != models.CampaignStatusDraft || c.ScheduledAt != nil {
+		t.Errorf("after UnscheduleCampaign() status = %s, scheduled_at = %v", c.Status, c.ScheduledAt)
+	}
+}
+
+func TestStartAndCancelCampaign(t *testing.T) {
+	database := newTestDB(t)
+	campaign := createCampaign(t, database, models.CampaignStatusDraft)
+
+	if err := database.CancelSendingCampaign(campaign.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("CancelSendingCampaign() on draft error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := database.StartCampaign(campaign.ID, "sender"); err != nil {
+		t.Fatalf("StartCampaign() error = %v", err)
+	}
+	// A second process trying to send the same campaign loses
+	if err := database.StartCampaign(campaign.ID, "sender"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second StartCampaign() error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := database.CancelSendingCampaign(campaign.ID); err != nil {
+		t.Fatalf("CancelSendingCampaign() error = %v", err)
+	}
+	status, err := database.GetCampaignStatus(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignStatus() error = %v", err)
+	}
+	if status != models.CampaignStatusCancelled {
+		t.Errorf("status after CancelSendingCampaign() = %s, want cancelled", status)
+	}
+}
+
+func TestCreateCampaignLogs(t *testing.T) {
+	database := newTestDB(t)
+	campaign := createCampaign(t, database, models.CampaignStatusDraft)
+	if err := database.StartCampaign(campaign.ID, "sender"); err != nil {
+		t.Fatalf("StartCampaign() error = %v", err)
+	}
+	jane := createSubscriber(t, database, "jane@example.com", models.StatusVerified)
+	john := createSubscriber(t, database, "john@example.com", models.StatusVerified)
+
+	errStr := "mailbox unavailable"
+	sentAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	logs := []*models.CampaignLog{
+		{CampaignID: campaign.ID, SubscriberID: jane.ID, Status: "sent", SentAt: sentAt},
+		{CampaignID: campaign.ID, SubscriberID: john.ID, Status: "failed", Error: &errStr},
+	}
+	if err := database.CreateCampaignLogs(campaign.ID, "sender", logs, 3, 1, 1); err != nil {
+		t.Fatalf("CreateCampaignLogs() error = %v", err)
+	}
+
+	// Subscribers logged by an earlier run keep their entry
+	retry := []*models.CampaignLog{{CampaignID: campaign.ID, SubscriberID: jane.ID, Status: "failed", Error: &errStr}}
+	if err := database.CreateCampaignLogs(campaign.ID, "sender", retry, 3, 1, 1); err != nil {
+		t.Fatalf("CreateCampaignLogs() with logged subscriber error = %v", err)
+	}
+
+	sentCount, failedCount, err := database.GetCampaignLogCounts(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignLogCounts() error = %v", err)
+	}
+	if sentCount != 1 || failedCount != 1 {
+		t.Errorf("GetCampaignLogCounts() = (%d, %d), want (1, 1)", sentCount, failedCount)
+	}
+
+	got, err := database.GetCampaignByID(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignByID() error = %v", err)
+	}
+	if got.TotalCount != 3 || got.SentCount != 1 || got.FailedCount != 1 {
+		t.Errorf("campaign counts = (%d, %d, %d), want (3, 1, 1)", got.TotalCount, got.SentCount, got.FailedCount)
+	}
+
+	entries, _, err := database.GetCampaignLogs(campaign.ID, "", 10)
+	if err != nil {
+		t.Fatalf("GetCampaignLogs() error = %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Email == jane.Email && !entry.SentAt.Equal(sentAt) {
+			t.Errorf("log SentAt = %v, want %v", entry.SentAt, sentAt)
+		}
+	}
+
+	// After a takeover the logs are saved, but the counts are the new sender's
+	if err := database.ClaimCampaign(campaign.ID, "other", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("ClaimCampaign() error = %v", err)
+	}
+	alice := createSubscriber(t, database, "alice@example.com", models.StatusVerified)
+	late := []*models.CampaignLog{{CampaignID: campaign.ID, SubscriberID: alice.ID, Status: "sent"}}
+	if err := database.CreateCampaignLogs(campaign.ID, "sender", late, 3, 2, 1); !errors.Is(err, db.ErrTakenOver) {
+		t.Fatalf("CreateCampaignLogs() after takeover error = %v, want ErrTakenOver", err)
+	}
+	if sentCount, _, _ := database.GetCampaignLogCounts(campaign.ID); sentCount != 2 {
+		t.Errorf("sent logs after takeover = %d, want 2", sentCount)
+	}
+	got, err = database.GetCampaignByID(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignByID() error = %v", err)
+	}
+	if got.SentCount != 1 {
+		t.Errorf("sent count after takeover = %d, want 1 left to the new sender", got.SentCount)
+	}
+
+	// Nothing is saved for a campaign that no longer exists
+	if err := database.CreateCampaignLogs(campaign.ID+100, "sender", nil, 0, 0, 0); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("CreateCampaignLogs() for missing campaign error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestStartScheduledCampaign(t *testing.T) {
+	database := newTestDB(t)
+	campaign := createCampaign(t, database, models.CampaignStatusDraft)
+
+	if err := database.ScheduleCampaign(campaign.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ScheduleCampaign() error = %v", err)
+	}
+	if err := database.StartScheduledCampaign(campaign.ID, "sender"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("StartScheduledCampaign() before it is due error = %v, want sql.ErrNoRows", err)
+	}
+
+	// Unscheduled after the scheduler found it due
+	if err := database.ScheduleCampaign(campaign.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("ScheduleCampaign() error = %v", err)
+	}
+	if err := database.UnscheduleCampaign(campaign.ID); err != nil {
+		t.Fatalf("UnscheduleCampaign() error = %v", err)
+	}
+	if err := database.StartScheduledCampaign(campaign.ID, "sender"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("StartScheduledCampaign() of unscheduled campaign error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := database.ScheduleCampaign(campaign.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("ScheduleCampaign() error = %v", err)
+	}
+	if err := database.StartScheduledCampaign(campaign.ID, "sender"); err != nil {
+		t.Fatalf("StartScheduledCampaign() error = %v", err)
+	}
+	status, err := database.GetCampaignStatus(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignStatus() error = %v", err)
+	}
+	if status != models.CampaignStatusSending {
+		t.Errorf("status after StartScheduledCampaign() = %s, want sending", status)
+	}
+}
+
+func TestClaimCampaign(t *testing.T) {
+	database := newTestDB(t)
+	campaign := createCampaign(t, database, models.CampaignStatusDraft)
+
+	if err := database.StartCampaign(campaign.ID, "first"); err != nil {
+		t.Fatalf("StartCampaign() error = %v", err)
+	}
+
+	// The first sender's heartbeat is fresh
+	staleBefore := time.Now().Add(-time.Minute)
+	if err := database.ClaimCampaign(campaign.ID, "second", staleBefore); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ClaimCampaign() with a live sender error = %v, want sql.ErrNoRows", err)
+	}
+	abandoned, err := database.GetAbandonedCampaigns(staleBefore)
+	if err != nil {
+		t.Fatalf("GetAbandonedCampaigns() error = %v", err)
+	}
+	if len(abandoned) != 0 {
+		t.Errorf("GetAbandonedCampaigns() returned %d campaigns, want none", len(abandoned))
+	}
+
+	// Once it has gone stale, exactly one claim wins
+	staleBefore = time.Now().Add(time.Minute)
+	abandoned, err = database.GetAbandonedCampaigns(staleBefore)
+	if err != nil {
+		t.Fatalf("GetAbandonedCampaigns() error = %v", err)
+	}
+	if len(abandoned) != 1 || abandoned[0].ID != campaign.ID {
+		t.Fatalf("GetAbandonedCampaigns() = %v, want the started campaign", abandoned)
+	}
+	if err := database.ClaimCampaign(campaign.ID, "second", staleBefore); err != nil {
+		t.Fatalf("ClaimCampaign() error = %v", err)
+	}
+	if err := database.ClaimCampaign(campaign.ID, "third", time.Now().Add(-time.Minute)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("second ClaimCampaign() error = %v, want sql.ErrNoRows", err)
+	}
+
+	// The first sender learns it was taken over from its next heartbeat
+	if err := database.HeartbeatCampaign(campaign.ID, "first"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("HeartbeatCampaign() of the replaced sender error = %v, want sql.ErrNoRows", err)
+	}
+	if err := database.HeartbeatCampaign(campaign.ID, "second"); err != nil {
+		t.Errorf("HeartbeatCampaign() error = %v", err)
+	}
+}
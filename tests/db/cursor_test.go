@@ -0,0 +1,111 @@
+package db_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestListSubscribersAfter(t *testing.T) {
+	database := newTestDB(t)
+	for i := 1; i <= 7; i++ {
+		createSubscriber(t, database, fmt.Sprintf("user%d@example.com", i), models.StatusVerified)
+	}
+
+	order := db.SubscriberSort{Field: "email"}
+	var emails []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		subs, next, err := database.ListSubscribersAfter(db.SubscriberFilter{}, order, cursor, 3)
+		if err != nil {
+			t.Fatalf("ListSubscribersAfter() error = %v", err)
+		}
+		for _, sub := range subs {
+			emails = append(emails, sub.Email)
+		}
+
+		// Signups while paging don't shift the following pages
+		if pages == 0 {
+			createSubscriber(t, database, "user0@example.com", models.StatusVerified)
+			createSubscriber(t, database, "user8@example.com", models.StatusVerified)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	want := []string{
+		"user1@example.com", "user2@example.com", "user3@example.com", "user4@example.com",
+		"user5@example.com", "user6@example.com", "user7@example.com", "user8@example.com",
+	}
+	if fmt.Sprint(emails) != fmt.Sprint(want) {
+		t.Errorf("ListSubscribersAfter() pages = %v, want %v", emails, want)
+	}
+
+	if _, _, err := database.ListSubscribersAfter(db.SubscriberFilter{}, order, "garbage", 3); !errors.Is(err, db.ErrInvalidCursor) {
+		t.Errorf("ListSubscribersAfter() with bad cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestListSubscribersAfterOtherSort(t *testing.T) {
+	database := newTestDB(t)
+	for i := 1; i <= 4; i++ {
+		createSubscriber(t, database, fmt.Sprintf("user%d@example.com", i), models.StatusVerified)
+	}
+
+	order := db.SubscriberSort{Field: "email"}
+	_, next, err := database.ListSubscribersAfter(db.SubscriberFilter{}, order, "", 2)
+	if err != nil || next == "" {
+		t.Fatalf("ListSubscribersAfter() next = %q, error = %v", next, err)
+	}
+
+	// A cursor only continues the sort it was issued for
+	for _, other := range []db.SubscriberSort{{Field: "name"}, {Field: "email", Desc: true}} {
+		if _, _, err := database.ListSubscribersAfter(db.SubscriberFilter{}, other, next, 2); !errors.Is(err, db.ErrInvalidCursor) {
+			t.Errorf("ListSubscribersAfter(%+v) with email cursor error = %v, want ErrInvalidCursor", other, err)
+		}
+	}
+	if _, _, err := database.ListCampaignsAfter(next, 2); !errors.Is(err, db.ErrInvalidCursor) {
+		t.Errorf("ListCampaignsAfter() with subscriber cursor error = %v, want ErrInvalidCursor", err)
+	}
+	if _, _, err := database.ListSubscribersAfter(db.SubscriberFilter{}, order, next, 2); err != nil {
+		t.Errorf("ListSubscribersAfter() with its own cursor error = %v", err)
+	}
+}
+
+func TestGetCampaignLogsPages(t *testing.T) {
+	database := newTestDB(t)
+	campaign := createCampaign(t, database, models.CampaignStatusSent)
+	for i := 0; i < 5; i++ {
+		sub := createSubscriber(t, database, fmt.Sprintf("user%d@example.com", i), models.StatusVerified)
+		log := &models.CampaignLog{CampaignID: campaign.ID, SubscriberID: sub.ID, Status: "sent"}
+		if err := database.CreateCampaignLog(log); err != nil {
+			t.Fatalf("CreateCampaignLog() error = %v", err)
+		}
+	}
+
+	var emails []string
+	cursor := ""
+	for {
+		logs, next, err := database.GetCampaignLogs(campaign.ID, cursor, 2)
+		if err != nil {
+			t.Fatalf("GetCampaignLogs() error = %v", err)
+		}
+		for _, log := range logs {
+			emails = append(emails, log.Email)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	want := []string{"user4@example.com", "user3@example.com", "user2@example.com", "user1@example.com", "user0@example.com"}
+	if fmt.Sprint(emails) != fmt.Sprint(want) {
+		t.Errorf("GetCampaignLogs() pages = %v, want %v", emails, want)
+	}
+}
@@ -0,0 +1,73 @@
+package db_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestConcurrentWrites(t *testing.T) {
+	database := newTestDB(t)
+
+	var journalMode string
+	if err := database.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("failed to read journal mode: %v", err)
+	}
+	if journalMode != "wal" {
+		t.Errorf("journal_mode = %q, want wal", journalMode)
+	}
+
+	// A second handle stands in for another process, like an admin command
+	// running next to the server
+	other, err := db.New(database.Path())
+	if err != nil {
+		t.Fatalf("failed to open second handle: %v", err)
+	}
+	defer other.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		for j, handle := range []*db.DB{database, other} {
+			wg.Add(1)
+			go func(handle *db.DB, email string) {
+				defer wg.Done()
+				sub := &models.Subscriber{
+					UUID:             uuid.New().String(),
+					Email:            email,
+					Status:           models.StatusVerified,
+					UnsubscribeToken: uuid.New().String(),
+				}
+				if err := handle.CreateSubscriber(sub); err != nil {
+					errs <- err
+					return
+				}
+				if _, _, err := handle.ListSubscribers(db.SubscriberFilter{}, db.SubscriberSort{}, 1, 20); err != nil {
+					errs <- err
+				}
+			}(handle, fmt.Sprintf("user%d-%d@example.com", i, j))
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent write error = %v", err)
+	}
+
+	_, total, err := database.ListSubscribers(db.SubscriberFilter{}, db.SubscriberSort{}, 1, 1)
+	if err != nil {
+		t.Fatalf("ListSubscribers() error = %v", err)
+	}
+	if total != 40 {
+		t.Errorf("ListSubscribers() total = %d, want 40", total)
+	}
+
+	// Writes sent to the read pool fail instead of bypassing the writer
+	if _, err := database.Query("DELETE FROM subscribers"); err == nil {
+		t.Error("write through Query() succeeded, want error")
+	}
+}
@@ -0,0 +1,64 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestExportSubscribers(t *testing.T) {
+	database := newTestDB(t)
+
+	list := createList(t, database, "weekly")
+	alice := createSubscriber(t, database, "alice@example.com", models.StatusVerified)
+	createSubscriber(t, database, "bob@example.com", models.StatusPending)
+	carol := createSubscriber(t, database, "carol@example.com", models.StatusVerified)
+	if err := database.SetListMembership(carol.ID, list.ID, models.StatusVerified); err != nil {
+		t.Fatalf("failed to add membership: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   db.SubscriberFilter
+		expected []string
+	}{
+		{"all", db.SubscriberFilter{}, []string{"alice@example.com", "bob@example.com", "carol@example.com"}},
+		{"by status", db.SubscriberFilter{Status: models.StatusVerified}, []string{"alice@example.com", "carol@example.com"}},
+		{"by list", db.SubscriberFilter{ListID: list.ID}, []string{"carol@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var emails []string
+			err := database.ExportSubscribers(context.Background(), tt.filter, func(sub *models.Subscriber) error {
+				emails = append(emails, sub.Email)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("ExportSubscribers failed: %v", err)
+			}
+			if !reflect.DeepEqual(emails, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, emails)
+			}
+		})
+	}
+
+	t.Run("callback error stops export", func(t *testing.T) {
+		stop := errors.New("stop")
+		var emails []string
+		err := database.ExportSubscribers(context.Background(), db.SubscriberFilter{}, func(sub *models.Subscriber) error {
+			emails = append(emails, sub.Email)
+			return stop
+		})
+		if !errors.Is(err, stop) {
+			t.Errorf("expected callback error, got %v", err)
+		}
+		if len(emails) != 1 || emails[0] != alice.Email {
+			t.Errorf("expected export to stop after first row, got %v", emails)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package db_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestImportSubscribers(t *testing.T) {
+	database := newTestDB(t)
+	createSubscriber(t, database, "existing@example.com", models.StatusPending)
+
+	rows := []db.ImportRow{
+		{Email: "new@example.com", Name: "New", Status: models.StatusVerified},
+		{Email: "existing@example.com", Name: "Existing", Status: models.StatusVerified},
+		{Email: "default@example.com"},
+	}
+
+	tests := []struct {
+		name     string
+		opts     db.ImportOptions
+		expected []string
+	}{
+		{
+			name:     "dry run",
+			opts:     db.ImportOptions{DryRun: true},
+			expected: []string{db.ImportCreated, db.ImportSkipped, db.ImportCreated},
+		},
+		{
+			name:     "dry run leaves nothing behind",
+			opts:     db.ImportOptions{DryRun: true, UpdateExisting: true},
+			expected: []string{db.ImportCreated, db.ImportUpdated, db.ImportCreated},
+		},
+		{
+			name:     "skip existing",
+			opts:     db.ImportOptions{},
+			expected: []string{db.ImportCreated, db.ImportSkipped, db.ImportCreated},
+		},
+		{
+			name:     "update existing",
+			opts:     db.ImportOptions{UpdateExisting: true},
+			expected: []string{db.ImportUpdated, db.ImportUpdated, db.ImportUpdated},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := database.ImportSubscribers(rows, tt.opts)
+			if err != nil {
+				t.Fatalf("ImportSubscribers() error = %v", err)
+			}
+			if !reflect.DeepEqual(results, tt.expected) {
+				t.Errorf("ImportSubscribers() = %v, want %v", results, tt.expected)
+			}
+		})
+	}
+
+	existing, err := database.GetSubscriberByEmail("existing@example.com")
+	if err != nil {
+		t.Fatalf("GetSubscriberByEmail() error = %v", err)
+	}
+	if existing.Status != models.StatusVerified || existing.Name != "Existing" || existing.VerifiedAt == nil {
+		t.Errorf("updated subscriber = %+v, want verified with name Existing", existing)
+	}
+
+	imported, err := database.GetSubscriberByEmail("default@example.com")
+	if err != nil {
+		t.Fatalf("GetSubscriberByEmail() error = %v", err)
+	}
+	if imported.Status != models.StatusPending || imported.VerifyToken == nil {
+		t.Errorf("imported subscriber status = %s, want pending with a verify token", imported.Status)
+	}
+}
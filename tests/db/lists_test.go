@@ -0,0 +1,135 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// createList inserts a list with the given slug
+func createList(t *testing.T, database *db.DB, slug string) *models.List {
+	t.Helper()
+	list := &models.List{UUID: uuid.New().String(), Slug: slug, Name: slug}
+	if err := database.CreateList(list); err != nil {
+		t.Fatalf("failed to create list: %v", err)
+	}
+	return list
+}
+
+func TestGetCampaignRecipientsTargetsLists(t *testing.T) {
+	database := newTestDB(t)
+
+	weekly := createList(t, database, "weekly")
+	monthly := createList(t, database, "monthly")
+
+	member := createSubscriber(t, database, "member@example.com", models.StatusVerified)
+	pendingMember := createSubscriber(t, database, "pending-member@example.com", models.StatusVerified)
+	otherList := createSubscriber(t, database, "other@example.com", models.StatusVerified)
+	createSubscriber(t, database, "nolist@example.com", models.StatusVerified)
+
+	memberships := []struct {
+		sub    *models.Subscriber
+		list   *models.List
+		status string
+	}{
+		{member, weekly, models.StatusVerified},
+		{pendingMember, weekly, models.StatusPending},
+		{otherList, monthly, models.StatusVerified},
+	}
+	for _, m := range memberships {
+		if err := database.SetListMembership(m.sub.ID, m.list.ID, m.status); err != nil {
+			t.Fatalf("SetListMembership() error = %v", err)
+		}
+	}
+
+	targeted := createCampaign(t, database, models.CampaignStatusDraft)
+	if err := database.SetCampaignLists(targeted.ID, []int{weekly.ID}); err != nil {
+		t.Fatalf("SetCampaignLists() error = %v", err)
+	}
+
+	recipients, err := database.GetCampaignRecipients(targeted.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignRecipients() error = %v", err)
+	}
+	if len(recipients) != 1 || recipients[0].ID != member.ID {
+		t.Fatalf("GetCampaignRecipients() returned %d recipients, want only the verified weekly member", len(recipients))
+	}
+
+	campaign, err := database.GetCampaignByID(targeted.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignByID() error = %v", err)
+	}
+	if len(campaign.ListIDs) != 1 || campaign.ListIDs[0] != weekly.UUID {
+		t.Errorf("campaign ListIDs = %v, want [%s]", campaign.ListIDs, weekly.UUID)
+	}
+
+	// Without target lists every verified subscriber is a recipient
+	everyone := createCampaign(t, database, models.CampaignStatusDraft)
+	recipients, err = database.GetCampaignRecipients(everyone.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignRecipients() error = %v", err)
+	}
+	if len(recipients) != 4 {
+		t.Errorf("GetCampaignRecipients() without lists returned %d recipients, want 4", len(recipients))
+	}
+
+	list, err := database.GetListByIdentifier("WEEKLY")
+	if err != nil {
+		t.Fatalf("GetListByIdentifier() error = %v", err)
+	}
+	if list.SubscriberCount != 1 {
+		t.Errorf("list SubscriberCount = %d, want 1", list.SubscriberCount)
+	}
+}
+
+func TestCampaignWithListsSavedTogether(t *testing.T) {
+	database := newTestDB(t)
+	weekly := createList(t, database, "weekly")
+	const missingList = 9999
+
+	// A campaign whose lists can't be saved isn't saved either
+	campaign := &models.Campaign{UUID: uuid.New().String(), Subject: "Hello", BodyText: "Hello", Status: models.CampaignStatusDraft}
+	if err := database.CreateCampaignWithLists(campaign, []int{missingList}); err == nil {
+		t.Fatal("CreateCampaignWithLists() with a missing list succeeded, want error")
+	}
+	if _, err := database.GetCampaignByUUID(campaign.UUID); err == nil {
+		t.Error("campaign was saved without its lists")
+	}
+
+	campaign = &models.Campaign{UUID: uuid.New().String(), Subject: "Hello", BodyText: "Hello", Status: models.CampaignStatusDraft}
+	if err := database.CreateCampaignWithLists(campaign, []int{weekly.ID}); err != nil {
+		t.Fatalf("CreateCampaignWithLists() error = %v", err)
+	}
+
+	// Neither the content nor the lists change when the lists can't be saved
+	campaign.Subject = "Changed"
+	if err := database.UpdateCampaignWithLists(campaign, []int{missingList}); err == nil {
+		t.Fatal("UpdateCampaignWithLists() with a missing list succeeded, want error")
+	}
+	stored, err := database.GetCampaignByID(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignByID() error = %v", err)
+	}
+	if stored.Subject != "Hello" {
+		t.Errorf("Subject = %q after failed update, want Hello", stored.Subject)
+	}
+	if len(stored.ListIDs) != 1 || stored.ListIDs[0] != weekly.UUID {
+		t.Errorf("ListIDs = %v after failed update, want [%s]", stored.ListIDs, weekly.UUID)
+	}
+
+	// Nil lists are left alone, empty ones removed
+	if err := database.UpdateCampaignWithLists(campaign, nil); err != nil {
+		t.Fatalf("UpdateCampaignWithLists() error = %v", err)
+	}
+	if stored, _ := database.GetCampaignByID(campaign.ID); len(stored.ListIDs) != 1 {
+		t.Errorf("ListIDs = %v after update without lists, want them kept", stored.ListIDs)
+	}
+	if err := database.UpdateCampaignWithLists(campaign, []int{}); err != nil {
+		t.Fatalf("UpdateCampaignWithLists() error = %v", err)
+	}
+	if stored, _ := database.GetCampaignByID(campaign.ID); len(stored.ListIDs) != 0 {
+		t.Errorf("ListIDs = %v after update with empty lists, want none", stored.ListIDs)
+	}
+}
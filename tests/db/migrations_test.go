@@ -0,0 +1,164 @@
+package db_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// legacySchema is the schema as created by the first TinyList release
+const legacySchema = `
+CREATE TABLE subscribers (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    email           TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    name            TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL CHECK(status IN ('pending', 'verified', 'unsubscribed')) DEFAULT 'pending',
+    verify_token    TEXT UNIQUE,
+    unsubscribe_token TEXT NOT NULL UNIQUE,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    verified_at     TEXT,
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+CREATE TABLE campaigns (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    uuid            TEXT NOT NULL UNIQUE,
+    subject         TEXT NOT NULL,
+    body_text       TEXT NOT NULL,
+    body_html       TEXT,
+    status          TEXT NOT NULL CHECK(status IN ('draft', 'sending', 'sent', 'failed', 'cancelled')) DEFAULT 'draft',
+    total_count     INTEGER NOT NULL DEFAULT 0,
+    sent_count      INTEGER NOT NULL DEFAULT 0,
+    failed_count    INTEGER NOT NULL DEFAULT 0,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    started_at      TEXT,
+    completed_at    TEXT
+);
+CREATE TABLE campaign_logs (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    campaign_id     INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    subscriber_id   INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+    status          TEXT NOT NULL CHECK(status IN ('sent', 'failed')),
+    error           TEXT,
+    sent_at         TEXT NOT NULL DEFAULT (datetime('now')),
+    UNIQUE(campaign_id, subscriber_id)
+);
+INSERT INTO subscribers (uuid, email, status, unsubscribe_token) VALUES ('s1', 'a@example.com', 'verified', 't1');
+INSERT INTO campaigns (uuid, subject, body_text, status) VALUES ('c1', 'Old', 'Body', 'sent');
+INSERT INTO campaigns (uuid, subject, body_text, status) VALUES ('c2', 'Draft', 'Body', 'draft');
+INSERT INTO campaign_logs (campaign_id, subscriber_id, status) VALUES (1, 1, 'sent');
+`
+
+func TestMigrateUpgradesLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := db.New(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := legacy.Exec(legacySchema); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	legacy.Close()
+
+	database, err := db.New(path)
+	if err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	// Running again must be a no-op
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("second Migrate() error = %v", err)
+	}
+
+	// The upgraded database is adopted at the baseline and then migrated as usual
+	migrations, err := database.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	for _, m := range migrations {
+		if m.AppliedAt == nil {
+			t.Errorf("migration %04d_%s is pending after Migrate()", m.Version, m.Name)
+		}
+	}
+
+	// Existing rows survive the rebuild, including rows referencing the rebuilt table
+	sent, err := database.GetCampaignByUUID("c1")
+	if err != nil {
+		t.Fatalf("GetCampaignByUUID() error = %v", err)
+	}
+	sentCount, _, err := database.GetCampaignLogCounts(sent.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignLogCounts() error = %v", err)
+	}
+	if sentCount != 1 {
+		t.Errorf("campaign logs after upgrade = %d, want 1", sentCount)
+	}
+
+	// The new status is accepted
+	draft, err := database.GetCampaignByUUID("c2")
+	if err != nil {
+		t.Fatalf("GetCampaignByUUID() error = %v", err)
+	}
+	if err := database.ScheduleCampaign(draft.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("ScheduleCampaign() error = %v", err)
+	}
+	due, err := database.GetDueCampaigns()
+	if err != nil {
+		t.Fatalf("GetDueCampaigns() error = %v", err)
+	}
+	if len(due) != 1 || due[0].Status != models.CampaignStatusScheduled || due[0].ScheduledAt == nil {
+		t.Fatalf("GetDueCampaigns() = %v, want the scheduled draft", due)
+	}
+
+	// Subscribers accept the bounced status
+	if _, err := database.RecordBounce(&models.Bounce{Email: "a@example.com", Type: models.BounceHard, Source: models.BounceSourceWebhook}, 3); err != nil {
+		t.Fatalf("RecordBounce() error = %v", err)
+	}
+	sub, err := database.GetSubscriberByEmail("a@example.com")
+	if err != nil {
+		t.Fatalf("GetSubscriberByEmail() error = %v", err)
+	}
+	if sub.Status != models.StatusBounced {
+		t.Errorf("subscriber status after hard bounce = %s, want bounced", sub.Status)
+	}
+}
+
+func TestMigrateRecordsVersions(t *testing.T) {
+	database := newTestDB(t)
+
+	migrations, err := database.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Migrations() returned no migrations")
+	}
+	for _, m := range migrations {
+		if m.AppliedAt == nil {
+			t.Errorf("migration %04d_%s is pending after Migrate()", m.Version, m.Name)
+		}
+	}
+
+	version, err := database.GetSchemaVersion()
+	if err != nil {
+		t.Fatalf("GetSchemaVersion() error = %v", err)
+	}
+	if version != len(migrations) {
+		t.Errorf("GetSchemaVersion() = %d, want %d", version, len(migrations))
+	}
+
+	// A database migrated by a newer build is left alone
+	if err := database.SetSchemaVersion(version + 1); err != nil {
+		t.Fatalf("SetSchemaVersion() error = %v", err)
+	}
+	if err := database.Migrate(); err == nil {
+		t.Error("Migrate() of a newer schema succeeded, want error")
+	}
+}
@@ -0,0 +1,92 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestListSubscribersSearch(t *testing.T) {
+	database := newTestDB(t)
+	for email, name := range map[string]string{
+		"jane.doe@example.com": "Jane Doe",
+		"john@example.org":     "John Smith",
+		"ann_100@example.com":  "Ann",
+		"ann2100@example.com":  "",
+	} {
+		sub := createSubscriber(t, database, email, models.StatusVerified)
+		sub.Name = name
+		if err := database.UpdateSubscriber(sub); err != nil {
+			t.Fatalf("UpdateSubscriber() error = %v", err)
+		}
+	}
+
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name   string
+		filter db.SubscriberFilter
+		want   int
+	}{
+		{"email substring", db.SubscriberFilter{Query: "example.org"}, 1},
+		{"name substring", db.SubscriberFilter{Query: "smith"}, 1},
+		{"email or name", db.SubscriberFilter{Query: "jane"}, 1},
+		{"wildcards are literal", db.SubscriberFilter{Query: "_1"}, 1},
+		{"percent is literal", db.SubscriberFilter{Query: "%"}, 0},
+		{"combined with status", db.SubscriberFilter{Query: "ann", Status: models.StatusPending}, 0},
+		{"created after", db.SubscriberFilter{CreatedAfter: &past}, 4},
+		{"created before", db.SubscriberFilter{CreatedBefore: &past}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, total, err := database.ListSubscribers(tt.filter, db.SubscriberSort{}, 1, 20)
+			if err != nil {
+				t.Fatalf("ListSubscribers() error = %v", err)
+			}
+			if total != tt.want {
+				t.Errorf("ListSubscribers() total = %d, want %d", total, tt.want)
+			}
+		})
+	}
+}
+
+func TestListSubscribersSort(t *testing.T) {
+	database := newTestDB(t)
+	for _, email := range []string{"b@example.com", "C@example.com", "a@example.com"} {
+		createSubscriber(t, database, email, models.StatusVerified)
+	}
+
+	tests := []struct {
+		name string
+		sort db.SubscriberSort
+		want []string
+	}{
+		{"email ascending", db.SubscriberSort{Field: "email"}, []string{"a@example.com", "b@example.com", "C@example.com"}},
+		{"email descending", db.SubscriberSort{Field: "email", Desc: true}, []string{"C@example.com", "b@example.com", "a@example.com"}},
+		// Subscribers created within the same second are ordered by ID
+		{"newest first", db.SubscriberSort{Field: "created_at", Desc: true}, []string{"a@example.com", "C@example.com", "b@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subs, _, err := database.ListSubscribers(db.SubscriberFilter{}, tt.sort, 1, 20)
+			if err != nil {
+				t.Fatalf("ListSubscribers() error = %v", err)
+			}
+			var got []string
+			for _, sub := range subs {
+				got = append(got, sub.Email)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ListSubscribers() returned %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ListSubscribers() returned %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
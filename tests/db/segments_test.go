@@ -0,0 +1,123 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+// createSegment inserts a segment with the given rules
+func createSegment(t *testing.T, database *db.DB, rules models.SegmentRules) *models.Segment {
+	t.Helper()
+	seg := &models.Segment{UUID: uuid.New().String(), Name: "segment", Rules: rules}
+	if err := database.CreateSegment(seg); err != nil {
+		t.Fatalf("failed to create segment: %v", err)
+	}
+	return seg
+}
+
+func TestCountSegment(t *testing.T) {
+	database := newTestDB(t)
+
+	weekly := createList(t, database, "weekly")
+	sent := createCampaign(t, database, models.CampaignStatusSent)
+
+	berlin := createSubscriber(t, database, "berlin@example.com", models.StatusVerified)
+	berlin.Attributes = map[string]interface{}{"city": "Berlin", "age": 30}
+	if err := database.UpdateSubscriber(berlin); err != nil {
+		t.Fatalf("UpdateSubscriber() error = %v", err)
+	}
+	reader := createSubscriber(t, database, "reader@example.com", models.StatusVerified)
+	createSubscriber(t, database, "pending@example.com", models.StatusPending)
+	createSubscriber(t, database, "gone@example.com", models.StatusUnsubscribed)
+
+	if err := database.SetListMembership(berlin.ID, weekly.ID, models.StatusVerified); err != nil {
+		t.Fatalf("SetListMembership() error = %v", err)
+	}
+	if err := database.CreateCampaignEvent(sent.ID, reader.ID, models.CampaignEventOpen, 0); err != nil {
+		t.Fatalf("CreateCampaignEvent() error = %v", err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name         string
+		rules        models.SegmentRules
+		wantTotal    int
+		wantVerified int
+	}{
+		{"no rules", models.SegmentRules{}, 4, 2},
+		{"status", models.SegmentRules{Status: []string{models.StatusPending, models.StatusUnsubscribed}}, 2, 0},
+		{"list", models.SegmentRules{Lists: []string{weekly.UUID}}, 1, 1},
+		{"subscribed after", models.SegmentRules{SubscribedAfter: &past}, 4, 2},
+		{"subscribed before", models.SegmentRules{SubscribedBefore: &past}, 0, 0},
+		{"subscribed range", models.SegmentRules{SubscribedAfter: &past, SubscribedBefore: &future}, 4, 2},
+		{"attribute", models.SegmentRules{Attributes: map[string]interface{}{"city": "Berlin"}}, 1, 1},
+		{"attribute type", models.SegmentRules{Attributes: map[string]interface{}{"age": "30"}}, 0, 0},
+		{"opened campaign", models.SegmentRules{OpenedCampaign: sent.UUID}, 1, 1},
+		{"clicked campaign", models.SegmentRules{ClickedCampaign: sent.UUID}, 0, 0},
+		{"all must match", models.SegmentRules{Lists: []string{weekly.UUID}, OpenedCampaign: sent.UUID}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, verified, err := database.CountSegment(tt.rules)
+			if err != nil {
+				t.Fatalf("CountSegment() error = %v", err)
+			}
+			if total != tt.wantTotal || verified != tt.wantVerified {
+				t.Errorf("CountSegment() = %d, %d, want %d, %d", total, verified, tt.wantTotal, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestGetCampaignRecipientsTargetsSegment(t *testing.T) {
+	database := newTestDB(t)
+
+	berlin := createSubscriber(t, database, "berlin@example.com", models.StatusVerified)
+	berlin.Attributes = map[string]interface{}{"city": "Berlin"}
+	if err := database.UpdateSubscriber(berlin); err != nil {
+		t.Fatalf("UpdateSubscriber() error = %v", err)
+	}
+	createSubscriber(t, database, "paris@example.com", models.StatusVerified)
+
+	seg := createSegment(t, database, models.SegmentRules{Attributes: map[string]interface{}{"city": "Berlin"}})
+	campaign := createCampaign(t, database, models.CampaignStatusDraft)
+	campaign.SegmentID = seg.ID
+	if err := database.UpdateCampaign(campaign); err != nil {
+		t.Fatalf("UpdateCampaign() error = %v", err)
+	}
+
+	recipients, err := database.GetCampaignRecipients(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignRecipients() error = %v", err)
+	}
+	if len(recipients) != 1 || recipients[0].ID != berlin.ID {
+		t.Fatalf("GetCampaignRecipients() returned %d recipients, want only the Berlin subscriber", len(recipients))
+	}
+
+	got, err := database.GetCampaignByID(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignByID() error = %v", err)
+	}
+	if got.SegmentUUID == nil || *got.SegmentUUID != seg.UUID {
+		t.Errorf("campaign SegmentUUID = %v, want %s", got.SegmentUUID, seg.UUID)
+	}
+
+	// Deleting the segment widens the campaign back to every verified subscriber
+	if err := database.DeleteSegment(seg.ID); err != nil {
+		t.Fatalf("DeleteSegment() error = %v", err)
+	}
+	recipients, err = database.GetCampaignRecipients(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignRecipients() error = %v", err)
+	}
+	if len(recipients) != 2 {
+		t.Errorf("GetCampaignRecipients() after deleting segment returned %d recipients, want 2", len(recipients))
+	}
+}
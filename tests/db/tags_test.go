@@ -0,0 +1,89 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestSubscriberTags(t *testing.T) {
+	database := newTestDB(t)
+	jane := createSubscriber(t, database, "jane@example.com", models.StatusVerified)
+	john := createSubscriber(t, database, "john@example.com", models.StatusVerified)
+	createSubscriber(t, database, "pending@example.com", models.StatusPending)
+
+	if err := database.AddSubscriberTags(jane.ID, []string{"customer", "beta", "beta"}); err != nil {
+		t.Fatalf("AddSubscriberTags() error = %v", err)
+	}
+	got, err := database.GetSubscriberByID(jane.ID)
+	if err != nil {
+		t.Fatalf("GetSubscriberByID() error = %v", err)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "beta" || got.Tags[1] != "customer" {
+		t.Errorf("subscriber Tags = %v, want [beta customer]", got.Tags)
+	}
+
+	// Bulk tagging skips subscribers that already have the tag
+	count, err := database.TagSubscribers(db.SubscriberFilter{Status: models.StatusVerified}, "beta")
+	if err != nil {
+		t.Fatalf("TagSubscribers() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("TagSubscribers() = %d, want 1", count)
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		want int
+	}{
+		{"one tag", []string{"beta"}, 2},
+		{"all must match", []string{"beta", "customer"}, 1},
+		{"unknown tag", []string{"nope"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, total, err := database.ListSubscribers(db.SubscriberFilter{Tags: tt.tags}, db.SubscriberSort{}, 1, 20)
+			if err != nil {
+				t.Fatalf("ListSubscribers() error = %v", err)
+			}
+			if total != tt.want {
+				t.Errorf("ListSubscribers() total = %d, want %d", total, tt.want)
+			}
+		})
+	}
+
+	total, _, err := database.CountSegment(models.SegmentRules{Tags: []string{"customer"}})
+	if err != nil {
+		t.Fatalf("CountSegment() error = %v", err)
+	}
+	if total != 1 {
+		t.Errorf("CountSegment() with tag = %d, want 1", total)
+	}
+
+	if err := database.RemoveSubscriberTag(john.ID, "customer"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RemoveSubscriberTag() of missing tag error = %v, want sql.ErrNoRows", err)
+	}
+	if err := database.RemoveSubscriberTag(jane.ID, "customer"); err != nil {
+		t.Fatalf("RemoveSubscriberTag() error = %v", err)
+	}
+
+	// Tags nobody has anymore are removed
+	count, err = database.UntagSubscribers(db.SubscriberFilter{}, "beta")
+	if err != nil {
+		t.Fatalf("UntagSubscribers() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("UntagSubscribers() = %d, want 2", count)
+	}
+	tags, err := database.ListTags()
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if len(tags) != 0 {
+		t.Errorf("ListTags() returned %d tags, want none", len(tags))
+	}
+}
@@ -0,0 +1,64 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestCampaignStats(t *testing.T) {
+	database := newTestDB(t)
+	campaign := createCampaign(t, database, models.CampaignStatusSent)
+	alice := createSubscriber(t, database, "alice@example.com", models.StatusVerified)
+	bob := createSubscriber(t, database, "bob@example.com", models.StatusVerified)
+
+	urls := []string{"https://example.com/a", "https://example.com/b"}
+	links, err := database.RegisterCampaignLinks(campaign.ID, urls)
+	if err != nil {
+		t.Fatalf("RegisterCampaignLinks() error = %v", err)
+	}
+	// Registering again, e.g. on resume, keeps the existing links
+	again, err := database.RegisterCampaignLinks(campaign.ID, urls)
+	if err != nil {
+		t.Fatalf("second RegisterCampaignLinks() error = %v", err)
+	}
+	if len(links) != 2 || links[urls[0]] != again[urls[0]] || links[urls[1]] != again[urls[1]] {
+		t.Fatalf("links changed between registrations: %v vs %v", links, again)
+	}
+
+	linkA, err := database.GetCampaignLinkByUUID(links[urls[0]])
+	if err != nil {
+		t.Fatalf("GetCampaignLinkByUUID() error = %v", err)
+	}
+
+	events := []struct {
+		subscriberID int
+		eventType    string
+		linkID       int
+	}{
+		{alice.ID, models.CampaignEventOpen, 0},
+		{alice.ID, models.CampaignEventOpen, 0},
+		{bob.ID, models.CampaignEventOpen, 0},
+		{alice.ID, models.CampaignEventClick, linkA.ID},
+		{alice.ID, models.CampaignEventClick, linkA.ID},
+	}
+	for _, e := range events {
+		if err := database.CreateCampaignEvent(campaign.ID, e.subscriberID, e.eventType, e.linkID); err != nil {
+			t.Fatalf("CreateCampaignEvent() error = %v", err)
+		}
+	}
+
+	stats, err := database.GetCampaignStats(campaign.ID, 4)
+	if err != nil {
+		t.Fatalf("GetCampaignStats() error = %v", err)
+	}
+	if stats.Opens != 2 || stats.Clicks != 1 {
+		t.Errorf("opens, clicks = %d, %d, want 2, 1", stats.Opens, stats.Clicks)
+	}
+	if stats.OpenRate != 0.5 || stats.ClickRate != 0.25 {
+		t.Errorf("open rate, click rate = %v, %v, want 0.5, 0.25", stats.OpenRate, stats.ClickRate)
+	}
+	if len(stats.Links) != 2 || stats.Links[0].URL != urls[0] || stats.Links[0].Clicks != 1 || stats.Links[1].Clicks != 0 {
+		t.Errorf("unexpected link stats: %+v, %+v", stats.Links[0], stats.Links[1])
+	}
+}
@@ -0,0 +1,75 @@
+package db_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestStartTxLogIdempotency(t *testing.T) {
+	database := newTestDB(t)
+
+	tmpl := &models.Template{UUID: uuid.New().String(), Name: "Receipt", Type: models.TemplateTypeTx, Subject: "Receipt", BodyText: "Thanks"}
+	if err := database.CreateTemplate(tmpl); err != nil {
+		t.Fatalf("CreateTemplate failed: %v", err)
+	}
+
+	newEntry := func() *models.TxLog {
+		return &models.TxLog{UUID: uuid.New().String(), TemplateID: tmpl.ID, Email: "jane@example.com"}
+	}
+
+	first, created, err := database.StartTxLog(newEntry(), "key-1", "hash-a")
+	if err != nil || !created {
+		t.Fatalf("first StartTxLog: created=%v err=%v", created, err)
+	}
+	if first.Status != models.TxStatusSending || first.TemplateUUID == nil || *first.TemplateUUID != tmpl.UUID {
+		t.Errorf("unexpected entry: %+v", first)
+	}
+	if err := database.FinishTxLog(first, models.TxStatusSent, nil); err != nil {
+		t.Fatalf("FinishTxLog failed: %v", err)
+	}
+
+	// Same key and request: the original entry comes back
+	again, created, err := database.StartTxLog(newEntry(), "key-1", "hash-a")
+	if err != nil || created {
+		t.Fatalf("repeated StartTxLog: created=%v err=%v", created, err)
+	}
+	if again.UUID != first.UUID || again.Status != models.TxStatusSent {
+		t.Errorf("got %+v, want the original sent entry", again)
+	}
+
+	// Same key, different request
+	if _, _, err := database.StartTxLog(newEntry(), "key-1", "hash-b"); !errors.Is(err, db.ErrIdempotencyMismatch) {
+		t.Errorf("expected ErrIdempotencyMismatch, got %v", err)
+	}
+
+	// Requests without a key are never deduplicated
+	for i := 0; i < 2; i++ {
+		if _, created, err := database.StartTxLog(newEntry(), "", "hash-a"); err != nil || !created {
+			t.Fatalf("StartTxLog without key: created=%v err=%v", created, err)
+		}
+	}
+
+	_, total, err := database.ListTxLogs(1, 20)
+	if err != nil {
+		t.Fatalf("ListTxLogs failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("total = %d, want 3", total)
+	}
+
+	// Logs outlive their template
+	if err := database.DeleteTemplate(tmpl.ID); err != nil {
+		t.Fatalf("DeleteTemplate failed: %v", err)
+	}
+	logs, _, err := database.ListTxLogs(1, 20)
+	if err != nil {
+		t.Fatalf("ListTxLogs failed: %v", err)
+	}
+	if len(logs) != 3 || logs[0].TemplateUUID != nil {
+		t.Errorf("expected 3 logs without template after delete, got %d", len(logs))
+	}
+}
@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/handlers/private"
+	"github.com/zhisme/tinylist/internal/mailer"
+)
+
+// exportRows is enough subscribers for the export to flush partway
+const exportRows = 2000
+
+// newTestDB creates a migrated database in a temporary directory
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	database, err := db.New(filepath.Join(t.TempDir(), "tinylist.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return database
+}
+
+// newExportHandler creates a subscriber handler on a database holding exportRows subscribers
+func newExportHandler(t *testing.T) *private.SubscriberHandler {
+	t.Helper()
+	database := newTestDB(t)
+	_, err := database.Exec(`
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < ?)
+		INSERT INTO subscribers (uuid, email, status, unsubscribe_token)
+		SELECT 'uuid-' || i, 'user' || i || '@example.com', 'verified', 'token-' || i FROM n
+	`, exportRows)
+	if err != nil {
+		t.Fatalf("failed to create subscribers: %v", err)
+	}
+	return private.NewSubscriberHandler(database, mailer.New(), "https://example.com")
+}
+
+func TestExportOutlastsRequestTimeout(t *testing.T) {
+	h := newExportHandler(t)
+	r := chi.NewRouter()
+	r.Use(middleware.Timeout(time.Nanosecond))
+	r.Get("/export", h.Export)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/export")
+	if err != nil {
+		t.Fatalf("GET /export error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading export error = %v", err)
+	}
+	if lines := strings.Count(string(body), "\n"); lines != exportRows+1 {
+		t.Errorf("export has %d lines, want a header and %d rows", lines, exportRows)
+	}
+}
+
+// cancelOnFlush cancels the request once the first rows have been sent
+type cancelOnFlush struct {
+	http.ResponseWriter
+	cancel context.CancelFunc
+}
+
+func (w *cancelOnFlush) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *cancelOnFlush) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+	w.cancel()
+	// Give the query time to notice
+	time.Sleep(100 * time.Millisecond)
+}
+
+func TestExportCancelledIsIncomplete(t *testing.T) {
+	h := newExportHandler(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		h.Export(&cancelOnFlush{ResponseWriter: w, cancel: cancel}, r.WithContext(ctx))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/export")
+	if err != nil {
+		t.Fatalf("GET /export error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200 with the rows sent before cancelling", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Errorf("export cut off after %d bytes read as complete, want error", len(body))
+	}
+}
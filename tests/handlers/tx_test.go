@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/zhisme/tinylist/internal/handlers/private"
+	"github.com/zhisme/tinylist/internal/mailer"
+	"github.com/zhisme/tinylist/internal/models"
+)
+
+func TestTxReplayOfUnfinishedSend(t *testing.T) {
+	database := newTestDB(t)
+	mail := mailer.New()
+	mail.SetBackend(mailer.NewLogBackend())
+	h := private.NewTxHandler(database, mail)
+
+	tmpl := &models.Template{UUID: uuid.New().String(), Name: "Receipt", Type: models.TemplateTypeTx, Subject: "Receipt", BodyText: "Thanks"}
+	if err := database.CreateTemplate(tmpl); err != nil {
+		t.Fatalf("CreateTemplate() error = %v", err)
+	}
+	body := `{"template_id":"` + tmpl.UUID + `","email":"jane@example.com"}`
+
+	// An earlier request took the key, then died before saving the outcome
+	hash := sha256.Sum256([]byte(body))
+	entry := &models.TxLog{UUID: uuid.New().String(), TemplateID: tmpl.ID, Email: "jane@example.com"}
+	if _, _, err := database.StartTxLog(entry, "key-1", hex.EncodeToString(hash[:])); err != nil {
+		t.Fatalf("StartTxLog() error = %v", err)
+	}
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "key-1")
+		rec := httptest.NewRecorder()
+		h.Send(rec, req)
+		return rec
+	}
+
+	if rec := send(); rec.Code != http.StatusConflict {
+		t.Errorf("replay while pending status = %d, want 409", rec.Code)
+	}
+
+	if _, err := database.Exec("UPDATE tx_logs SET updated_at = datetime('now', '-1 hour')"); err != nil {
+		t.Fatalf("failed to age tx log: %v", err)
+	}
+	rec := send()
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("replay of abandoned send status = %d, want 500", rec.Code)
+	}
+	var got models.TxLog
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.UUID != entry.UUID || got.Status != models.TxStatusUnknown {
+		t.Errorf("replay = %s %s, want %s unknown", got.UUID, got.Status, entry.UUID)
+	}
+}
@@ -0,0 +1,119 @@
+package mailer_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/mailer"
+)
+
+func TestFileBackend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	m := mailer.New()
+	m.SetBackend(mailer.NewFileBackend(dir))
+
+	if !m.IsConfigured() {
+		t.Fatal("mailer with file backend should be configured without SMTP settings")
+	}
+
+	err := m.SendCampaign(context.Background(), "jane@example.com", "Jane", "Hello", "Body", "<p>Body</p>", "https://example.com/u/tok", nil)
+	if err != nil {
+		t.Fatalf("SendCampaign failed: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("got %d .eml files (err %v), want 1", len(files), err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	for _, want := range []string{"Subject: Hello", "jane@example.com", "List-Unsubscribe: <https://example.com/u/tok>"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("message does not contain %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestHTTPBackend(t *testing.T) {
+	var got []mailer.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var msg mailer.Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if msg.ToEmail == "reject@example.com" {
+			http.Error(w, "mailbox unavailable", http.StatusUnprocessableEntity)
+			return
+		}
+		got = append(got, msg)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	m := mailer.New()
+	m.SetBackend(mailer.NewHTTPBackend(server.URL, "secret"))
+
+	sender := m.NewSender()
+	defer sender.Close()
+	ctx := context.Background()
+
+	if err := sender.SendCampaign(ctx, "jane@example.com", "Jane", "Hello", "Body", "", "https://example.com/u/tok", map[string]string{"X-Test": "1"}); err != nil {
+		t.Fatalf("SendCampaign failed: %v", err)
+	}
+	err := sender.SendCampaign(ctx, "reject@example.com", "", "Hello", "Body", "", "https://example.com/u/tok", nil)
+	if err == nil || !strings.Contains(err.Error(), "mailbox unavailable") {
+		t.Errorf("expected rejection with the server's reason, got %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("got %d delivered messages, want 1", len(got))
+	}
+	msg := got[0]
+	if msg.ToEmail != "jane@example.com" || msg.Subject != "Hello" || msg.Headers["X-Test"] != "1" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if !strings.Contains(msg.Text, "https://example.com/u/tok") {
+		t.Errorf("text is missing the unsubscribe footer: %q", msg.Text)
+	}
+}
+
+func TestBackendFromSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		wantNil  bool
+		wantErr  bool
+	}{
+		{name: "default is smtp", settings: map[string]string{}, wantNil: true},
+		{name: "smtp", settings: map[string]string{"delivery_backend": "smtp"}, wantNil: true},
+		{name: "file", settings: map[string]string{"delivery_backend": "file", "delivery_dir": "/tmp/out"}},
+		{name: "log", settings: map[string]string{"delivery_backend": "log"}},
+		{name: "http", settings: map[string]string{"delivery_backend": "http", "delivery_url": "http://localhost"}},
+		{name: "unknown", settings: map[string]string{"delivery_backend": "pigeon"}, wantNil: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, err := mailer.BackendFromSettings(tt.settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (backend == nil) != tt.wantNil {
+				t.Errorf("backend = %v, wantNil %v", backend, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package mailer_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/mailer"
+)
+
+// smtpServer is a minimal SMTP server that accepts everything except
+// recipients containing "reject"
+type smtpServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    int
+	messages int
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &smtpServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "RCPT") && strings.Contains(cmd, "REJECT"):
+			reply("550 no such user")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+			}
+			s.mu.Lock()
+			s.messages++
+			s.mu.Unlock()
+			reply("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *smtpServer) counts() (conns, messages int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns, s.messages
+}
+
+func TestSenderReusesConnection(t *testing.T) {
+	server := newSMTPServer(t)
+	addr := server.listener.Addr().(*net.TCPAddr)
+
+	m := mailer.New()
+	m.Reconfigure("127.0.0.1", addr.Port, "", "", "news@example.com", "News", false)
+
+	sender := m.NewSender()
+	defer sender.Close()
+	ctx := context.Background()
+
+	send := func(to string) error {
+		return sender.SendCampaign(ctx, to, "", "Hello", "Body", "", "https://example.com/unsubscribe", nil)
+	}
+
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := send(to); err != nil {
+			t.Fatalf("send to %s failed: %v", to, err)
+		}
+	}
+	if conns, messages := server.counts(); conns != 1 || messages != 3 {
+		t.Errorf("got %d connections and %d messages, want 1 and 3", conns, messages)
+	}
+
+	// A failed send drops the connection, the next one redials
+	if err := send("reject@example.com"); err == nil {
+		t.Fatal("expected send to rejected recipient to fail")
+	}
+	if err := send("d@example.com"); err != nil {
+		t.Fatalf("send after failure failed: %v", err)
+	}
+	if conns, messages := server.counts(); conns != 2 || messages != 4 {
+		t.Errorf("got %d connections and %d messages, want 2 and 4", conns, messages)
+	}
+}
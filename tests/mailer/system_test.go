@@ -0,0 +1,82 @@
+package mailer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/mailer"
+)
+
+func TestRenderSystemEmail(t *testing.T) {
+	urls := map[string]string{"verify_url": "https://example.com/api/verify/tok"}
+
+	tests := []struct {
+		name        string
+		stored      map[string]mailer.SystemEmail
+		wantSubject string
+		wantText    string
+	}{
+		{
+			name:        "built-in",
+			wantSubject: "Please verify your email address",
+			wantText:    "https://example.com/api/verify/tok",
+		},
+		{
+			name: "customized",
+			stored: map[string]mailer.SystemEmail{
+				mailer.SystemVerification: {Subject: "Confirm, {{ .Name }}", BodyText: "Go to {{ .Data.verify_url }}"},
+			},
+			wantSubject: "Confirm, Jane",
+			wantText:    "Go to https://example.com/api/verify/tok",
+		},
+		{
+			name: "invalid template falls back",
+			stored: map[string]mailer.SystemEmail{
+				mailer.SystemVerification: {Subject: "Confirm {{ if }}", BodyText: "Body"},
+			},
+			wantSubject: "Please verify your email address",
+			wantText:    "https://example.com/api/verify/tok",
+		},
+		{
+			name: "render error falls back",
+			stored: map[string]mailer.SystemEmail{
+				mailer.SystemVerification: {Subject: "Confirm", BodyText: "{{ .Name.Missing }}"},
+			},
+			wantSubject: "Please verify your email address",
+			wantText:    "https://example.com/api/verify/tok",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mailer.New()
+			m.SetSystemEmails(tt.stored)
+
+			subject, text, _, err := m.RenderSystemEmail(mailer.SystemVerification, nil, "jane@example.com", "Jane", urls)
+			if err != nil {
+				t.Fatalf("RenderSystemEmail failed: %v", err)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+			if !strings.Contains(text, tt.wantText) {
+				t.Errorf("text %q does not contain %q", text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestSystemEmailsFromSettings(t *testing.T) {
+	emails := mailer.SystemEmailsFromSettings(map[string]string{
+		"email_welcome_subject": "Hi",
+		"email_welcome_text":    "Welcome {{ .Name }}",
+		"email_goodbye_subject": "Bye", // No body, so the built-in is used
+	})
+
+	if len(emails) != 1 {
+		t.Fatalf("got %d emails, want only welcome: %v", len(emails), emails)
+	}
+	if got := emails[mailer.SystemWelcome]; got.Subject != "Hi" || got.BodyText != "Welcome {{ .Name }}" {
+		t.Errorf("welcome = %+v", got)
+	}
+}
@@ -0,0 +1,180 @@
+package render_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/render"
+)
+
+func TestExecute(t *testing.T) {
+	campaign := &models.Campaign{UUID: "c-1", Subject: "News"}
+
+	tests := []struct {
+		name     string
+		template string
+		subName  string
+		expected string
+	}{
+		{"legacy placeholders", "Hi {{name}}, we'll write to {{ email }}", "Jane", "Hi Jane, we'll write to jane@example.com"},
+		{"fallback for empty name", `Hi {{ .Name | default "there" }}!`, "", "Hi there!"},
+		{"fallback keeps name", `Hi {{ .Name | default "there" }}!`, "Jane", "Hi Jane!"},
+		{"conditional", `{{ if .Name }}Dear {{ .Name }}{{ else }}Hello{{ end }}`, "", "Hello"},
+		{"campaign metadata", `{{ .Campaign.Subject }} ({{ .Campaign.UUID }})`, "Jane", "News (c-1)"},
+		{"subscriber fields", `{{ .Subscriber.Email | upper }}`, "Jane", "JANE@EXAMPLE.COM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := render.Compile(tt.template, tt.template, "")
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			sub := &models.Subscriber{UUID: "s-1", Email: "jane@example.com", Name: tt.subName}
+			subject, text, html, err := tmpl.Execute(render.NewData(sub, campaign))
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if subject != tt.expected || text != tt.expected {
+				t.Errorf("Execute() = %q, %q, want %q", subject, text, tt.expected)
+			}
+			if html != "" {
+				t.Errorf("Execute() html = %q, want empty without an HTML body", html)
+			}
+		})
+	}
+}
+
+func TestExecuteEscapesHTML(t *testing.T) {
+	tmpl, err := render.Compile("Hi {{name}}", "Hi {{name}}", `<p>Hi {{name}}</p><a href="https://example.com/?u={{ .Subscriber.UUID }}">x</a>`)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	sub := &models.Subscriber{UUID: "a b", Email: "x@example.com", Name: "<script>alert(1)</script>"}
+	subject, text, html, err := tmpl.Execute(render.NewData(sub, &models.Campaign{}))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	// Plain text parts are not HTML and stay as they are
+	if subject != "Hi <script>alert(1)</script>" || text != subject {
+		t.Errorf("subject, text = %q, %q, want unescaped name", subject, text)
+	}
+	if strings.Contains(html, "<script>") {
+		t.Errorf("html contains unescaped name: %s", html)
+	}
+	if !strings.Contains(html, "&lt;script&gt;") || !strings.Contains(html, "?u=a%20b") {
+		t.Errorf("html not escaped for its context: %s", html)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  string
+		bodyText string
+		bodyHTML string
+		wantErr  string
+	}{
+		{"valid", "Hi {{name}}", "Hi {{ .Name | default \"there\" }}", "<p>{{ .Email }}</p>", ""},
+		{"subject syntax error", "Hi {{ .Name ", "body", "", "invalid subject template"},
+		{"unknown function", "Hi", "{{ shout .Name }}", "", "invalid body_text template"},
+		{"unknown field", "Hi", "body", "<p>{{ .Nickname }}</p>", "body_html"},
+		{"unclosed block", "Hi", "{{ if .Name }}Dear", "", "invalid body_text template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := render.Validate(tt.subject, tt.bodyText, tt.bodyHTML)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileWithLayout(t *testing.T) {
+	layout := &render.Layout{
+		Text: "ACME\n\n{{ content }}\n\n-- ACME Inc.",
+		HTML: `<html><body><header>ACME</header>{{content}}<footer>{{ .Email }}</footer></body></html>`,
+	}
+	tmpl, err := render.CompileWithLayout("News", "Hi {{name}}", "<p>Hi {{ .Name }}</p>", layout)
+	if err != nil {
+		t.Fatalf("CompileWithLayout failed: %v", err)
+	}
+
+	sub := &models.Subscriber{UUID: "s-1", Email: "jane@example.com", Name: "<Jane>"}
+	_, text, html, err := tmpl.Execute(render.NewData(sub, &models.Campaign{UUID: "c-1", Subject: "News"}))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if text != "ACME\n\nHi <Jane>\n\n-- ACME Inc." {
+		t.Errorf("text = %q", text)
+	}
+	want := `<html><body><header>ACME</header><p>Hi &lt;Jane&gt;</p><footer>jane@example.com</footer></body></html>`
+	if html != want {
+		t.Errorf("html = %q, want %q", html, want)
+	}
+}
+
+func TestValidateLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		layout  render.Layout
+		wantErr string
+	}{
+		{"valid", render.Layout{Text: "{{ content }}", HTML: "<div>{{ content }}</div>"}, ""},
+		{"text only", render.Layout{Text: "Hello\n{{content}}"}, ""},
+		{"text without slot", render.Layout{Text: "Hello"}, "body_text layout"},
+		{"html without slot", render.Layout{Text: "{{ content }}", HTML: "<div></div>"}, "body_html layout"},
+		{"broken html", render.Layout{Text: "{{ content }}", HTML: "<div>{{ content }}{{ if }}</div>"}, "body_html layout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := render.ValidateLayout(tt.layout)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteAttributes(t *testing.T) {
+	tmpl, err := render.Compile("Hi", `{{ .Subscriber.Attributes.city | default "your city" }}`, "")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	sub := &models.Subscriber{UUID: "s-1", Email: "jane@example.com", Attributes: map[string]interface{}{"city": "Berlin"}}
+	for _, tt := range []struct {
+		sub  *models.Subscriber
+		want string
+	}{
+		{sub, "Berlin"},
+		{&models.Subscriber{UUID: "s-2", Email: "joe@example.com"}, "your city"},
+	} {
+		_, text, _, err := tmpl.Execute(render.NewData(tt.sub, &models.Campaign{UUID: "c-1"}))
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if text != tt.want {
+			t.Errorf("text = %q, want %q", text, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package worker_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/config"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/worker"
+)
+
+func TestBackupJobRotatesSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.New(filepath.Join(dir, "tinylist.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	backups := filepath.Join(dir, "backups")
+	// Files that aren't snapshots are left alone
+	if err := os.MkdirAll(backups, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(backups, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := worker.NewBackupJob(database, config.BackupConfig{Dir: backups, Interval: 1, Keep: 2})
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := job.Snapshot()
+		if err != nil {
+			t.Fatalf("Snapshot() error = %v", err)
+		}
+		paths = append(paths, path)
+		// Snapshot names have a resolution of one second
+		if i < 2 {
+			time.Sleep(time.Second)
+		}
+	}
+
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("oldest snapshot %s was kept", paths[0])
+	}
+	for _, path := range append(paths[1:], filepath.Join(backups, "notes.txt")) {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+	if err := db.ValidateSnapshot(paths[2]); err != nil {
+		t.Errorf("ValidateSnapshot() of newest snapshot error = %v", err)
+	}
+}
@@ -1,8 +1,12 @@
 package worker_test
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/zhisme/tinylist/internal/config"
+	"github.com/zhisme/tinylist/internal/mailer"
+	"github.com/zhisme/tinylist/internal/models"
 	"github.com/zhisme/tinylist/internal/worker"
 )
 
@@ -74,3 +78,58 @@ func TestReplaceTemplateVars(t *testing.T) {
 		})
 	}
 }
+
+func TestResumeCampaignLeavesLiveSender(t *testing.T) {
+	database := newTestDB(t)
+	w := worker.NewCampaignWorker(database, mailer.New(), config.SendingConfig{BatchSize: 10}, "https://example.com")
+
+	campaign := &models.Campaign{UUID: "c1", Subject: "Hello", BodyText: "Hello", Status: models.CampaignStatusDraft}
+	if err := database.CreateCampaign(campaign); err != nil {
+		t.Fatalf("CreateCampaign() error = %v", err)
+	}
+
+	// Another process, such as the command line, is sending it
+	if err := database.StartCampaign(campaign.ID, "cli"); err != nil {
+		t.Fatalf("StartCampaign() error = %v", err)
+	}
+
+	if err := w.ResumeCampaign(campaign.ID); !errors.Is(err, worker.ErrSentElsewhere) {
+		t.Errorf("ResumeCampaign() error = %v, want ErrSentElsewhere", err)
+	}
+}
+
+func TestSendCampaignLeavesUnsavedLogsToResume(t *testing.T) {
+	database := newTestDB(t)
+	mail := mailer.New()
+	mail.SetBackend(mailer.NewLogBackend())
+	w := worker.NewCampaignWorker(database, mail, config.SendingConfig{BatchSize: 2, Connections: 1}, "https://example.com")
+
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		sub := &models.Subscriber{UUID: email, Email: email, Status: models.StatusVerified, UnsubscribeToken: email}
+		if err := database.CreateSubscriber(sub); err != nil {
+			t.Fatalf("CreateSubscriber() error = %v", err)
+		}
+	}
+	campaign := &models.Campaign{UUID: "c1", Subject: "Hello", BodyText: "Hello", Status: models.CampaignStatusDraft}
+	if err := database.CreateCampaign(campaign); err != nil {
+		t.Fatalf("CreateCampaign() error = %v", err)
+	}
+
+	// No log can be saved
+	if _, err := database.Exec(`CREATE TRIGGER fail_logs BEFORE INSERT ON campaign_logs
+		BEGIN SELECT RAISE(ABORT, 'disk full'); END`); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+
+	if err := w.SendCampaign(campaign.ID); err == nil {
+		t.Error("SendCampaign() succeeded without saving the logs, want error")
+	}
+
+	stored, err := database.GetCampaignByID(campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignByID() error = %v", err)
+	}
+	if stored.Status != models.CampaignStatusSending {
+		t.Errorf("status = %s, want sending so it is resumed", stored.Status)
+	}
+}
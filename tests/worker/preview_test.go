@@ -0,0 +1,82 @@
+package worker_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/config"
+	"github.com/zhisme/tinylist/internal/db"
+	"github.com/zhisme/tinylist/internal/mailer"
+	"github.com/zhisme/tinylist/internal/models"
+	"github.com/zhisme/tinylist/internal/worker"
+)
+
+// newTestDB creates a migrated database in a temporary directory
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	database, err := db.New(filepath.Join(t.TempDir(), "tinylist.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return database
+}
+
+func TestPreview(t *testing.T) {
+	database := newTestDB(t)
+	w := worker.NewCampaignWorker(database, mailer.New(), config.SendingConfig{}, "https://example.com/tinylist")
+
+	html := `<p>Hi {{.Name | default "friend"}}, <a href="https://example.com/post">read on</a></p>`
+	campaign := &models.Campaign{
+		UUID:        "c1",
+		Subject:     "News for {{.Email}}",
+		BodyText:    "Hi {{.Name}}",
+		BodyHTML:    &html,
+		Status:      models.CampaignStatusDraft,
+		ListIDs:     []string{"list-1"},
+		TrackOpens:  true,
+		TrackClicks: true,
+	}
+	if err := database.CreateCampaign(campaign); err != nil {
+		t.Fatalf("CreateCampaign() error = %v", err)
+	}
+	sub := &models.Subscriber{UUID: "s1", Email: "jane@example.com", Name: "Jane", UnsubscribeToken: "tok"}
+
+	preview, err := w.Preview(campaign, sub)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	if preview.Subject != "News for jane@example.com" {
+		t.Errorf("Subject = %q", preview.Subject)
+	}
+	unsubscribeURL := "https://example.com/tinylist/api/unsubscribe/tok?list=list-1"
+	if !strings.HasPrefix(preview.BodyText, "Hi Jane") || !strings.Contains(preview.BodyText, unsubscribeURL) {
+		t.Errorf("BodyText = %q, want rendered body with unsubscribe footer", preview.BodyText)
+	}
+	if preview.BodyHTML == nil || !strings.Contains(*preview.BodyHTML, "Hi Jane") {
+		t.Fatalf("BodyHTML = %v, want rendered body", preview.BodyHTML)
+	}
+	// Tracking is rendered as sent, but for a stand-in subscriber
+	if !strings.Contains(*preview.BodyHTML, "https://example.com/tinylist/api/track/open/c1/preview") {
+		t.Errorf("BodyHTML = %q, want inert open pixel", *preview.BodyHTML)
+	}
+	if !strings.Contains(*preview.BodyHTML, "/api/track/click/") || strings.Contains(*preview.BodyHTML, "https://example.com/post") {
+		t.Errorf("BodyHTML = %q, want tracked link", *preview.BodyHTML)
+	}
+	if strings.Contains(*preview.BodyHTML, "/s1") {
+		t.Errorf("BodyHTML = %q, tracks the real subscriber", *preview.BodyHTML)
+	}
+
+	sample, err := w.Preview(campaign, worker.SampleSubscriber("someone@example.com"))
+	if err != nil {
+		t.Fatalf("Preview with sample subscriber failed: %v", err)
+	}
+	if !strings.Contains(*sample.BodyHTML, "Hi friend") {
+		t.Errorf("sample BodyHTML = %q, want default name", *sample.BodyHTML)
+	}
+}
@@ -0,0 +1,48 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zhisme/tinylist/internal/worker"
+)
+
+func TestRateLimiterShared(t *testing.T) {
+	limiter := worker.NewRateLimiter(100)
+	ctx := context.Background()
+
+	// 4 goroutines taking 5 slots each share 100/s, so 20 slots take at least 190ms
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				if err := limiter.Wait(ctx); err != nil {
+					t.Errorf("Wait failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("20 waits at 100/s took %v, want at least 190ms", elapsed)
+	}
+}
+
+func TestRateLimiterCancelled(t *testing.T) {
+	limiter := worker.NewRateLimiter(1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("first Wait failed: %v", err)
+	}
+	cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("expected Wait to fail once the context is cancelled")
+	}
+}
@@ -0,0 +1,81 @@
+package worker_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zhisme/tinylist/internal/worker"
+)
+
+func TestTrackableLinks(t *testing.T) {
+	body := `<p><a href="https://example.com/a">A</a>
+<a class="btn" href='https://example.com/b?x=1&amp;y=2'>B</a>
+<a href="https://example.com/a">A again</a>
+<a href="https://example.com/u?e={{email}}">Personal</a>
+<a href="mailto:hi@example.com">Mail</a></p>`
+
+	expected := []string{"https://example.com/a", "https://example.com/b?x=1&y=2"}
+	if got := worker.TrackableLinks(body); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	links := map[string]string{
+		"https://example.com/a":         "link-a",
+		"https://example.com/b?x=1&y=2": "link-b",
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "tracked link",
+			body:     `<a href="https://example.com/a">A</a>`,
+			expected: `<a href="https://list.example.com/api/track/click/link-a/sub-1">A</a>`,
+		},
+		{
+			name:     "escaped link in single quotes",
+			body:     `<a class="btn" href='https://example.com/b?x=1&amp;y=2'>B</a>`,
+			expected: `<a class="btn" href="https://list.example.com/api/track/click/link-b/sub-1">B</a>`,
+		},
+		{
+			name:     "unknown link kept",
+			body:     `<a href="https://example.com/other">Other</a>`,
+			expected: `<a href="https://example.com/other">Other</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := worker.RewriteLinks(tt.body, links, "https://list.example.com", "sub-1")
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAddOpenPixel(t *testing.T) {
+	pixel := `<img src="https://list.example.com/api/track/open/camp-1/sub-1" width="1" height="1" alt="" style="display:none">`
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"before closing body", "<html><body><p>Hi</p></BODY></html>", "<html><body><p>Hi</p>" + pixel + "</BODY></html>"},
+		{"fragment", "<p>Hi</p>", "<p>Hi</p>" + pixel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := worker.AddOpenPixel(tt.body, "https://list.example.com", "camp-1", "sub-1")
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}